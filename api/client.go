@@ -32,6 +32,7 @@ import (
 	goruntime "runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"text/tabwriter"
 	"text/template"
@@ -88,6 +89,7 @@ func (cli *DockerCli) CmdHelp(args ...string) error {
 		{"cp", "Copy files/folders from the containers filesystem to the host path"},
 		{"diff", "Inspect changes on a container's filesystem"},
 		{"events", "Get real time events from the server"},
+		{"exec", "Run a command in an existing container"},
 		{"export", "Stream the contents of a container as a tar archive"},
 		{"history", "Show the history of an image"},
 		{"images", "List images"},
@@ -99,10 +101,13 @@ func (cli *DockerCli) CmdHelp(args ...string) error {
 		{"load", "Load an image from a tar archive"},
 		{"login", "Register or Login to the docker registry server"},
 		{"logs", "Fetch the logs of a container"},
+		{"network", "Manage the networks of the containers"},
+		{"pause", "Pause all processes within a container"},
 		{"port", "Lookup the public-facing port which is NAT-ed to PRIVATE_PORT"},
 		{"ps", "List containers"},
 		{"pull", "Pull an image or a repository from the docker registry server"},
 		{"push", "Push an image or a repository to the docker registry server"},
+		{"rename", "Rename an existing container"},
 		{"restart", "Restart a running container"},
 		{"rm", "Remove one or more containers"},
 		{"rmi", "Remove one or more images"},
@@ -110,9 +115,12 @@ func (cli *DockerCli) CmdHelp(args ...string) error {
 		{"save", "Save an image to a tar archive"},
 		{"search", "Search for an image in the docker index"},
 		{"start", "Start a stopped container"},
+		{"stats", "Display a live stream of one or more containers' resource usage statistics"},
 		{"stop", "Stop a running container"},
 		{"tag", "Tag an image into a repository"},
 		{"top", "Lookup the running processes of a container"},
+		{"unpause", "Unpause a paused container"},
+		{"update", "Update the resource limits of one or more containers"},
 		{"version", "Show the docker version information"},
 		{"wait", "Block until a container stops, then print its exit code"},
 	} {
@@ -523,6 +531,115 @@ func (cli *DockerCli) CmdStop(args ...string) error {
 	return encounteredError
 }
 
+func (cli *DockerCli) CmdPause(args ...string) error {
+	cmd := cli.Subcmd("pause", "CONTAINER [CONTAINER...]", "Pause all processes within a container")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("POST", "/containers/"+name+"/pause", nil, false)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to pause one or more containers")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
+func (cli *DockerCli) CmdUnpause(args ...string) error {
+	cmd := cli.Subcmd("unpause", "CONTAINER [CONTAINER...]", "Unpause all processes within a container")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("POST", "/containers/"+name+"/unpause", nil, false)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to unpause one or more containers")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
+func (cli *DockerCli) CmdUpdate(args ...string) error {
+	cmd := cli.Subcmd("update", "[OPTIONS] CONTAINER [CONTAINER...]", "Update the resource limits of one or more containers")
+	flMemory := cmd.String([]string{"m", "-memory"}, "", "Memory limit (format: <number><optional unit>, where unit = b, k, m or g)")
+	flMemorySwap := cmd.String([]string{"-memory-swap"}, "", "Total memory limit (memory + swap), -1 to disable the swap limit")
+	flCpuShares := cmd.Int64([]string{"c", "-cpu-shares"}, 0, "CPU shares (relative weight)")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	resources := map[string]int64{"CpuShares": *flCpuShares}
+	if *flMemory != "" {
+		memory, err := utils.RAMInBytes(*flMemory)
+		if err != nil {
+			return err
+		}
+		resources["Memory"] = memory
+	}
+	if *flMemorySwap == "-1" {
+		resources["MemorySwap"] = -1
+	} else if *flMemorySwap != "" {
+		memorySwap, err := utils.RAMInBytes(*flMemorySwap)
+		if err != nil {
+			return err
+		}
+		resources["MemorySwap"] = memorySwap
+	}
+	if resources["Memory"] == 0 && resources["MemorySwap"] == 0 && resources["CpuShares"] == 0 {
+		return fmt.Errorf("Error: no limit to update, use --memory, --memory-swap or --cpu-shares")
+	}
+
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("POST", "/containers/"+name+"/update", resources, false)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to update one or more containers")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
+func (cli *DockerCli) CmdRename(args ...string) error {
+	cmd := cli.Subcmd("rename", "OLD_NAME NEW_NAME", "Rename an existing container")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	v := url.Values{}
+	v.Set("name", cmd.Arg(1))
+	if _, _, err := readBody(cli.call("POST", "/containers/"+cmd.Arg(0)+"/rename?"+v.Encode(), nil, false)); err != nil {
+		fmt.Fprintf(cli.err, "%s\n", err)
+		return fmt.Errorf("Error: failed to rename container named %s", cmd.Arg(0))
+	}
+	return nil
+}
+
 func (cli *DockerCli) CmdRestart(args ...string) error {
 	cmd := cli.Subcmd("restart", "[OPTIONS] CONTAINER [CONTAINER...]", "Restart a running container")
 	nSeconds := cmd.Int([]string{"t", "-time"}, 10, "Number of seconds to try to stop for before killing the container. Once killed it will then be restarted. Default=10")
@@ -653,7 +770,7 @@ func (cli *DockerCli) CmdStart(args ...string) error {
 
 	if *openStdin || *attach {
 		if tty && cli.isTerminal {
-			if err := cli.monitorTtySize(cmd.Arg(0)); err != nil {
+			if err := cli.monitorTtySize(cmd.Arg(0), false); err != nil {
 				utils.Errorf("Error monitoring TTY size: %s\n", err)
 			}
 		}
@@ -956,6 +1073,156 @@ func (cli *DockerCli) CmdRm(args ...string) error {
 	return encounteredError
 }
 
+// 'docker network COMMAND' manages the networks the containers are attached to with --net
+func (cli *DockerCli) CmdNetwork(args ...string) error {
+	usage := "Usage: docker network COMMAND [arg...]\n\nManage the networks of the containers\n\nCommands:\n" +
+		"    create   Create a network with its own bridge and subnet\n" +
+		"    inspect  Return low-level information on a network\n" +
+		"    ls       List the networks\n" +
+		"    rm       Remove one or more networks\n"
+	if len(args) == 0 || args[0] == "--help" {
+		fmt.Fprint(cli.err, usage)
+		return nil
+	}
+	switch args[0] {
+	case "create":
+		return cli.networkCreate(args[1:]...)
+	case "inspect":
+		return cli.networkInspect(args[1:]...)
+	case "ls":
+		return cli.networkList(args[1:]...)
+	case "rm":
+		return cli.networkRemove(args[1:]...)
+	}
+	fmt.Fprint(cli.err, usage)
+	return fmt.Errorf("Error: unknown network command: %s", args[0])
+}
+
+func (cli *DockerCli) networkCreate(args ...string) error {
+	cmd := cli.Subcmd("network create", "[OPTIONS] NAME", "Create a network with its own bridge and subnet")
+	subnet := cmd.String([]string{"-subnet"}, "", "Subnet of the network in CIDR notation, a free one by default")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+	config := map[string]string{
+		"Name":   cmd.Arg(0),
+		"Subnet": *subnet,
+	}
+	stream, _, err := cli.call("POST", "/networks/create", config, false)
+	if err != nil {
+		return err
+	}
+	var out engine.Env
+	if err := out.Decode(stream); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "%s\n", out.Get("Id"))
+	return nil
+}
+
+func (cli *DockerCli) networkList(args ...string) error {
+	cmd := cli.Subcmd("network ls", "[OPTIONS]", "List the networks")
+	quiet := cmd.Bool([]string{"q", "-quiet"}, false, "Only display numeric IDs")
+	noTrunc := cmd.Bool([]string{"#notrunc", "-no-trunc"}, false, "Don't truncate output")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 {
+		cmd.Usage()
+		return nil
+	}
+	body, _, err := readBody(cli.call("GET", "/networks/json", nil, false))
+	if err != nil {
+		return err
+	}
+	outs := engine.NewTable("", 0)
+	if _, err := outs.ReadListFrom(body); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	if !*quiet {
+		fmt.Fprintln(w, "NETWORK ID\tNAME\tBRIDGE\tSUBNET\tCONTAINERS")
+	}
+	for _, out := range outs.Data {
+		id := out.Get("Id")
+		if !*noTrunc {
+			id = utils.TruncateID(id)
+		}
+		if *quiet {
+			fmt.Fprintln(w, id)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", id, out.Get("Name"), out.Get("Bridge"), out.Get("Subnet"), out.GetInt("Containers"))
+	}
+	w.Flush()
+	return nil
+}
+
+func (cli *DockerCli) networkInspect(args ...string) error {
+	cmd := cli.Subcmd("network inspect", "NETWORK [NETWORK...]", "Return low-level information on a network")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+	indented := new(bytes.Buffer)
+	indented.WriteByte('[')
+	status := 0
+	for _, name := range cmd.Args() {
+		obj, _, err := readBody(cli.call("GET", "/networks/"+name+"/json", nil, false))
+		if err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		if err := json.Indent(indented, obj, "", "    "); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			status = 1
+			continue
+		}
+		indented.WriteString(",")
+	}
+	if indented.Len() > 1 {
+		// Remove trailing ','
+		indented.Truncate(indented.Len() - 1)
+	}
+	indented.WriteByte(']')
+	if _, err := io.Copy(cli.out, indented); err != nil {
+		return err
+	}
+	if status != 0 {
+		return &utils.StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+func (cli *DockerCli) networkRemove(args ...string) error {
+	cmd := cli.Subcmd("network rm", "NETWORK [NETWORK...]", "Remove one or more networks")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+	var encounteredError error
+	for _, name := range cmd.Args() {
+		if _, _, err := readBody(cli.call("DELETE", "/networks/"+name, nil, false)); err != nil {
+			fmt.Fprintf(cli.err, "%s\n", err)
+			encounteredError = fmt.Errorf("Error: failed to remove one or more networks")
+		} else {
+			fmt.Fprintf(cli.out, "%s\n", name)
+		}
+	}
+	return encounteredError
+}
+
 // 'docker kill NAME' kills a running container
 func (cli *DockerCli) CmdKill(args ...string) error {
 	cmd := cli.Subcmd("kill", "[OPTIONS] CONTAINER [CONTAINER...]", "Kill a running container (send SIGKILL, or specified signal)")
@@ -1566,8 +1833,11 @@ func (cli *DockerCli) CmdDiff(args ...string) error {
 }
 
 func (cli *DockerCli) CmdLogs(args ...string) error {
-	cmd := cli.Subcmd("logs", "CONTAINER", "Fetch the logs of a container")
+	cmd := cli.Subcmd("logs", "[OPTIONS] CONTAINER", "Fetch the logs of a container")
 	follow := cmd.Bool([]string{"f", "-follow"}, false, "Follow log output")
+	times := cmd.Bool([]string{"t", "-timestamps"}, false, "Show timestamps")
+	tail := cmd.String([]string{"-tail"}, "all", "Output the specified number of lines at the end of logs (defaults to all logs)")
+	since := cmd.Int64([]string{"-since"}, 0, "Show the logs written after this unix timestamp")
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
@@ -1588,17 +1858,20 @@ func (cli *DockerCli) CmdLogs(args ...string) error {
 	}
 
 	v := url.Values{}
-	v.Set("logs", "1")
 	v.Set("stdout", "1")
 	v.Set("stderr", "1")
+	if *times {
+		v.Set("timestamps", "1")
+	}
 	if *follow && container.State.Running {
-		v.Set("stream", "1")
+		v.Set("follow", "1")
 	}
-
-	if err := cli.hijack("POST", "/containers/"+name+"/attach?"+v.Encode(), container.Config.Tty, nil, cli.out, cli.err, nil); err != nil {
-		return err
+	v.Set("tail", *tail)
+	if *since > 0 {
+		v.Set("since", strconv.FormatInt(*since, 10))
 	}
-	return nil
+
+	return cli.streamHelper("GET", "/containers/"+name+"/logs?"+v.Encode(), container.Config.Tty, nil, cli.out, cli.err, nil)
 }
 
 func (cli *DockerCli) CmdAttach(args ...string) error {
@@ -1629,7 +1902,7 @@ func (cli *DockerCli) CmdAttach(args ...string) error {
 	}
 
 	if container.Config.Tty && cli.isTerminal {
-		if err := cli.monitorTtySize(cmd.Arg(0)); err != nil {
+		if err := cli.monitorTtySize(cmd.Arg(0), false); err != nil {
 			utils.Debugf("Error monitoring TTY size: %s", err)
 		}
 	}
@@ -1665,6 +1938,246 @@ func (cli *DockerCli) CmdAttach(args ...string) error {
 	return nil
 }
 
+func (cli *DockerCli) CmdExec(args ...string) error {
+	cmd := cli.Subcmd("exec", "[OPTIONS] CONTAINER COMMAND [ARG...]", "Run a command in an existing container")
+
+	name, execConfig, err := runconfig.ParseExec(cmd, args)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		cmd.Usage()
+		return nil
+	}
+
+	stream, _, err := cli.call("POST", "/containers/"+name+"/exec", execConfig, false)
+	if err != nil {
+		return err
+	}
+	var result engine.Env
+	if err := result.Decode(stream); err != nil {
+		return err
+	}
+	execID := result.Get("Id")
+
+	if execConfig.Detach {
+		if _, _, err := readBody(cli.call("POST", "/exec/"+execID+"/start", execConfig, false)); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	var (
+		in             io.ReadCloser
+		stdout, stderr io.Writer
+	)
+	if execConfig.AttachStdin {
+		in = cli.in
+	}
+	if execConfig.AttachStdout {
+		stdout = cli.out
+	}
+	if execConfig.AttachStderr {
+		if execConfig.Tty {
+			stderr = cli.out
+		} else {
+			stderr = cli.err
+		}
+	}
+
+	hijacked := make(chan io.Closer)
+	errCh := utils.Go(func() error {
+		return cli.hijack("POST", "/exec/"+execID+"/start", execConfig.Tty, in, stdout, stderr, hijacked)
+	})
+
+	// Acknowledge the hijack before starting to monitor the tty size
+	select {
+	case closer := <-hijacked:
+		if closer != nil {
+			defer closer.Close()
+		}
+	case err := <-errCh:
+		if err != nil {
+			utils.Debugf("Error hijack: %s", err)
+			return err
+		}
+	}
+
+	if execConfig.Tty && cli.isTerminal {
+		if err := cli.monitorTtySize(execID, true); err != nil {
+			utils.Errorf("Error monitoring TTY size: %s", err)
+		}
+	}
+
+	if err := <-errCh; err != nil {
+		utils.Debugf("Error hijack: %s", err)
+		return err
+	}
+
+	status, err := getExecExitCode(cli, execID)
+	if err != nil {
+		return err
+	}
+	if status != 0 {
+		return &utils.StatusError{StatusCode: status}
+	}
+	return nil
+}
+
+// containerStats is the last sample rendered by CmdStats for a container
+type containerStats struct {
+	Name             string
+	CpuPercentage    float64
+	Memory           float64
+	MemoryLimit      float64
+	MemoryPercentage float64
+	NetworkRx        float64
+	NetworkTx        float64
+	BlockRead        float64
+	BlockWrite       float64
+	Err              error
+}
+
+func (cli *DockerCli) CmdStats(args ...string) error {
+	cmd := cli.Subcmd("stats", "CONTAINER [CONTAINER...]", "Display a live stream of one or more containers' resource usage statistics")
+	noStream := cmd.Bool([]string{"-no-stream"}, false, "Disable streaming stats and only pull the first result")
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() < 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	var (
+		names = cmd.Args()
+		lock  sync.Mutex
+		stats = make([]*containerStats, len(names))
+		done  = make(chan struct{}, len(names))
+	)
+	v := url.Values{}
+	if *noStream {
+		v.Set("stream", "0")
+	} else {
+		v.Set("stream", "1")
+	}
+	for i, name := range names {
+		s := &containerStats{Name: name}
+		stats[i] = s
+		go func() {
+			defer func() { done <- struct{}{} }()
+			stream, _, err := cli.call("GET", "/containers/"+s.Name+"/stats?"+v.Encode(), nil, false)
+			if err != nil {
+				lock.Lock()
+				s.Err = err
+				lock.Unlock()
+				return
+			}
+			defer stream.Close()
+
+			var (
+				previousCpu    uint64
+				previousSystem uint64
+				dec            = json.NewDecoder(stream)
+			)
+			for {
+				var sample ContainerStats
+				if err := dec.Decode(&sample); err != nil {
+					if err != io.EOF {
+						lock.Lock()
+						s.Err = err
+						lock.Unlock()
+					}
+					return
+				}
+				lock.Lock()
+				s.CpuPercentage = calculateCpuPercent(previousCpu, previousSystem, &sample)
+				s.Memory = float64(sample.MemoryUsage.Usage)
+				s.MemoryLimit = float64(sample.MemoryUsage.Limit)
+				if sample.MemoryUsage.Limit != 0 {
+					s.MemoryPercentage = float64(sample.MemoryUsage.Usage) / float64(sample.MemoryUsage.Limit) * 100.0
+				}
+				s.NetworkRx = float64(sample.Network.RxBytes)
+				s.NetworkTx = float64(sample.Network.TxBytes)
+				s.BlockRead, s.BlockWrite = 0, 0
+				for _, entry := range sample.BlkioUsage.IoServiceBytes {
+					switch entry.Op {
+					case "Read":
+						s.BlockRead += float64(entry.Value)
+					case "Write":
+						s.BlockWrite += float64(entry.Value)
+					}
+				}
+				lock.Unlock()
+				previousCpu, previousSystem = sample.CpuUsage.TotalUsage, sample.SystemUsage
+			}
+		}()
+	}
+
+	display := func() {
+		w := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+		fmt.Fprint(w, "CONTAINER\tCPU %\tMEM USAGE/LIMIT\tMEM %\tNET I/O\tBLOCK I/O\n")
+		lock.Lock()
+		for _, s := range stats {
+			if s.Err != nil {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, "--", "-- / --", "--", "-- / --", "-- / --")
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%.2f%%\t%s/%s\t%.2f%%\t%s/%s\t%s/%s\n",
+				s.Name,
+				s.CpuPercentage,
+				utils.HumanSize(int64(s.Memory)), utils.HumanSize(int64(s.MemoryLimit)),
+				s.MemoryPercentage,
+				utils.HumanSize(int64(s.NetworkRx)), utils.HumanSize(int64(s.NetworkTx)),
+				utils.HumanSize(int64(s.BlockRead)), utils.HumanSize(int64(s.BlockWrite)))
+		}
+		lock.Unlock()
+		w.Flush()
+	}
+
+	var (
+		finished = 0
+		ticker   = time.NewTicker(500 * time.Millisecond)
+	)
+	defer ticker.Stop()
+	for finished < len(names) {
+		select {
+		case <-done:
+			finished++
+		case <-ticker.C:
+			if !*noStream {
+				// clear the screen and move the cursor to the top left corner
+				fmt.Fprint(cli.out, "\033[2J\033[H")
+				display()
+			}
+		}
+	}
+	display()
+
+	var encounteredError error
+	for _, s := range stats {
+		if s.Err != nil {
+			fmt.Fprintf(cli.err, "%s: %s\n", s.Name, s.Err)
+			encounteredError = fmt.Errorf("Error: failed to get stats from one or more containers")
+		}
+	}
+	return encounteredError
+}
+
+// calculateCpuPercent returns the CPU usage of the container relative to the host
+// between the previous sample and v, scaled by the number of cores
+func calculateCpuPercent(previousCpu, previousSystem uint64, v *ContainerStats) float64 {
+	var (
+		cpuPercent  = 0.0
+		cpuDelta    = float64(v.CpuUsage.TotalUsage) - float64(previousCpu)
+		systemDelta = float64(v.SystemUsage) - float64(previousSystem)
+	)
+	if previousSystem != 0 && systemDelta > 0.0 && cpuDelta > 0.0 {
+		cpuPercent = (cpuDelta / systemDelta) * float64(len(v.CpuUsage.PercpuUsage)) * 100.0
+	}
+	return cpuPercent
+}
+
 func (cli *DockerCli) CmdSearch(args ...string) error {
 	cmd := cli.Subcmd("search", "TERM", "Search the docker index for images")
 	noTrunc := cmd.Bool([]string{"#notrunc", "-no-trunc"}, false, "Don't truncate output")
@@ -1947,7 +2460,7 @@ func (cli *DockerCli) CmdRun(args ...string) error {
 	}
 
 	if (config.AttachStdin || config.AttachStdout || config.AttachStderr) && config.Tty && cli.isTerminal {
-		if err := cli.monitorTtySize(runResult.Get("Id")); err != nil {
+		if err := cli.monitorTtySize(runResult.Get("Id"), false); err != nil {
 			utils.Errorf("Error monitoring TTY size: %s\n", err)
 		}
 	}
@@ -2167,6 +2680,12 @@ func (cli *DockerCli) call(method, path string, data interface{}, passAuthInfo b
 }
 
 func (cli *DockerCli) stream(method, path string, in io.Reader, out io.Writer, headers map[string][]string) error {
+	return cli.streamHelper(method, path, true, in, out, nil, headers)
+}
+
+// streamHelper copies the body of the response to stdout. When setRawTerminal
+// is false, the body is multiplexed and demultiplexed to stdout and stderr.
+func (cli *DockerCli) streamHelper(method, path string, setRawTerminal bool, in io.Reader, stdout, stderr io.Writer, headers map[string][]string) error {
 	if (method == "POST" || method == "PUT") && in == nil {
 		in = bytes.NewReader([]byte{})
 	}
@@ -2221,10 +2740,16 @@ func (cli *DockerCli) stream(method, path string, in io.Reader, out io.Writer, h
 	}
 
 	if MatchesContentType(resp.Header.Get("Content-Type"), "application/json") {
-		return utils.DisplayJSONMessagesStream(resp.Body, out, cli.terminalFd, cli.isTerminal)
+		return utils.DisplayJSONMessagesStream(resp.Body, stdout, cli.terminalFd, cli.isTerminal)
 	}
-	if _, err := io.Copy(out, resp.Body); err != nil {
-		return err
+	if setRawTerminal {
+		if _, err := io.Copy(stdout, resp.Body); err != nil {
+			return err
+		}
+	} else {
+		if _, err := utils.StdCopy(stdout, stderr, resp.Body); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -2355,7 +2880,7 @@ func (cli *DockerCli) getTtySize() (int, int) {
 	return int(ws.Height), int(ws.Width)
 }
 
-func (cli *DockerCli) resizeTty(id string) {
+func (cli *DockerCli) resizeTty(id string, isExec bool) {
 	height, width := cli.getTtySize()
 	if height == 0 && width == 0 {
 		return
@@ -2363,19 +2888,23 @@ func (cli *DockerCli) resizeTty(id string) {
 	v := url.Values{}
 	v.Set("h", strconv.Itoa(height))
 	v.Set("w", strconv.Itoa(width))
-	if _, _, err := readBody(cli.call("POST", "/containers/"+id+"/resize?"+v.Encode(), nil, false)); err != nil {
+	path := "/containers/" + id + "/resize?"
+	if isExec {
+		path = "/exec/" + id + "/resize?"
+	}
+	if _, _, err := readBody(cli.call("POST", path+v.Encode(), nil, false)); err != nil {
 		utils.Errorf("Error resize: %s", err)
 	}
 }
 
-func (cli *DockerCli) monitorTtySize(id string) error {
-	cli.resizeTty(id)
+func (cli *DockerCli) monitorTtySize(id string, isExec bool) error {
+	cli.resizeTty(id, isExec)
 
 	sigchan := make(chan os.Signal, 1)
 	gosignal.Notify(sigchan, syscall.SIGWINCH)
 	go func() {
 		for _ = range sigchan {
-			cli.resizeTty(id)
+			cli.resizeTty(id, isExec)
 		}
 	}()
 	return nil
@@ -2430,6 +2959,24 @@ func getExitCode(cli *DockerCli, containerId string) (bool, int, error) {
 	return c.State.Running, c.State.ExitCode, nil
 }
 
+// getExecExitCode perform an inspect on the exec command. It returns
+// the exit code.
+func getExecExitCode(cli *DockerCli, execId string) (int, error) {
+	stream, _, err := cli.call("GET", "/exec/"+execId+"/json", nil, false)
+	if err != nil {
+		// If we can't connect, then the daemon probably died.
+		if err != ErrConnectionRefused {
+			return -1, err
+		}
+		return -1, nil
+	}
+	var c engine.Env
+	if err := c.Decode(stream); err != nil {
+		return -1, err
+	}
+	return c.GetInt("ExitCode"), nil
+}
+
 func readBody(stream io.ReadCloser, statusCode int, err error) ([]byte, int, error) {
 	if stream != nil {
 		defer stream.Close()
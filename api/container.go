@@ -16,3 +16,26 @@ type Container struct {
 		Ports nat.PortMap
 	}
 }
+
+// ContainerStats holds the subset of a stats sample rendered by the client
+type ContainerStats struct {
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	CpuUsage    struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	MemoryUsage struct {
+		Usage uint64 `json:"usage"`
+		Limit uint64 `json:"limit"`
+	} `json:"memory_usage"`
+	BlkioUsage struct {
+		IoServiceBytes []struct {
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes"`
+	} `json:"blkio_usage"`
+	Network struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"network"`
+}
@@ -4,6 +4,8 @@ import (
 	"net"
 
 	"github.com/dotcloud/docker/engine"
+	"github.com/dotcloud/docker/pkg/ulimit"
+	"github.com/dotcloud/docker/runconfig"
 	"github.com/dotcloud/docker/runtime/networkdriver"
 )
 
@@ -23,11 +25,16 @@ type Config struct {
 	DefaultIp                   net.IP
 	BridgeIface                 string
 	BridgeIP                    string
+	BridgeIPv6                  string
 	InterContainerCommunication bool
 	GraphDriver                 string
 	ExecDriver                  string
 	Mtu                         int
 	DisableNetwork              bool
+	LogConfig                   runconfig.LogConfig // default log driver of the containers
+	UsernsRemap                 string              // user whose subordinate ids the containers are remapped to
+	Ulimits                     []*ulimit.Ulimit    // default ulimits of the containers
+	CgroupSlice                 string              // systemd slice of the scopes of the containers
 }
 
 // ConfigFromJob creates and returns a new DaemonConfig object
@@ -40,11 +47,14 @@ func ConfigFromJob(job *engine.Job) *Config {
 		EnableIptables:              job.GetenvBool("EnableIptables"),
 		EnableIpForward:             job.GetenvBool("EnableIpForward"),
 		BridgeIP:                    job.Getenv("BridgeIP"),
+		BridgeIPv6:                  job.Getenv("BridgeIPv6"),
 		BridgeIface:                 job.Getenv("BridgeIface"),
 		DefaultIp:                   net.ParseIP(job.Getenv("DefaultIp")),
 		InterContainerCommunication: job.GetenvBool("InterContainerCommunication"),
 		GraphDriver:                 job.Getenv("GraphDriver"),
 		ExecDriver:                  job.Getenv("ExecDriver"),
+		UsernsRemap:                 job.Getenv("UsernsRemap"),
+		CgroupSlice:                 job.Getenv("CgroupSlice"),
 	}
 	if dns := job.GetenvList("Dns"); dns != nil {
 		config.Dns = dns
@@ -55,6 +65,9 @@ func ConfigFromJob(job *engine.Job) *Config {
 		config.Mtu = GetDefaultNetworkMtu()
 	}
 	config.DisableNetwork = config.BridgeIface == DisableNetworkBridge
+	config.LogConfig.Type = job.Getenv("LogDriver")
+	job.GetenvJson("LogOpts", &config.LogConfig.Config)
+	job.GetenvJson("Ulimits", &config.Ulimits)
 
 	return config
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/dotcloud/docker/engine"
 	"github.com/dotcloud/docker/opts"
 	flag "github.com/dotcloud/docker/pkg/mflag"
+	"github.com/dotcloud/docker/runconfig"
 	"github.com/dotcloud/docker/sysinit"
 	"github.com/dotcloud/docker/utils"
 )
@@ -30,11 +31,12 @@ func main() {
 		flAutoRestart        = flag.Bool([]string{"r", "-restart"}, true, "Restart previously running containers")
 		bridgeName           = flag.String([]string{"b", "-bridge"}, "", "Attach containers to a pre-existing network bridge; use 'none' to disable container networking")
 		bridgeIp             = flag.String([]string{"#bip", "-bip"}, "", "Use this CIDR notation address for the network bridge's IP, not compatible with -b")
+		bridgeIpv6           = flag.String([]string{"-bip6"}, "", "Use this CIDR notation IPv6 address for the network bridge and give IPv6 addresses to the containers")
 		pidfile              = flag.String([]string{"p", "-pidfile"}, "/var/run/docker.pid", "Path to use for daemon PID file")
 		flRoot               = flag.String([]string{"g", "-graph"}, "/var/lib/docker", "Path to use as the root of the docker runtime")
 		flSocketGroup        = flag.String([]string{"G", "-group"}, "docker", "Group to assign the unix socket specified by -H when running in daemon mode; use '' (the empty string) to disable setting of a group")
 		flEnableCors         = flag.Bool([]string{"#api-enable-cors", "-api-enable-cors"}, false, "Enable CORS headers in the remote API")
-		flDns                = opts.NewListOpts(opts.ValidateIp4Address)
+		flDns                = opts.NewListOpts(opts.ValidateIPAddress)
 		flEnableIptables     = flag.Bool([]string{"#iptables", "-iptables"}, true, "Enable Docker's addition of iptables rules")
 		flEnableIpForward    = flag.Bool([]string{"#ip-forward", "-ip-forward"}, true, "Enable net.ipv4.ip_forward")
 		flDefaultIp          = flag.String([]string{"#ip", "-ip"}, "0.0.0.0", "Default IP address to use when binding container ports")
@@ -43,9 +45,16 @@ func main() {
 		flExecDriver         = flag.String([]string{"e", "-exec-driver"}, "native", "Force the docker runtime to use a specific exec driver")
 		flHosts              = opts.NewListOpts(api.ValidateHost)
 		flMtu                = flag.Int([]string{"#mtu", "-mtu"}, 0, "Set the containers network MTU; if no value is provided: default to the default route MTU or 1500 if no default route is available")
+		flLogDriver          = flag.String([]string{"-log-driver"}, "json-file", "Default log driver of the containers (json-file, syslog, journald or none)")
+		flLogOpts            = opts.NewListOpts(nil)
+		flUlimits            = opts.NewListOpts(nil)
+		flUsernsRemap        = flag.String([]string{"-userns-remap"}, "", "Remap the root of the containers to the first subordinate uid and gid of this user (name or uid) in /etc/subuid and /etc/subgid")
+		flCgroupSlice        = flag.String([]string{"-cgroup-slice"}, "system.slice", "Systemd slice of the scopes of the containers when the native exec driver uses systemd")
 	)
 	flag.Var(&flDns, []string{"#dns", "-dns"}, "Force docker to use specific DNS servers")
 	flag.Var(&flHosts, []string{"H", "-host"}, "tcp://host:port, unix://path/to/socket, fd://* or fd://socketfd to use in daemon mode. Multiple sockets can be specified")
+	flag.Var(&flLogOpts, []string{"-log-opt"}, "Default log driver options of the containers (e.g. --log-opt syslog-address=udp://10.0.0.1:514)")
+	flag.Var(&flUlimits, []string{"-default-ulimit"}, "Default ulimits of the containers (e.g. --default-ulimit nofile=1024:2048)")
 
 	flag.Parse()
 
@@ -79,6 +88,15 @@ func main() {
 			return
 		}
 
+		logOpts, err := runconfig.ParseLogOpts(flLogOpts.GetAll())
+		if err != nil {
+			log.Fatal(err)
+		}
+		ulimits, err := runconfig.ParseUlimits(flUlimits.GetAll())
+		if err != nil {
+			log.Fatal(err)
+		}
+
 		// set up the TempDir to use a canonical path
 		tmp := os.TempDir()
 		realTmp, err := utils.ReadSymlinkedDirectory(tmp)
@@ -119,11 +137,17 @@ func main() {
 			job.SetenvBool("EnableIpForward", *flEnableIpForward)
 			job.Setenv("BridgeIface", *bridgeName)
 			job.Setenv("BridgeIP", *bridgeIp)
+			job.Setenv("BridgeIPv6", *bridgeIpv6)
 			job.Setenv("DefaultIp", *flDefaultIp)
 			job.SetenvBool("InterContainerCommunication", *flInterContainerComm)
 			job.Setenv("GraphDriver", *flGraphDriver)
 			job.Setenv("ExecDriver", *flExecDriver)
 			job.SetenvInt("Mtu", *flMtu)
+			job.Setenv("LogDriver", *flLogDriver)
+			job.SetenvJson("LogOpts", logOpts)
+			job.Setenv("UsernsRemap", *flUsernsRemap)
+			job.SetenvJson("Ulimits", ulimits)
+			job.Setenv("CgroupSlice", *flCgroupSlice)
 			if err := job.Run(); err != nil {
 				log.Fatal(err)
 			}
@@ -5,6 +5,7 @@ import (
 	"github.com/dotcloud/docker/archive"
 	"github.com/dotcloud/docker/dockerversion"
 	"github.com/dotcloud/docker/image"
+	"github.com/dotcloud/docker/pkg/libcontainer"
 	"github.com/dotcloud/docker/runconfig"
 	"github.com/dotcloud/docker/runtime/graphdriver"
 	"github.com/dotcloud/docker/utils"
@@ -24,6 +25,8 @@ type Graph struct {
 	Root    string
 	idIndex *utils.TruncIndex
 	driver  graphdriver.Driver
+	uidMaps []libcontainer.IdMap // the files of the layers are given to these uids and gids of the host
+	gidMaps []libcontainer.IdMap
 }
 
 // NewGraph instantiates a new graph at the given root path in the filesystem.
@@ -49,6 +52,18 @@ func NewGraph(root string, driver graphdriver.Driver) (*Graph, error) {
 	return graph, nil
 }
 
+// SetIdMappings chowns the layers registered from now on to the ids of the
+// host the ids of the containers are mapped to, nil to keep their ids
+func (graph *Graph) SetIdMappings(uidMaps, gidMaps []libcontainer.IdMap) {
+	graph.uidMaps = uidMaps
+	graph.gidMaps = gidMaps
+}
+
+// IdMappings returns the ids of the host the layers are chowned to
+func (graph *Graph) IdMappings() (uidMaps, gidMaps []libcontainer.IdMap) {
+	return graph.uidMaps, graph.gidMaps
+}
+
 func (graph *Graph) restore() error {
 	dir, err := ioutil.ReadDir(graph.Root)
 	if err != nil {
@@ -202,6 +217,11 @@ func (graph *Graph) Register(jsonData []byte, layerData archive.ArchiveReader, i
 	if err := image.StoreImage(img, jsonData, layerData, tmp, rootfs); err != nil {
 		return err
 	}
+	if graph.uidMaps != nil {
+		if err := graphdriver.ChownLayer(graph.driver, img.ID, graph.uidMaps, graph.gidMaps); err != nil {
+			return err
+		}
+	}
 	// Commit
 	if err := os.Rename(tmp, graph.ImageRoot(img.ID)); err != nil {
 		return err
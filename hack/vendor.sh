@@ -47,6 +47,10 @@ clone git github.com/gorilla/mux 9b36453141c
 
 clone git github.com/syndtr/gocapability 3454319be2
 
+clone git github.com/godbus/dbus v4.1.0
+
+clone git github.com/coreos/go-systemd 40e2722dffea
+
 clone hg code.google.com/p/go.net 84a4013f96e0
 
 clone hg code.google.com/p/gosqlite 74691fb6f837
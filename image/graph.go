@@ -1,6 +1,7 @@
 package image
 
 import (
+	"github.com/dotcloud/docker/pkg/libcontainer"
 	"github.com/dotcloud/docker/runtime/graphdriver"
 )
 
@@ -8,4 +9,5 @@ type Graph interface {
 	Get(id string) (*Image, error)
 	ImageRoot(id string) string
 	Driver() graphdriver.Driver
+	IdMappings() (uidMaps, gidMaps []libcontainer.IdMap)
 }
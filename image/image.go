@@ -150,10 +150,21 @@ func jsonPath(root string) string {
 }
 
 // TarLayer returns a tar archive of the image's filesystem layer.
-func (img *Image) TarLayer() (arch archive.Archive, err error) {
+// The files of a layer chowned to the ids of the host are given back
+// to the ids of the containers.
+func (img *Image) TarLayer() (archive.Archive, error) {
 	if img.graph == nil {
 		return nil, fmt.Errorf("Can't load storage driver for unregistered image %s", img.ID)
 	}
+	arch, err := img.tarLayer()
+	if err != nil {
+		return nil, err
+	}
+	uidMaps, gidMaps := img.graph.IdMappings()
+	return graphdriver.UnmapArchive(arch, uidMaps, gidMaps), nil
+}
+
+func (img *Image) tarLayer() (arch archive.Archive, err error) {
 	driver := img.graph.Driver()
 	if differ, ok := driver.(graphdriver.Differ); ok {
 		return differ.Diff(img.ID)
@@ -6,13 +6,14 @@ package nat
 import (
 	"fmt"
 	"github.com/dotcloud/docker/utils"
+	"net"
 	"strconv"
 	"strings"
 )
 
 const (
 	PortSpecTemplate       = "ip:hostPort:containerPort"
-	PortSpecTemplateFormat = "ip:hostPort:containerPort | ip::containerPort | hostPort:containerPort"
+	PortSpecTemplateFormat = "ip:hostPort:containerPort | ip::containerPort | hostPort:containerPort | [ip6]:hostPort:containerPort"
 )
 
 type PortBinding struct {
@@ -39,6 +40,24 @@ func ParsePort(rawPort string) (int, error) {
 	return int(port), nil
 }
 
+// ParsePortRange parses a port or a range of ports, start-end,
+// and returns the first and the last port of the range
+func ParsePortRange(rawRange string) (int, int, error) {
+	parts := strings.SplitN(rawRange, "-", 2)
+	start, err := ParsePort(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid port range: %s", rawRange)
+	}
+	if len(parts) == 1 {
+		return start, start, nil
+	}
+	end, err := ParsePort(parts[1])
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("Invalid port range: %s", rawRange)
+	}
+	return start, end, nil
+}
+
 func (p Port) Proto() string {
 	parts := strings.Split(string(p), "/")
 	if len(parts) == 1 {
@@ -59,7 +78,7 @@ func (p Port) Int() int {
 	return i
 }
 
-// Splits a port in the format of port/proto
+// Splits a port in the format of port/proto into its proto and its port
 func SplitProtoPort(rawPort string) (string, string) {
 	parts := strings.Split(rawPort, "/")
 	l := len(parts)
@@ -69,11 +88,13 @@ func SplitProtoPort(rawPort string) (string, string) {
 	if l == 1 {
 		return "tcp", rawPort
 	}
-	return parts[0], parts[1]
+	return parts[1], parts[0]
 }
 
 // We will receive port specs in the format of ip:public:private/proto and these need to be
-// parsed in the internal types
+// parsed in the internal types. The public and private ports can be ranges of the same
+// size, start-end, each port of the private range is bound to the port of the public one
+// at the same offset.
 func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding, error) {
 	var (
 		exposedPorts = make(map[Port]struct{}, len(ports))
@@ -81,12 +102,26 @@ func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding,
 	)
 
 	for _, rawPort := range ports {
-		proto := "tcp"
+		var (
+			proto  = "tcp"
+			rawIp6 string
+		)
 
 		if i := strings.LastIndex(rawPort, "/"); i != -1 {
 			proto = rawPort[i+1:]
 			rawPort = rawPort[:i]
 		}
+		if strings.HasPrefix(rawPort, "[") {
+			// an IPv6 address of the host is in brackets: [ip]:hostPort:containerPort
+			i := strings.Index(rawPort, "]:")
+			if i == -1 || !strings.Contains(rawPort[i+2:], ":") {
+				return nil, nil, fmt.Errorf("Invalid port format, expected [ip]:hostPort:containerPort: %s", rawPort)
+			}
+			if rawIp6 = rawPort[1:i]; net.ParseIP(rawIp6) == nil {
+				return nil, nil, fmt.Errorf("Invalid IPv6 address: %s", rawIp6)
+			}
+			rawPort = ":" + rawPort[i+2:]
+		}
 		if !strings.Contains(rawPort, ":") {
 			rawPort = fmt.Sprintf("::%s", rawPort)
 		} else if len(strings.Split(rawPort, ":")) == 2 {
@@ -103,31 +138,52 @@ func ParsePortSpecs(ports []string) (map[Port]struct{}, map[Port][]PortBinding,
 			rawIp         = parts["ip"]
 			hostPort      = parts["hostPort"]
 		)
+		if rawIp6 != "" {
+			rawIp = rawIp6
+		}
 
 		if containerPort == "" {
 			return nil, nil, fmt.Errorf("No port specified: %s<empty>", rawPort)
 		}
-		if _, err := strconv.ParseUint(containerPort, 10, 16); err != nil {
+		startPort, endPort, err := ParsePortRange(containerPort)
+		if err != nil {
 			return nil, nil, fmt.Errorf("Invalid containerPort: %s", containerPort)
 		}
-		if _, err := strconv.ParseUint(hostPort, 10, 16); hostPort != "" && err != nil {
-			return nil, nil, fmt.Errorf("Invalid hostPort: %s", hostPort)
+		var startHostPort, endHostPort int
+		if hostPort != "" {
+			if startHostPort, endHostPort, err = ParsePortRange(hostPort); err != nil {
+				return nil, nil, fmt.Errorf("Invalid hostPort: %s", hostPort)
+			}
+			if endHostPort-startHostPort != endPort-startPort {
+				return nil, nil, fmt.Errorf("Invalid ranges specified for container and host ports: %s and %s", containerPort, hostPort)
+			}
 		}
 
-		port := NewPort(proto, containerPort)
-		if _, exists := exposedPorts[port]; !exists {
-			exposedPorts[port] = struct{}{}
-		}
-
-		binding := PortBinding{
-			HostIp:   rawIp,
-			HostPort: hostPort,
-		}
-		bslice, exists := bindings[port]
-		if !exists {
-			bslice = []PortBinding{}
+		// a single port is kept as it was specified
+		isRange := strings.Contains(containerPort, "-") || strings.Contains(hostPort, "-")
+		for i := 0; i <= endPort-startPort; i++ {
+			if isRange {
+				containerPort = strconv.Itoa(startPort + i)
+				if hostPort != "" {
+					hostPort = strconv.Itoa(startHostPort + i)
+				}
+			}
+
+			port := NewPort(proto, containerPort)
+			if _, exists := exposedPorts[port]; !exists {
+				exposedPorts[port] = struct{}{}
+			}
+
+			binding := PortBinding{
+				HostIp:   rawIp,
+				HostPort: hostPort,
+			}
+			bslice, exists := bindings[port]
+			if !exists {
+				bslice = []PortBinding{}
+			}
+			bindings[port] = append(bslice, binding)
 		}
-		bindings[port] = append(bslice, binding)
 	}
 	return exposedPorts, bindings, nil
 }
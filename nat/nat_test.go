@@ -0,0 +1,59 @@
+package nat
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestParsePortSpecsIPv6(t *testing.T) {
+	_, bindings, err := ParsePortSpecs([]string{"[2001:db8::1]:8080:80", "[::]::53/udp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := bindings[NewPort("tcp", "80")]; len(b) != 1 || b[0].HostIp != "2001:db8::1" || b[0].HostPort != "8080" {
+		t.Fatalf("Expected 80/tcp to be bound on [2001:db8::1]:8080, got %v", b)
+	}
+	if b := bindings[NewPort("udp", "53")]; len(b) != 1 || b[0].HostIp != "::" || b[0].HostPort != "" {
+		t.Fatalf("Expected 53/udp to be bound on [::], got %v", b)
+	}
+
+	for _, spec := range []string{"[2001:db8::1]:80", "[2001:db8::1:80:80", "[2001:db8::zz]:80:80"} {
+		if _, _, err := ParsePortSpecs([]string{spec}); err == nil {
+			t.Fatalf("Expected an error parsing %s", spec)
+		}
+	}
+}
+
+func TestParsePortSpecsRange(t *testing.T) {
+	exposed, bindings, err := ParsePortSpecs([]string{"10000-10002:20000-20002/udp", "7000-7001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exposed) != 5 {
+		t.Fatalf("Expected 5 exposed ports, got %v", exposed)
+	}
+	for i := 0; i < 3; i++ {
+		port := NewPort("udp", strconv.Itoa(20000+i))
+		if b := bindings[port]; len(b) != 1 || b[0].HostPort != strconv.Itoa(10000+i) {
+			t.Fatalf("Expected %s to be bound on %d, got %v", port, 10000+i, b)
+		}
+	}
+	if b := bindings[NewPort("tcp", "7001")]; len(b) != 1 || b[0].HostPort != "" {
+		t.Fatalf("Expected 7001/tcp to be bound on a dynamic port, got %v", b)
+	}
+
+	// a range of a single port
+	_, bindings, err = ParsePortSpecs([]string{"8080-8080:80"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := bindings[NewPort("tcp", "80")]; len(b) != 1 || b[0].HostPort != "8080" {
+		t.Fatalf("Expected 80/tcp to be bound on 8080, got %v", b)
+	}
+
+	for _, spec := range []string{"10000-10002:20000-20001", "10000:20000-20001", "20001-20000", "20000-"} {
+		if _, _, err := ParsePortSpecs([]string{spec}); err == nil {
+			t.Fatalf("Expected an error parsing %s", spec)
+		}
+	}
+}
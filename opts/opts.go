@@ -3,6 +3,7 @@ package opts
 import (
 	"fmt"
 	"github.com/dotcloud/docker/utils"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -136,3 +137,11 @@ func ValidateIp4Address(val string) (string, error) {
 	}
 	return "", fmt.Errorf("%s is not an ip4 address", val)
 }
+
+// ValidateIPAddress accepts an IPv4 or an IPv6 address
+func ValidateIPAddress(val string) (string, error) {
+	if ip := net.ParseIP(strings.TrimSpace(val)); ip != nil {
+		return ip.String(), nil
+	}
+	return "", fmt.Errorf("%s is not an ip address", val)
+}
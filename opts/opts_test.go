@@ -22,3 +22,20 @@ func TestValidateIP4(t *testing.T) {
 	}
 
 }
+
+func TestValidateIPAddress(t *testing.T) {
+	for val, expected := range map[string]string{
+		`1.2.3.4`:            "1.2.3.4",
+		`2001:4860:4860::88`: "2001:4860:4860::88",
+		`::1 `:               "::1",
+	} {
+		if ret, err := ValidateIPAddress(val); err != nil || ret != expected {
+			t.Fatalf("ValidateIPAddress(%q) got %s %s", val, ret, err)
+		}
+	}
+	for _, val := range []string{`127`, `2001:4860::88::1`, `random invalid string`} {
+		if ret, err := ValidateIPAddress(val); err == nil || ret != "" {
+			t.Fatalf("ValidateIPAddress(%q) got %s %s", val, ret, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package cgroups
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"github.com/dotcloud/docker/pkg/mount"
 	"io"
@@ -10,8 +11,25 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// FreezerState is the state of the processes of a cgroup in the freezer subsystem
+type FreezerState string
+
+const (
+	Undefined FreezerState = ""
+	Frozen    FreezerState = "FROZEN"
+	Thawed    FreezerState = "THAWED"
+)
+
+// subsystems are the subsystems the cgroup is set up in
+var subsystems = []string{"memory", "devices", "cpu", "cpuset", "blkio", "pids", "freezer"}
+
+// freezerTimeout is how long the freezer state is waited for, a task stuck
+// in an uninterruptible sleep keeps the cgroup FREEZING
+var freezerTimeout = 10 * time.Second
+
 type Cgroup struct {
 	Name   string `json:"name,omitempty"`
 	Parent string `json:"parent,omitempty"`
@@ -20,6 +38,39 @@ type Cgroup struct {
 	Memory       int64 `json:"memory,omitempty"`        // Memory limit (in bytes)
 	MemorySwap   int64 `json:"memory_swap,omitempty"`   // Total memory usage (memory + swap); set `-1' to disable swap
 	CpuShares    int64 `json:"cpu_shares,omitempty"`    // CPU shares (relative weight vs. other containers)
+
+	MemoryReservation int64  `json:"memory_reservation,omitempty"` // Memory soft limit (in bytes), the memory limit when 0
+	KernelMemory      int64  `json:"kernel_memory,omitempty"`      // Kernel memory limit (in bytes)
+	CpuPeriod         int64  `json:"cpu_period,omitempty"`         // CFS period (in microseconds)
+	CpuQuota          int64  `json:"cpu_quota,omitempty"`          // CPU time allowed in each CFS period (in microseconds)
+	CpusetCpus        string `json:"cpuset_cpus,omitempty"`        // CPUs allowed, e.g. 0-2,6
+	CpusetMems        string `json:"cpuset_mems,omitempty"`        // memory nodes allowed, e.g. 0,1
+	BlkioWeight       int64  `json:"blkio_weight,omitempty"`       // Block IO weight (relative weight vs. other containers, 10 to 1000)
+	PidsLimit         int64  `json:"pids_limit,omitempty"`         // Maximum number of processes
+
+	BlkioThrottleReadBpsDevice   []*ThrottleDevice `json:"blkio_throttle_read_bps_device,omitempty"`   // bytes read per second from the devices
+	BlkioThrottleWriteBpsDevice  []*ThrottleDevice `json:"blkio_throttle_write_bps_device,omitempty"`  // bytes written per second to the devices
+	BlkioThrottleReadIOpsDevice  []*ThrottleDevice `json:"blkio_throttle_read_iops_device,omitempty"`  // reads per second from the devices
+	BlkioThrottleWriteIOpsDevice []*ThrottleDevice `json:"blkio_throttle_write_iops_device,omitempty"` // writes per second to the devices
+
+	AllowedDevices []string `json:"allowed_devices,omitempty"` // devices.allow entries added to the defaults, without device access
+
+	Freezer FreezerState `json:"freezer,omitempty"` // set the freeze value for the process
+
+	Systemd bool   `json:"systemd,omitempty"` // the cgroup is a transient scope of systemd, see UseSystemd
+	Slice   string `json:"slice,omitempty"`   // slice of the scope, system.slice when empty
+}
+
+// ThrottleDevice limits the rate of the IO of a block device
+type ThrottleDevice struct {
+	Major int64  `json:"major"`
+	Minor int64  `json:"minor"`
+	Rate  uint64 `json:"rate"`
+}
+
+// String returns the entry of the device in the blkio.throttle files
+func (t *ThrottleDevice) String() string {
+	return fmt.Sprintf("%d:%d %d", t.Major, t.Minor, t.Rate)
 }
 
 // https://www.kernel.org/doc/Documentation/cgroups/cgroups.txt
@@ -63,6 +114,9 @@ func GetInitCgroupDir(subsystem string) (string, error) {
 }
 
 func (c *Cgroup) Path(root, subsystem string) (string, error) {
+	if c.Systemd {
+		return c.systemdPath(root, subsystem)
+	}
 	cgroup := c.Name
 	if c.Parent != "" {
 		cgroup = filepath.Join(c.Parent, cgroup)
@@ -89,21 +143,40 @@ func (c *Cgroup) Join(root, subsystem string, pid int) (string, error) {
 }
 
 func (c *Cgroup) Cleanup(root string) error {
-	get := func(subsystem string) string {
-		path, _ := c.Path(root, subsystem)
-		return path
+	if c.Systemd {
+		if err := c.cleanupSystemd(); err != nil {
+			return err
+		}
 	}
-
-	for _, path := range []string{
-		get("memory"),
-		get("devices"),
-		get("cpu"),
-	} {
-		os.RemoveAll(path)
+	for _, subsystem := range subsystems {
+		if path, err := c.Path(root, subsystem); err == nil {
+			os.RemoveAll(path)
+		}
 	}
 	return nil
 }
 
+// Paths returns the directories of the cgroup in the hierarchies of the
+// subsystems mounted on the host where the cgroup exists, by subsystem
+func (c *Cgroup) Paths() (map[string]string, error) {
+	paths := make(map[string]string)
+	for _, subsystem := range append([]string{"cpuacct"}, subsystems...) {
+		cgroupRoot, err := FindCgroupMountpoint(subsystem)
+		if err != nil {
+			// the subsystem is not mounted
+			continue
+		}
+		path, err := c.Path(filepath.Dir(cgroupRoot), subsystem)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); err == nil {
+			paths[subsystem] = path
+		}
+	}
+	return paths, nil
+}
+
 func parseCgroupFile(subsystem string, r io.Reader) (string, error) {
 	s := bufio.NewScanner(r)
 	for s.Scan() {
@@ -141,6 +214,13 @@ func (c *Cgroup) Apply(pid int) error {
 	if _, err := os.Stat(cgroupRoot); err != nil {
 		return fmt.Errorf("cgroups fs not found")
 	}
+	if c.Systemd {
+		return c.applySystemd(cgroupRoot, pid)
+	}
+	return c.applyFs(cgroupRoot, pid)
+}
+
+func (c *Cgroup) applyFs(cgroupRoot string, pid int) error {
 	if err := c.setupDevices(cgroupRoot, pid); err != nil {
 		return err
 	}
@@ -150,9 +230,151 @@ func (c *Cgroup) Apply(pid int) error {
 	if err := c.setupCpu(cgroupRoot, pid); err != nil {
 		return err
 	}
+	if err := c.setupCpuset(cgroupRoot, pid); err != nil {
+		return err
+	}
+	if err := c.setupBlkio(cgroupRoot, pid); err != nil {
+		return err
+	}
+	if err := c.setupPids(cgroupRoot, pid); err != nil {
+		return err
+	}
+	if err := c.setupFreezer(cgroupRoot, pid); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Freeze changes the freezer state of all the processes in the cgroup
+// and blocks until the kernel reports the new state
+func (c *Cgroup) Freeze(state FreezerState) error {
+	cgroupRoot, err := FindCgroupMountpoint("freezer")
+	if err != nil {
+		return err
+	}
+	dir, err := c.Path(filepath.Dir(cgroupRoot), "freezer")
+	if err != nil {
+		return err
+	}
+	if err := setFreezerState(dir, state); err != nil {
+		return err
+	}
+	c.Freezer = state
 	return nil
 }
 
+// Update writes the memory and cpu limits of c to the cgroup of the running
+// processes. The limits are applied all together: if one of them cannot be
+// written, the values written before it are restored and an error is returned.
+func (c *Cgroup) Update() error {
+	cgroupRoot, err := FindCgroupMountpoint("cpu")
+	if err != nil {
+		return err
+	}
+	cgroupRoot = filepath.Dir(cgroupRoot)
+
+	return c.update(func(subsystem string) (string, error) {
+		return c.existingPath(cgroupRoot, subsystem)
+	}, c.updateSystemd)
+}
+
+// update writes the limits to the directories of the cgroup returned by path.
+// The properties of the systemd scope are set by updateSystemd once all the
+// files are written, the files are restored when systemd fails to set them.
+func (c *Cgroup) update(path func(subsystem string) (string, error), updateSystemd func() error) error {
+	var values []cgroupValue
+	if c.Memory != 0 || c.MemorySwap > 0 {
+		dir, err := path("memory")
+		if err != nil {
+			return err
+		}
+		if c.Memory != 0 {
+			values = append(values,
+				cgroupValue{dir, "memory.limit_in_bytes", strconv.FormatInt(c.Memory, 10)},
+				cgroupValue{dir, "memory.soft_limit_in_bytes", strconv.FormatInt(c.memorySoftLimit(), 10)},
+			)
+		}
+		if c.MemorySwap != -1 {
+			current, err := readUint(dir, "memory.limit_in_bytes")
+			if err != nil {
+				return err
+			}
+			memsw := cgroupValue{dir, "memory.memsw.limit_in_bytes", strconv.FormatInt(c.memorySwapLimit(), 10)}
+			// the memory limit cannot be larger than memory + swap,
+			// the latter is raised first when the memory is increased
+			if uint64(c.Memory) > current {
+				values = append([]cgroupValue{memsw}, values...)
+			} else {
+				values = append(values, memsw)
+			}
+		}
+	}
+	if c.CpuShares != 0 {
+		dir, err := path("cpu")
+		if err != nil {
+			return err
+		}
+		values = append(values, cgroupValue{dir, "cpu.shares", strconv.FormatInt(c.CpuShares, 10)})
+	}
+	previous, err := writeValues(values)
+	if err != nil {
+		return err
+	}
+	if c.Systemd {
+		if err := updateSystemd(); err != nil {
+			writeValues(previous)
+			return err
+		}
+	}
+	return nil
+}
+
+// existingPath returns the path of the cgroup for the subsystem, the processes
+// have to be in the cgroup already for its limits to be updated
+func (c *Cgroup) existingPath(root, subsystem string) (string, error) {
+	dir, err := c.Path(root, subsystem)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("cgroup %s not found for %s, the limits cannot be changed while the processes are running", c.Name, subsystem)
+	}
+	return dir, nil
+}
+
+// cgroupValue is the content of a file of a cgroup
+type cgroupValue struct {
+	dir   string
+	file  string
+	value string
+}
+
+// writeValues writes the values in order and returns the previous content of
+// the files, in the order to write it back. When a value cannot be written,
+// the previous content of the files already written is restored.
+func writeValues(values []cgroupValue) (previous []cgroupValue, err error) {
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, v := range previous {
+			writeFile(v.dir, v.file, v.value)
+		}
+	}()
+
+	for _, v := range values {
+		data, err := ioutil.ReadFile(filepath.Join(v.dir, v.file))
+		if err != nil {
+			return previous, err
+		}
+		if err := writeFile(v.dir, v.file, v.value); err != nil {
+			return previous, fmt.Errorf("cannot set %s to %s: %s", v.file, v.value, err)
+		}
+		previous = append([]cgroupValue{{v.dir, v.file, strings.TrimSpace(string(data))}}, previous...)
+	}
+	return previous, nil
+}
+
 func (c *Cgroup) setupDevices(cgroupRoot string, pid int) (err error) {
 	if !c.DeviceAccess {
 		dir, err := c.Join(cgroupRoot, "devices", pid)
@@ -193,6 +415,7 @@ func (c *Cgroup) setupDevices(cgroupRoot string, pid int) (err error) {
 			// tuntap
 			"c 10:200 rwm",
 		}
+		allow = append(allow, c.AllowedDevices...)
 
 		for _, val := range allow {
 			if err := writeFile(dir, "devices.allow", val); err != nil {
@@ -204,29 +427,42 @@ func (c *Cgroup) setupDevices(cgroupRoot string, pid int) (err error) {
 }
 
 func (c *Cgroup) setupMemory(cgroupRoot string, pid int) (err error) {
-	if c.Memory != 0 || c.MemorySwap != 0 {
-		dir, err := c.Join(cgroupRoot, "memory", pid)
+	if c.Memory != 0 || c.MemorySwap != 0 || c.MemoryReservation != 0 || c.KernelMemory != 0 {
+		dir, err := c.Path(cgroupRoot, "memory")
 		if err != nil {
 			return err
 		}
+		if err := os.MkdirAll(dir, 0755); err != nil && !os.IsExist(err) {
+			return err
+		}
 		defer func() {
 			if err != nil {
 				os.RemoveAll(dir)
 			}
 		}()
 
+		// the kernel memory can only be limited before the cgroup has tasks
+		if c.KernelMemory != 0 {
+			if err := writeFile(dir, "memory.kmem.limit_in_bytes", strconv.FormatInt(c.KernelMemory, 10)); err != nil {
+				return err
+			}
+		}
+		if _, err := c.Join(cgroupRoot, "memory", pid); err != nil {
+			return err
+		}
+
 		if c.Memory != 0 {
 			if err := writeFile(dir, "memory.limit_in_bytes", strconv.FormatInt(c.Memory, 10)); err != nil {
 				return err
 			}
-			if err := writeFile(dir, "memory.soft_limit_in_bytes", strconv.FormatInt(c.Memory, 10)); err != nil {
+		}
+		if c.Memory != 0 || c.MemoryReservation != 0 {
+			if err := writeFile(dir, "memory.soft_limit_in_bytes", strconv.FormatInt(c.memorySoftLimit(), 10)); err != nil {
 				return err
 			}
 		}
-		// By default, MemorySwap is set to twice the size of RAM.
-		// If you want to omit MemorySwap, set it to `-1'.
-		if c.MemorySwap != -1 {
-			if err := writeFile(dir, "memory.memsw.limit_in_bytes", strconv.FormatInt(c.Memory*2, 10)); err != nil {
+		if c.MemorySwap != -1 && (c.Memory != 0 || c.MemorySwap > 0) {
+			if err := writeFile(dir, "memory.memsw.limit_in_bytes", strconv.FormatInt(c.memorySwapLimit(), 10)); err != nil {
 				return err
 			}
 		}
@@ -234,6 +470,25 @@ func (c *Cgroup) setupMemory(cgroupRoot string, pid int) (err error) {
 	return nil
 }
 
+// memorySoftLimit returns the memory reservation, or the memory limit
+// when the container has no reservation
+func (c *Cgroup) memorySoftLimit() int64 {
+	if c.MemoryReservation != 0 {
+		return c.MemoryReservation
+	}
+	return c.Memory
+}
+
+// memorySwapLimit returns the total memory usage (memory + swap) allowed.
+// By default, MemorySwap is set to twice the size of RAM.
+// If you want to omit MemorySwap, set it to `-1'.
+func (c *Cgroup) memorySwapLimit() int64 {
+	if c.MemorySwap > 0 {
+		return c.MemorySwap
+	}
+	return c.Memory * 2
+}
+
 func (c *Cgroup) setupCpu(cgroupRoot string, pid int) (err error) {
 	// We always want to join the cpu group, to allow fair cpu scheduling
 	// on a container basis
@@ -246,5 +501,184 @@ func (c *Cgroup) setupCpu(cgroupRoot string, pid int) (err error) {
 			return err
 		}
 	}
+	// the period is set first, the quota is checked against it
+	if c.CpuPeriod != 0 {
+		if err := writeFile(dir, "cpu.cfs_period_us", strconv.FormatInt(c.CpuPeriod, 10)); err != nil {
+			return err
+		}
+	}
+	if c.CpuQuota != 0 {
+		if err := writeFile(dir, "cpu.cfs_quota_us", strconv.FormatInt(c.CpuQuota, 10)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func (c *Cgroup) setupCpuset(cgroupRoot string, pid int) (err error) {
+	if c.CpusetCpus == "" && c.CpusetMems == "" {
+		return nil
+	}
+	dir, err := c.Path(cgroupRoot, "cpuset")
+	if err != nil {
+		return err
+	}
+	if err := ensureCpuset(filepath.Join(cgroupRoot, "cpuset"), dir); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	// the tasks can only join a cpuset with cpus and memory nodes
+	if c.CpusetCpus != "" {
+		if err := writeFile(dir, "cpuset.cpus", c.CpusetCpus); err != nil {
+			return err
+		}
+	}
+	if c.CpusetMems != "" {
+		if err := writeFile(dir, "cpuset.mems", c.CpusetMems); err != nil {
+			return err
+		}
+	}
+	_, err = c.Join(cgroupRoot, "cpuset", pid)
+	return err
+}
+
+// ensureCpuset creates the cpusets from root to dir, the new cpusets
+// have no cpus and no memory nodes and get the ones of their parent
+func ensureCpuset(root, dir string) error {
+	if dir == root {
+		return nil
+	}
+	parent := filepath.Dir(dir)
+	if parent == dir || !strings.HasPrefix(dir, root) {
+		return fmt.Errorf("%s is not in the cpuset hierarchy %s", dir, root)
+	}
+	if err := ensureCpuset(root, parent); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	for _, file := range []string{"cpuset.cpus", "cpuset.mems"} {
+		current, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(bytes.TrimSpace(current)) > 0 {
+			continue
+		}
+		value, err := ioutil.ReadFile(filepath.Join(parent, file))
+		if err != nil {
+			return err
+		}
+		if err := writeFile(dir, file, string(bytes.TrimSpace(value))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cgroup) setupBlkio(cgroupRoot string, pid int) (err error) {
+	throttles := map[string][]*ThrottleDevice{
+		"blkio.throttle.read_bps_device":   c.BlkioThrottleReadBpsDevice,
+		"blkio.throttle.write_bps_device":  c.BlkioThrottleWriteBpsDevice,
+		"blkio.throttle.read_iops_device":  c.BlkioThrottleReadIOpsDevice,
+		"blkio.throttle.write_iops_device": c.BlkioThrottleWriteIOpsDevice,
+	}
+	throttled := false
+	for _, devices := range throttles {
+		throttled = throttled || len(devices) > 0
+	}
+	if c.BlkioWeight == 0 && !throttled {
+		return nil
+	}
+	dir, err := c.Join(cgroupRoot, "blkio", pid)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	if c.BlkioWeight != 0 {
+		if err := writeFile(dir, "blkio.weight", strconv.FormatInt(c.BlkioWeight, 10)); err != nil {
+			return err
+		}
+	}
+	for file, devices := range throttles {
+		// each write sets the limit of one device
+		for _, d := range devices {
+			if err := writeFile(dir, file, d.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Cgroup) setupPids(cgroupRoot string, pid int) (err error) {
+	if c.PidsLimit == 0 {
+		return nil
+	}
+	dir, err := c.Join(cgroupRoot, "pids", pid)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(dir, "pids.max", strconv.FormatInt(c.PidsLimit, 10)); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	return nil
+}
+
+func (c *Cgroup) setupFreezer(cgroupRoot string, pid int) (err error) {
+	// the freezer subsystem is optional, only join it when it is mounted
+	if _, err := FindCgroupMountpoint("freezer"); err != nil {
+		return nil
+	}
+	dir, err := c.Join(cgroupRoot, "freezer", pid)
+	if err != nil {
+		return err
+	}
+	if c.Freezer != Undefined {
+		if err := setFreezerState(dir, c.Freezer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFreezerState writes the state to the freezer of the cgroup in dir and waits
+// for the transition to complete, the kernel reports FREEZING until every task is frozen.
+// A freeze which does not complete within freezerTimeout is undone and an error is returned.
+func setFreezerState(dir string, state FreezerState) error {
+	if err := writeFile(dir, "freezer.state", string(state)); err != nil {
+		return err
+	}
+	timeout := time.After(freezerTimeout)
+	for {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "freezer.state"))
+		if err != nil {
+			return err
+		}
+		current := FreezerState(strings.TrimSpace(string(data)))
+		if current == state {
+			return nil
+		}
+		select {
+		case <-timeout:
+			if state == Frozen {
+				// the tasks already frozen are thawed rather than left stuck
+				writeFile(dir, "freezer.state", string(Thawed))
+			}
+			return fmt.Errorf("Timeout after %s waiting for the freezer state %s of %s, the state is %s", freezerTimeout, state, dir, current)
+		case <-time.After(1 * time.Millisecond):
+		}
+	}
+}
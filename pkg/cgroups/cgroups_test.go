@@ -2,7 +2,12 @@ package cgroups
 
 import (
 	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 const (
@@ -25,3 +30,248 @@ func TestParseCgroups(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestSetFreezerState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroups-freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := setFreezerState(dir, Frozen); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "freezer.state"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if FreezerState(data) != Frozen {
+		t.Fatalf("Expected freezer state %s, received %s", Frozen, data)
+	}
+}
+
+func TestSetFreezerStateTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroups-freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the state written is never read back, like a freeze which does not complete
+	if err := os.Symlink("/dev/null", filepath.Join(dir, "freezer.state")); err != nil {
+		t.Fatal(err)
+	}
+	defer func(timeout time.Duration) { freezerTimeout = timeout }(freezerTimeout)
+	freezerTimeout = 10 * time.Millisecond
+
+	if err := setFreezerState(dir, Frozen); err == nil {
+		t.Fatal("Expected an error when the freezer state is not reached")
+	}
+}
+
+func TestWriteValuesRestoresOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroups-update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for file, value := range map[string]string{
+		"memory.limit_in_bytes": "1048576\n",
+		"cpu.shares":            "1024\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = writeValues([]cgroupValue{
+		{dir, "memory.limit_in_bytes", "2097152"},
+		{dir, "cpu.shares", "512"},
+		{filepath.Join(dir, "missing"), "memory.memsw.limit_in_bytes", "4194304"},
+	})
+	if err == nil {
+		t.Fatal("Expected an error when a value cannot be written")
+	}
+	for file, expected := range map[string]string{
+		"memory.limit_in_bytes": "1048576",
+		"cpu.shares":            "1024",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Fatalf("Expected %s to be restored to %s, received %s", file, expected, data)
+		}
+	}
+
+	if _, err := writeValues([]cgroupValue{{dir, "cpu.shares", "512"}}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "cpu.shares")); string(data) != "512" {
+		t.Fatalf("Expected cpu.shares to be 512, received %s", data)
+	}
+}
+
+// updateDir creates the files of a cgroup in a temporary directory, for
+// the update to write to it
+func updateDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "cgroups-update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, value := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func checkValues(t *testing.T, dir string, expected map[string]string) {
+	for file, value := range expected {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != value {
+			t.Fatalf("Expected %s to be %s, received %s", file, value, data)
+		}
+	}
+}
+
+func TestUpdateMemorySwapOnly(t *testing.T) {
+	dir := updateDir(t, map[string]string{
+		"memory.limit_in_bytes":       "1048576",
+		"memory.soft_limit_in_bytes":  "1048576",
+		"memory.memsw.limit_in_bytes": "2097152",
+	})
+	defer os.RemoveAll(dir)
+
+	c := &Cgroup{MemorySwap: 4194304}
+	if err := c.update(func(string) (string, error) { return dir, nil }, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkValues(t, dir, map[string]string{
+		"memory.limit_in_bytes":       "1048576",
+		"memory.soft_limit_in_bytes":  "1048576",
+		"memory.memsw.limit_in_bytes": "4194304",
+	})
+}
+
+func TestUpdateRestoresOnSystemdError(t *testing.T) {
+	dir := updateDir(t, map[string]string{
+		"memory.limit_in_bytes":       "1048576",
+		"memory.soft_limit_in_bytes":  "1048576",
+		"memory.memsw.limit_in_bytes": "2097152",
+		"cpu.shares":                  "1024",
+	})
+	defer os.RemoveAll(dir)
+
+	// systemd is only asked once the files are written
+	var written map[string]string
+	c := &Cgroup{Systemd: true, Memory: 4194304, CpuShares: 512}
+	err := c.update(func(string) (string, error) { return dir, nil }, func() error {
+		written = make(map[string]string)
+		for _, file := range []string{"memory.limit_in_bytes", "memory.memsw.limit_in_bytes", "cpu.shares"} {
+			data, _ := ioutil.ReadFile(filepath.Join(dir, file))
+			written[file] = string(data)
+		}
+		return fmt.Errorf("systemd failed")
+	})
+	if err == nil {
+		t.Fatal("Expected the error of systemd")
+	}
+	if written["memory.limit_in_bytes"] != "4194304" || written["memory.memsw.limit_in_bytes"] != "8388608" || written["cpu.shares"] != "512" {
+		t.Fatalf("Expected the files to be written before systemd is asked, received %v", written)
+	}
+	checkValues(t, dir, map[string]string{
+		"memory.limit_in_bytes":       "1048576",
+		"memory.soft_limit_in_bytes":  "1048576",
+		"memory.memsw.limit_in_bytes": "2097152",
+		"cpu.shares":                  "1024",
+	})
+}
+
+func TestParseBlkioEntries(t *testing.T) {
+	r := bytes.NewBufferString(`8:0 Read 1024
+8:0 Write 2048
+8:0 Sync 0
+8:0 Async 3072
+8:0 Total 3072
+Total 3072`)
+	entries, err := parseBlkioEntries(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("Expected 5 entries, received %d", len(entries))
+	}
+	if e := entries[1]; e.Major != 8 || e.Minor != 0 || e.Op != "Write" || e.Value != 2048 {
+		t.Fatalf("Unexpected entry %+v", e)
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	r := bytes.NewBufferString("cache 4096\nrss 8192\n")
+	values, err := parseKeyValues(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["cache"] != 4096 || values["rss"] != 8192 {
+		t.Fatalf("Unexpected values %v", values)
+	}
+}
+
+func TestEnsureCpuset(t *testing.T) {
+	root, err := ioutil.TempDir("", "cgroups-cpuset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for file, value := range map[string]string{
+		"cpuset.cpus": "0-3\n",
+		"cpuset.mems": "0\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(root, file), []byte(value), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the cpus of an existing cpuset are kept
+	if err := os.Mkdir(filepath.Join(root, "docker"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "docker", "cpuset.cpus"), []byte("1-2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(root, "docker", "abc")
+	if err := ensureCpuset(root, dir); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		"cpuset.cpus": "1-2",
+		"cpuset.mems": "0",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Fatalf("Expected %s to be %s, received %s", file, expected, data)
+		}
+	}
+
+	if err := ensureCpuset(root, "/tmp"); err == nil {
+		t.Fatal("Expected an error for a directory outside of the hierarchy")
+	}
+}
+
+func TestThrottleDeviceString(t *testing.T) {
+	d := &ThrottleDevice{Major: 8, Minor: 16, Rate: 1048576}
+	if s := d.String(); s != "8:16 1048576" {
+		t.Fatalf("Unexpected throttle entry %s", s)
+	}
+}
@@ -0,0 +1,246 @@
+package cgroups
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type CpuUsage struct {
+	TotalUsage        uint64   `json:"total_usage"`         // Total CPU time consumed (in nanoseconds)
+	PercpuUsage       []uint64 `json:"percpu_usage"`        // Total CPU time consumed per core (in nanoseconds)
+	UsageInUsermode   uint64   `json:"usage_in_usermode"`   // Time spent in user mode (in USER_HZ ticks)
+	UsageInKernelmode uint64   `json:"usage_in_kernelmode"` // Time spent in kernel mode (in USER_HZ ticks)
+}
+
+type MemoryUsage struct {
+	Usage    uint64            `json:"usage"`     // Current memory usage (in bytes)
+	MaxUsage uint64            `json:"max_usage"` // Maximum memory usage recorded (in bytes)
+	Limit    uint64            `json:"limit"`     // Memory limit of the cgroup (in bytes)
+	Failcnt  uint64            `json:"failcnt"`   // Number of times the limit was hit
+	Stats    map[string]uint64 `json:"stats"`     // Content of memory.stat
+}
+
+type BlkioEntry struct {
+	Major uint64 `json:"major"`
+	Minor uint64 `json:"minor"`
+	Op    string `json:"op"`
+	Value uint64 `json:"value"`
+}
+
+type BlkioUsage struct {
+	IoServiceBytes []BlkioEntry `json:"io_service_bytes"` // Number of bytes transferred to/from each device
+	IoServiced     []BlkioEntry `json:"io_serviced"`      // Number of IO operations issued to each device
+}
+
+// Stats holds the resource usage of a cgroup
+type Stats struct {
+	CpuUsage    CpuUsage    `json:"cpu_usage"`
+	MemoryUsage MemoryUsage `json:"memory_usage"`
+	BlkioUsage  BlkioUsage  `json:"blkio_usage"`
+}
+
+// GetStats reads the cpuacct, memory and blkio usage of the cgroup name. The
+// cgroup is searched in the cgroup of the current process, directly or below one
+// of the parents.
+func GetStats(name string, parents ...string) (*Stats, error) {
+	return getStats(func(subsystem string) (string, error) {
+		return findCgroupDir(subsystem, name, parents)
+	})
+}
+
+// Stats reads the cpuacct, memory and blkio usage of the cgroup of
+// running processes
+func (c *Cgroup) Stats() (*Stats, error) {
+	return getStats(func(subsystem string) (string, error) {
+		cgroupRoot, err := FindCgroupMountpoint(subsystem)
+		if err != nil {
+			return "", err
+		}
+		dir, err := c.Path(filepath.Dir(cgroupRoot), subsystem)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(dir); err != nil {
+			return "", fmt.Errorf("cgroup %s not found for %s", c.Name, subsystem)
+		}
+		return dir, nil
+	})
+}
+
+// getStats reads the usage of a cgroup in the directories
+// returned by cgroupDir for each subsystem
+func getStats(cgroupDir func(subsystem string) (string, error)) (*Stats, error) {
+	stats := &Stats{}
+
+	dir, err := cgroupDir("cpuacct")
+	if err != nil {
+		return nil, err
+	}
+	if err := getCpuUsage(dir, &stats.CpuUsage); err != nil {
+		return nil, err
+	}
+
+	// memory and blkio are optional subsystems
+	if dir, err := cgroupDir("memory"); err == nil {
+		if err := getMemoryUsage(dir, &stats.MemoryUsage); err != nil {
+			return nil, err
+		}
+	}
+	if dir, err := cgroupDir("blkio"); err == nil {
+		if err := getBlkioUsage(dir, &stats.BlkioUsage); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+func findCgroupDir(subsystem, name string, parents []string) (string, error) {
+	cgroupRoot, err := FindCgroupMountpoint(subsystem)
+	if err != nil {
+		return "", err
+	}
+	cgroupDir, err := GetThisCgroupDir(subsystem)
+	if err != nil {
+		return "", err
+	}
+	for _, parent := range append([]string{""}, parents...) {
+		dir := filepath.Join(cgroupRoot, cgroupDir, parent, name)
+		if _, err := os.Stat(dir); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("cgroup %s not found for %s", name, subsystem)
+}
+
+func getCpuUsage(dir string, usage *CpuUsage) (err error) {
+	if usage.TotalUsage, err = readUint(dir, "cpuacct.usage"); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cpuacct.usage_percpu"))
+	if err != nil {
+		return err
+	}
+	for _, field := range strings.Fields(string(data)) {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return err
+		}
+		usage.PercpuUsage = append(usage.PercpuUsage, value)
+	}
+	f, err := os.Open(filepath.Join(dir, "cpuacct.stat"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := parseKeyValues(f)
+	if err != nil {
+		return err
+	}
+	usage.UsageInUsermode = stat["user"]
+	usage.UsageInKernelmode = stat["system"]
+	return nil
+}
+
+func getMemoryUsage(dir string, usage *MemoryUsage) (err error) {
+	if usage.Usage, err = readUint(dir, "memory.usage_in_bytes"); err != nil {
+		return err
+	}
+	if usage.MaxUsage, err = readUint(dir, "memory.max_usage_in_bytes"); err != nil {
+		return err
+	}
+	if usage.Limit, err = readUint(dir, "memory.limit_in_bytes"); err != nil {
+		return err
+	}
+	if usage.Failcnt, err = readUint(dir, "memory.failcnt"); err != nil {
+		return err
+	}
+	f, err := os.Open(filepath.Join(dir, "memory.stat"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	usage.Stats, err = parseKeyValues(f)
+	return err
+}
+
+func getBlkioUsage(dir string, usage *BlkioUsage) (err error) {
+	if usage.IoServiceBytes, err = readBlkioEntries(dir, "blkio.throttle.io_service_bytes"); err != nil {
+		return err
+	}
+	if usage.IoServiced, err = readBlkioEntries(dir, "blkio.throttle.io_serviced"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readUint(dir, file string) (uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func readBlkioEntries(dir, file string) ([]BlkioEntry, error) {
+	f, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseBlkioEntries(f)
+}
+
+// parseKeyValues parses the "key value" lines of files like memory.stat
+func parseKeyValues(r io.Reader) (map[string]uint64, error) {
+	values := make(map[string]uint64)
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[fields[0]] = value
+	}
+	return values, s.Err()
+}
+
+// parseBlkioEntries parses the "major:minor op value" lines of the blkio files,
+// the trailing "Total value" line is skipped
+func parseBlkioEntries(r io.Reader) ([]BlkioEntry, error) {
+	var entries []BlkioEntry
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		devices := strings.Split(fields[0], ":")
+		if len(devices) != 2 {
+			return nil, fmt.Errorf("invalid blkio device %s", fields[0])
+		}
+		major, err := strconv.ParseUint(devices[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		minor, err := strconv.ParseUint(devices[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		value, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, BlkioEntry{Major: major, Minor: minor, Op: fields[1], Value: value})
+	}
+	return entries, s.Err()
+}
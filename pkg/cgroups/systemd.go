@@ -0,0 +1,184 @@
+package cgroups
+
+import (
+	"fmt"
+	systemd "github.com/coreos/go-systemd/dbus"
+	"github.com/godbus/dbus"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultSlice = "system.slice"
+
+var (
+	// systemd creates this directory when it is the init of the host
+	systemdRunDir = "/run/systemd/system"
+
+	// jobTimeout is how long a call waits for the job systemd queued for it
+	jobTimeout = 30 * time.Second
+
+	useSystemdOnce sync.Once
+	useSystemd     bool
+)
+
+// UseSystemd returns true when systemd is the init of the host and accepts
+// connections on its D-Bus socket. The cgroups of the containers are then
+// transient scope units of systemd, which owns the cgroup hierarchy.
+func UseSystemd() bool {
+	useSystemdOnce.Do(func() {
+		if fi, err := os.Stat(systemdRunDir); err != nil || !fi.IsDir() {
+			return
+		}
+		conn, err := systemd.NewSystemdConnection()
+		if err != nil {
+			return
+		}
+		conn.Close()
+		useSystemd = true
+	})
+	return useSystemd
+}
+
+// ExpandSlice returns the path of a slice in the cgroup hierarchy, the
+// dashes of its name separate its parents: a-b.slice is a.slice/a-b.slice
+func ExpandSlice(slice string) (string, error) {
+	const suffix = ".slice"
+	name := strings.TrimSuffix(slice, suffix)
+	if name == slice || name == "" || strings.Contains(name, "/") {
+		return "", fmt.Errorf("invalid slice name %s", slice)
+	}
+	if name == "-" {
+		// the root slice
+		return "/", nil
+	}
+	var path, prefix string
+	for _, component := range strings.Split(name, "-") {
+		if component == "" {
+			return "", fmt.Errorf("invalid slice name %s", slice)
+		}
+		path = filepath.Join(path, prefix+component+suffix)
+		prefix += component + "-"
+	}
+	return "/" + path, nil
+}
+
+func property(name string, value interface{}) systemd.Property {
+	return systemd.Property{Name: name, Value: dbus.MakeVariant(value)}
+}
+
+// runJob waits until the job queued by start completed
+func runJob(start func(chan<- string) (int, error)) error {
+	result := make(chan string, 1)
+	if _, err := start(result); err != nil {
+		return err
+	}
+	select {
+	case r := <-result:
+		if r != "done" {
+			return fmt.Errorf("job %s", r)
+		}
+		return nil
+	case <-time.After(jobTimeout):
+		return fmt.Errorf("timeout after %s waiting for the job", jobTimeout)
+	}
+}
+
+func (c *Cgroup) scopeName() string {
+	return "docker-" + c.Name + ".scope"
+}
+
+func (c *Cgroup) slice() string {
+	if c.Slice != "" {
+		return c.Slice
+	}
+	return defaultSlice
+}
+
+// systemdPath returns the directory of the scope in the hierarchy of the
+// subsystem, systemd places the scopes below their slice from the root
+func (c *Cgroup) systemdPath(root, subsystem string) (string, error) {
+	slice, err := ExpandSlice(c.slice())
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, subsystem, slice, c.scopeName()), nil
+}
+
+// systemdProperties returns the limits of the cgroup which systemd
+// manages itself, systemd would reset them when it reloads otherwise
+func (c *Cgroup) systemdProperties() []systemd.Property {
+	var properties []systemd.Property
+	if c.Memory != 0 {
+		properties = append(properties, property("MemoryLimit", uint64(c.Memory)))
+	}
+	if c.CpuShares != 0 {
+		properties = append(properties, property("CPUShares", uint64(c.CpuShares)))
+	}
+	if c.BlkioWeight != 0 {
+		properties = append(properties, property("BlockIOWeight", uint64(c.BlkioWeight)))
+	}
+	return properties
+}
+
+// applySystemd moves the process to a new transient scope in the slice of
+// the cgroup. systemd only creates the scope in the hierarchies of the
+// subsystems it manages, the process joins the others and gets the rest of
+// its limits through the cgroup filesystem, in the directories of the scope.
+func (c *Cgroup) applySystemd(cgroupRoot string, pid int) error {
+	conn, err := systemd.NewSystemdConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	properties := append([]systemd.Property{
+		systemd.PropSlice(c.slice()),
+		systemd.PropDescription("docker container " + c.Name),
+		systemd.PropPids(uint32(pid)),
+		// the scope is not ordered against the basic units of the host
+		property("DefaultDependencies", false),
+	}, c.systemdProperties()...)
+	if err := runJob(func(result chan<- string) (int, error) {
+		return conn.StartTransientUnit(c.scopeName(), "replace", properties, result)
+	}); err != nil {
+		return fmt.Errorf("cannot create the scope %s: %s", c.scopeName(), err)
+	}
+	return c.applyFs(cgroupRoot, pid)
+}
+
+// updateSystemd sets the limits of the scope which systemd manages
+// until the scope stops
+func (c *Cgroup) updateSystemd() error {
+	properties := c.systemdProperties()
+	if len(properties) == 0 {
+		return nil
+	}
+	conn, err := systemd.NewSystemdConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.SetUnitProperties(c.scopeName(), true, properties...)
+}
+
+// cleanupSystemd stops the scope, systemd removes its directories in the
+// hierarchies it manages. The scope is already gone when systemd stopped it
+// once its processes exited.
+func (c *Cgroup) cleanupSystemd() error {
+	conn, err := systemd.NewSystemdConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := runJob(func(result chan<- string) (int, error) {
+		return conn.StopUnit(c.scopeName(), "replace", result)
+	}); err != nil {
+		if e, ok := err.(dbus.Error); !ok || e.Name != "org.freedesktop.systemd1.NoSuchUnit" {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package cgroups
+
+import (
+	"testing"
+)
+
+func TestExpandSlice(t *testing.T) {
+	for slice, expected := range map[string]string{
+		"system.slice":             "/system.slice",
+		"-.slice":                  "/",
+		"machine-docker.slice":     "/machine.slice/machine-docker.slice",
+		"a-b-c.slice":              "/a.slice/a-b.slice/a-b-c.slice",
+		"user-1000.slice":          "/user.slice/user-1000.slice",
+		"machine-docker-web.slice": "/machine.slice/machine-docker.slice/machine-docker-web.slice",
+	} {
+		path, err := ExpandSlice(slice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != expected {
+			t.Fatalf("Expected %s to expand to %s, got %s", slice, expected, path)
+		}
+	}
+	for _, slice := range []string{"system", ".slice", "a--b.slice", "-a.slice", "a/b.slice", "system.scope"} {
+		if _, err := ExpandSlice(slice); err == nil {
+			t.Fatalf("Expected an error for the slice %s", slice)
+		}
+	}
+}
+
+func TestSystemdPath(t *testing.T) {
+	c := &Cgroup{Name: "123", Parent: "docker", Systemd: true}
+	path, err := c.Path("/sys/fs/cgroup", "memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/sys/fs/cgroup/memory/system.slice/docker-123.scope"; path != expected {
+		t.Fatalf("Expected %s, got %s", expected, path)
+	}
+
+	c.Slice = "machine-docker.slice"
+	path, err = c.Path("/sys/fs/cgroup", "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/sys/fs/cgroup/cpu/machine.slice/machine-docker.slice/docker-123.scope"; path != expected {
+		t.Fatalf("Expected %s, got %s", expected, path)
+	}
+}
+
+func TestSystemdProperties(t *testing.T) {
+	c := &Cgroup{Name: "123", Memory: 1 << 20, CpuShares: 512}
+	properties := c.systemdProperties()
+	if len(properties) != 2 {
+		t.Fatalf("Expected 2 properties, got %v", properties)
+	}
+	if p := properties[0]; p.Name != "MemoryLimit" || p.Value.Signature().String() != "t" || p.Value.Value() != uint64(1<<20) {
+		t.Fatalf("Unexpected property %v", p)
+	}
+	if p := properties[1]; p.Name != "CPUShares" || p.Value.Value() != uint64(512) {
+		t.Fatalf("Unexpected property %v", p)
+	}
+}
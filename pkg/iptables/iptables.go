@@ -18,30 +18,45 @@ const (
 )
 
 var (
-	ErrIptablesNotFound = errors.New("Iptables not found")
-	nat                 = []string{"-t", "nat"}
+	ErrIptablesNotFound  = errors.New("Iptables not found")
+	ErrIp6tablesNotFound = errors.New("Ip6tables not found")
+	nat                  = []string{"-t", "nat"}
 )
 
 type Chain struct {
 	Name   string
 	Bridge string
+	IPv6   bool // the chain is in the tables of ip6tables
 }
 
 func NewChain(name, bridge string) (*Chain, error) {
-	if output, err := Raw("-t", "nat", "-N", name); err != nil {
+	return newChain(&Chain{
+		Name:   name,
+		Bridge: bridge,
+	})
+}
+
+// NewChainV6 creates the chain in the nat table of ip6tables,
+// which needs a kernel 3.7 or later
+func NewChainV6(name, bridge string) (*Chain, error) {
+	return newChain(&Chain{
+		Name:   name,
+		Bridge: bridge,
+		IPv6:   true,
+	})
+}
+
+func newChain(chain *Chain) (*Chain, error) {
+	if output, err := chain.raw("-t", "nat", "-N", chain.Name); err != nil {
 		return nil, err
 	} else if len(output) != 0 {
 		return nil, fmt.Errorf("Error creating new iptables chain: %s", output)
 	}
-	chain := &Chain{
-		Name:   name,
-		Bridge: bridge,
-	}
 
 	if err := chain.Prerouting(Add, "-m", "addrtype", "--dst-type", "LOCAL"); err != nil {
 		return nil, fmt.Errorf("Failed to inject docker in PREROUTING chain: %s", err)
 	}
-	if err := chain.Output(Add, "-m", "addrtype", "--dst-type", "LOCAL", "!", "--dst", "127.0.0.0/8"); err != nil {
+	if err := chain.Output(Add, "-m", "addrtype", "--dst-type", "LOCAL", "!", "--dst", chain.loopback()); err != nil {
 		return nil, fmt.Errorf("Failed to inject docker in OUTPUT chain: %s", err)
 	}
 	return chain, nil
@@ -54,7 +69,55 @@ func RemoveExistingChain(name string) error {
 	return chain.Remove()
 }
 
+func RemoveExistingChainV6(name string) error {
+	chain := &Chain{
+		Name: name,
+		IPv6: true,
+	}
+	return chain.Remove()
+}
+
+// raw runs iptables, or ip6tables for an IPv6 chain
+func (c *Chain) raw(args ...string) ([]byte, error) {
+	if c.IPv6 {
+		return Raw6(args...)
+	}
+	return Raw(args...)
+}
+
+// loopback returns the loopback network, excluded from the OUTPUT rule
+func (c *Chain) loopback() string {
+	if c.IPv6 {
+		return "::1/128"
+	}
+	return "127.0.0.0/8"
+}
+
 func (c *Chain) Forward(action Action, ip net.IP, port int, proto, dest_addr string, dest_port int) error {
+	return c.ForwardRange(action, ip, port, port, proto, dest_addr, dest_port)
+}
+
+// ForwardRange forwards the ports port to port_end with a single rule, to
+// the ports of dest_addr at the same offset from dest_port. Shifting the
+// range to other ports needs iptables 1.8 and a kernel 5.0 or later.
+func (c *Chain) ForwardRange(action Action, ip net.IP, port, port_end int, proto, dest_addr string, dest_port int) error {
+	var (
+		dport      = strconv.Itoa(port)
+		dest_dport = strconv.Itoa(dest_port)
+		dest       = net.JoinHostPort(dest_addr, dest_dport)
+	)
+	if port_end > port {
+		dest_port_end := dest_port + port_end - port
+		dport = fmt.Sprintf("%d:%d", port, port_end)
+		dest_dport = fmt.Sprintf("%d:%d", dest_port, dest_port_end)
+		if dest_port == port {
+			// the destination port is the one of the packet
+			dest = dest_addr
+		} else {
+			dest = fmt.Sprintf("%s-%d/%d", net.JoinHostPort(dest_addr, strconv.Itoa(dest_port)), dest_port_end, port)
+		}
+	}
+
 	daddr := ip.String()
 	if ip.IsUnspecified() {
 		// iptables interprets "0.0.0.0" as "0.0.0.0/32", whereas we
@@ -62,13 +125,13 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, dest_addr str
 		// value" by both iptables and ip6tables.
 		daddr = "0/0"
 	}
-	if output, err := Raw("-t", "nat", fmt.Sprint(action), c.Name,
+	if output, err := c.raw("-t", "nat", fmt.Sprint(action), c.Name,
 		"-p", proto,
 		"-d", daddr,
-		"--dport", strconv.Itoa(port),
+		"--dport", dport,
 		"!", "-i", c.Bridge,
 		"-j", "DNAT",
-		"--to-destination", net.JoinHostPort(dest_addr, strconv.Itoa(dest_port))); err != nil {
+		"--to-destination", dest); err != nil {
 		return err
 	} else if len(output) != 0 {
 		return fmt.Errorf("Error iptables forward: %s", output)
@@ -78,12 +141,12 @@ func (c *Chain) Forward(action Action, ip net.IP, port int, proto, dest_addr str
 	if fAction == Add {
 		fAction = "-I"
 	}
-	if output, err := Raw(string(fAction), "FORWARD",
+	if output, err := c.raw(string(fAction), "FORWARD",
 		"!", "-i", c.Bridge,
 		"-o", c.Bridge,
 		"-p", proto,
 		"-d", dest_addr,
-		"--dport", strconv.Itoa(dest_port),
+		"--dport", dest_dport,
 		"-j", "ACCEPT"); err != nil {
 		return err
 	} else if len(output) != 0 {
@@ -98,7 +161,7 @@ func (c *Chain) Prerouting(action Action, args ...string) error {
 	if len(args) > 0 {
 		a = append(a, args...)
 	}
-	if output, err := Raw(append(a, "-j", c.Name)...); err != nil {
+	if output, err := c.raw(append(a, "-j", c.Name)...); err != nil {
 		return err
 	} else if len(output) != 0 {
 		return fmt.Errorf("Error iptables prerouting: %s", output)
@@ -111,7 +174,7 @@ func (c *Chain) Output(action Action, args ...string) error {
 	if len(args) > 0 {
 		a = append(a, args...)
 	}
-	if output, err := Raw(append(a, "-j", c.Name)...); err != nil {
+	if output, err := c.raw(append(a, "-j", c.Name)...); err != nil {
 		return err
 	} else if len(output) != 0 {
 		return fmt.Errorf("Error iptables output: %s", output)
@@ -122,14 +185,14 @@ func (c *Chain) Output(action Action, args ...string) error {
 func (c *Chain) Remove() error {
 	// Ignore errors - This could mean the chains were never set up
 	c.Prerouting(Delete, "-m", "addrtype", "--dst-type", "LOCAL")
-	c.Output(Delete, "-m", "addrtype", "--dst-type", "LOCAL", "!", "--dst", "127.0.0.0/8")
+	c.Output(Delete, "-m", "addrtype", "--dst-type", "LOCAL", "!", "--dst", c.loopback())
 	c.Output(Delete, "-m", "addrtype", "--dst-type", "LOCAL") // Created in versions <= 0.1.6
 
 	c.Prerouting(Delete)
 	c.Output(Delete)
 
-	Raw("-t", "nat", "-F", c.Name)
-	Raw("-t", "nat", "-X", c.Name)
+	c.raw("-t", "nat", "-F", c.Name)
+	c.raw("-t", "nat", "-X", c.Name)
 
 	return nil
 }
@@ -142,17 +205,38 @@ func Exists(args ...string) bool {
 	return true
 }
 
+// Exists6 checks if an existing rule exists in the tables of ip6tables
+func Exists6(args ...string) bool {
+	if _, err := Raw6(append([]string{"-C"}, args...)...); err != nil {
+		return false
+	}
+	return true
+}
+
 func Raw(args ...string) ([]byte, error) {
 	path, err := exec.LookPath("iptables")
 	if err != nil {
 		return nil, ErrIptablesNotFound
 	}
+	return run(path, "iptables", args)
+}
+
+// Raw6 runs ip6tables with the arguments, as Raw runs iptables
+func Raw6(args ...string) ([]byte, error) {
+	path, err := exec.LookPath("ip6tables")
+	if err != nil {
+		return nil, ErrIp6tablesNotFound
+	}
+	return run(path, "ip6tables", args)
+}
+
+func run(path, name string, args []string) ([]byte, error) {
 	if os.Getenv("DEBUG") != "" {
-		fmt.Printf("[DEBUG] [iptables]: %s, %v\n", path, args)
+		fmt.Printf("[DEBUG] [%s]: %s, %v\n", name, path, args)
 	}
 	output, err := exec.Command(path, args...).CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("iptables failed: iptables %v: %s (%s)", strings.Join(args, " "), output, err)
+		return nil, fmt.Errorf("%s failed: %s %v: %s (%s)", name, name, strings.Join(args, " "), output, err)
 	}
 	return output, err
 }
@@ -24,6 +24,34 @@ type Container struct {
 	Cgroups          *cgroups.Cgroup `json:"cgroups,omitempty"`           // cgroups
 	Context          Context         `json:"context,omitempty"`           // generic context for specific options (apparmor, selinux)
 	Mounts           []Mount         `json:"mounts,omitempty"`
+	Devices          []*Device       `json:"devices,omitempty"` // devices of the host created in the container
+
+	Tmpfs   map[string]string `json:"tmpfs,omitempty"`   // tmpfs mounted at the paths, with their mount options
+	Seccomp *Seccomp          `json:"seccomp,omitempty"` // syscall filter installed before executing the process
+
+	UidMappings []IdMap `json:"uid_mappings,omitempty"` // uid maps of the user namespace
+	GidMappings []IdMap `json:"gid_mappings,omitempty"` // gid maps of the user namespace
+
+	Rlimits []Rlimit `json:"rlimits,omitempty"` // resource limits set before executing the process
+
+	NoNewPrivileges bool `json:"no_new_privileges,omitempty"` // the process and its children cannot gain privileges, with setuid binaries for example
+
+	Restorable bool `json:"restorable,omitempty"` // the process outlives its parent so that a new parent can restore it
+}
+
+// Rlimit is a resource limit of the process, as set by setrlimit
+type Rlimit struct {
+	Type int    `json:"type"` // resource, RLIMIT_NOFILE for example
+	Hard uint64 `json:"hard"`
+	Soft uint64 `json:"soft"`
+}
+
+// IdMap maps a range of uids or gids of the container to the host,
+// as in /proc/<pid>/uid_map and /proc/<pid>/gid_map
+type IdMap struct {
+	ContainerId int `json:"container_id"` // first id of the range in the container
+	HostId      int `json:"host_id"`      // first id of the range on the host
+	Size        int `json:"size"`         // number of ids in the range
 }
 
 // Network defines configuration for a container's networking stack
@@ -36,6 +64,9 @@ type Network struct {
 	Address string  `json:"address,omitempty"`
 	Gateway string  `json:"gateway,omitempty"`
 	Mtu     int     `json:"mtu,omitempty"`
+
+	IPv6Address string `json:"ipv6_address,omitempty"` // in CIDR notation, the interface has no IPv6 address when empty
+	IPv6Gateway string `json:"ipv6_gateway,omitempty"`
 }
 
 // Bind mounts from the host system to the container
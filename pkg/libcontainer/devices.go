@@ -0,0 +1,61 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Device is a device node of the host created in the container
+// and allowed in its devices cgroup
+type Device struct {
+	Path        string      `json:"path"`        // path of the node in the container
+	HostPath    string      `json:"host_path"`   // path of the node on the host
+	Type        string      `json:"type"`        // "c" for a character device, "b" for a block device
+	Major       int64       `json:"major"`       // major number of the device
+	Minor       int64       `json:"minor"`       // minor number of the device
+	Permissions string      `json:"permissions"` // cgroup permissions, any of r, w and m
+	FileMode    os.FileMode `json:"file_mode"`   // permission bits of the node
+}
+
+// GetDevice returns the device of the node at hostPath, created at
+// containerPath in the container with the cgroup permissions
+func GetDevice(hostPath, containerPath, permissions string) (*Device, error) {
+	fi, err := os.Stat(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	var devType string
+	switch {
+	case fi.Mode()&os.ModeDevice == 0:
+		return nil, fmt.Errorf("%s is not a device node", hostPath)
+	case fi.Mode()&os.ModeCharDevice != 0:
+		devType = "c"
+	default:
+		devType = "b"
+	}
+	if strings.Trim(permissions, "rwm") != "" || permissions == "" {
+		return nil, fmt.Errorf("Invalid cgroup permissions %s for %s: any of r, w and m", permissions, hostPath)
+	}
+	rdev := uint64(fi.Sys().(*syscall.Stat_t).Rdev)
+	return &Device{
+		Path:        containerPath,
+		HostPath:    hostPath,
+		Type:        devType,
+		Major:       int64((rdev >> 8) & 0xfff),
+		Minor:       int64((rdev & 0xff) | ((rdev >> 12) & 0xfff00)),
+		Permissions: permissions,
+		FileMode:    fi.Mode().Perm(),
+	}, nil
+}
+
+// CgroupString returns the entry of the device in devices.allow
+func (d *Device) CgroupString() string {
+	return fmt.Sprintf("%s %d:%d %s", d.Type, d.Major, d.Minor, d.Permissions)
+}
+
+// Mkdev returns the device number given to mknod
+func (d *Device) Mkdev() int {
+	return int((d.Minor & 0xff) | ((d.Major & 0xfff) << 8) | ((d.Minor &^ 0xff) << 12))
+}
@@ -0,0 +1,36 @@
+package libcontainer
+
+import (
+	"testing"
+)
+
+func TestGetDevice(t *testing.T) {
+	device, err := GetDevice("/dev/null", "/dev/mynull", "rw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cgroup := device.CgroupString(); cgroup != "c 1:3 rw" {
+		t.Fatalf("Expected c 1:3 rw, received %s", cgroup)
+	}
+	if device.Path != "/dev/mynull" {
+		t.Fatalf("Expected the node to be created at /dev/mynull, not %s", device.Path)
+	}
+	if device.Mkdev() != 0x103 {
+		t.Fatalf("Expected the device number 0x103, received %#x", device.Mkdev())
+	}
+
+	if _, err := GetDevice("/dev/null", "/dev/null", "rwx"); err == nil {
+		t.Fatal("Expected an error for invalid permissions")
+	}
+	if _, err := GetDevice("/", "/dev/null", "rwm"); err == nil {
+		t.Fatal("Expected an error for a directory")
+	}
+}
+
+func TestMkdev(t *testing.T) {
+	// minor numbers above 255 are split around the major number
+	device := &Device{Major: 10, Minor: 0x12345}
+	if dev := device.Mkdev(); dev != 0x12300a45 {
+		t.Fatalf("Expected the device number 0x12300a45, received %#x", dev)
+	}
+}
@@ -0,0 +1,34 @@
+package network
+
+import (
+	"fmt"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/pkg/system"
+	"os"
+	"syscall"
+)
+
+// NetNS is a network strategy that joins the existing network namespace
+// at the nspath of its context, such as the one of another container
+type NetNS struct {
+}
+
+func (v *NetNS) Create(n *libcontainer.Network, nspid int, context libcontainer.Context) error {
+	if _, exists := n.Context["nspath"]; !exists {
+		return fmt.Errorf("nspath does not exist in network context")
+	}
+	return nil
+}
+
+func (v *NetNS) Initialize(config *libcontainer.Network, context libcontainer.Context) error {
+	nspath := config.Context["nspath"]
+	f, err := os.Open(nspath)
+	if err != nil {
+		return fmt.Errorf("open network namespace %s %s", nspath, err)
+	}
+	defer f.Close()
+	if err := system.Setns(f.Fd(), syscall.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("setns to network namespace %s %s", nspath, err)
+	}
+	return nil
+}
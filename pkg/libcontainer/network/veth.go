@@ -62,6 +62,11 @@ func (v *Veth) Initialize(config *libcontainer.Network, context libcontainer.Con
 	if err := SetInterfaceIp("eth0", config.Address); err != nil {
 		return fmt.Errorf("set eth0 ip %s", err)
 	}
+	if config.IPv6Address != "" {
+		if err := SetInterfaceIp("eth0", config.IPv6Address); err != nil {
+			return fmt.Errorf("set eth0 ipv6 %s", err)
+		}
+	}
 	if err := SetMtu("eth0", config.Mtu); err != nil {
 		return fmt.Errorf("set eth0 mtu to %d %s", config.Mtu, err)
 	}
@@ -73,6 +78,11 @@ func (v *Veth) Initialize(config *libcontainer.Network, context libcontainer.Con
 			return fmt.Errorf("set gateway to %s %s", config.Gateway, err)
 		}
 	}
+	if config.IPv6Gateway != "" {
+		if err := SetDefaultGateway(config.IPv6Gateway); err != nil {
+			return fmt.Errorf("set ipv6 gateway to %s %s", config.IPv6Gateway, err)
+		}
+	}
 	return nil
 }
 
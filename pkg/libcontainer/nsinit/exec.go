@@ -3,11 +3,15 @@
 package nsinit
 
 import (
+	"fmt"
+	"github.com/dotcloud/docker/pkg/cgroups"
 	"github.com/dotcloud/docker/pkg/libcontainer"
 	"github.com/dotcloud/docker/pkg/libcontainer/network"
 	"github.com/dotcloud/docker/pkg/system"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"syscall"
 )
 
@@ -38,6 +42,12 @@ func (ns *linuxNs) Exec(container *libcontainer.Container, term Terminal, args [
 	}
 
 	command := ns.commandFactory.Create(container, console, syncPipe.child, args)
+	if container.Namespaces.Contains("NEWUSER") {
+		ns.logger.Println("setting up user namespace")
+		if err := setupUserNamespace(container, command, console); err != nil {
+			return -1, err
+		}
+	}
 	ns.logger.Println("attach terminal to command")
 	if err := term.Attach(command); err != nil {
 		return -1, err
@@ -48,28 +58,40 @@ func (ns *linuxNs) Exec(container *libcontainer.Container, term Terminal, args [
 	if err := command.Start(); err != nil {
 		return -1, err
 	}
-	ns.logger.Printf("writting pid %d to file\n", command.Process.Pid)
-	if err := ns.stateWriter.WritePid(command.Process.Pid); err != nil {
+	// Do this before syncing with child so that no children
+	// can escape the cgroup
+	ns.logger.Println("setting cgroups")
+	if err := ns.SetupCgroups(container, command.Process.Pid); err != nil {
 		command.Process.Kill()
 		return -1, err
 	}
 	defer func() {
-		ns.logger.Println("removing pid file")
-		ns.stateWriter.DeletePid()
+		ns.logger.Println("removing cgroups")
+		if err := ns.CleanupCgroups(container); err != nil {
+			ns.logger.Printf("removing cgroups %s\n", err)
+		}
 	}()
+	ns.logger.Println("setting up network")
+	context, err := ns.InitializeNetworking(container, command.Process.Pid, syncPipe)
+	if err != nil {
+		command.Process.Kill()
+		return -1, err
+	}
 
-	// Do this before syncing with child so that no children
-	// can escape the cgroup
-	ns.logger.Println("setting cgroups")
-	if err := ns.SetupCgroups(container, command.Process.Pid); err != nil {
+	ns.logger.Printf("writting state of pid %d to file\n", command.Process.Pid)
+	state, err := ns.getState(container, command.Process.Pid, context)
+	if err != nil {
 		command.Process.Kill()
 		return -1, err
 	}
-	ns.logger.Println("setting up network")
-	if err := ns.InitializeNetworking(container, command.Process.Pid, syncPipe); err != nil {
+	if err := ns.stateWriter.WriteState(state); err != nil {
 		command.Process.Kill()
 		return -1, err
 	}
+	defer func() {
+		ns.logger.Println("removing state file")
+		ns.stateWriter.DeleteState()
+	}()
 
 	ns.logger.Println("closing sync pipe with child")
 	// Sync with child
@@ -85,6 +107,50 @@ func (ns *linuxNs) Exec(container *libcontainer.Container, term Terminal, args [
 	return status, err
 }
 
+// setupUserNamespace has the uid and gid maps of the container written by the
+// parent while the child waits before executing the init. The child becomes
+// the root of the user namespace before the exec, so the init keeps its
+// capabilities in the namespace. The console is given to the root of the
+// container, the init opens it as this user.
+func setupUserNamespace(container *libcontainer.Container, command *exec.Cmd, console string) error {
+	rootUid, err := hostId(container.UidMappings, 0)
+	if err != nil {
+		return fmt.Errorf("uid mappings %s", err)
+	}
+	rootGid, err := hostId(container.GidMappings, 0)
+	if err != nil {
+		return fmt.Errorf("gid mappings %s", err)
+	}
+	if console != "" {
+		if err := os.Chown(console, rootUid, rootGid); err != nil {
+			return fmt.Errorf("chown console %s", err)
+		}
+	}
+	command.SysProcAttr.UidMappings = sysProcIdMaps(container.UidMappings)
+	command.SysProcAttr.GidMappings = sysProcIdMaps(container.GidMappings)
+	command.SysProcAttr.GidMappingsEnableSetgroups = true
+	command.SysProcAttr.Credential = &syscall.Credential{Uid: 0, Gid: 0}
+	return nil
+}
+
+// hostId returns the id of the host an id of the container is mapped to
+func hostId(mappings []libcontainer.IdMap, id int) (int, error) {
+	for _, m := range mappings {
+		if id >= m.ContainerId && id < m.ContainerId+m.Size {
+			return m.HostId + id - m.ContainerId, nil
+		}
+	}
+	return -1, fmt.Errorf("%d is not mapped", id)
+}
+
+func sysProcIdMaps(mappings []libcontainer.IdMap) []syscall.SysProcIDMap {
+	maps := make([]syscall.SysProcIDMap, len(mappings))
+	for i, m := range mappings {
+		maps[i] = syscall.SysProcIDMap{ContainerID: m.ContainerId, HostID: m.HostId, Size: m.Size}
+	}
+	return maps
+}
+
 func (ns *linuxNs) SetupCgroups(container *libcontainer.Container, nspid int) error {
 	if container.Cgroups != nil {
 		if err := container.Cgroups.Apply(nspid); err != nil {
@@ -94,16 +160,59 @@ func (ns *linuxNs) SetupCgroups(container *libcontainer.Container, nspid int) er
 	return nil
 }
 
-func (ns *linuxNs) InitializeNetworking(container *libcontainer.Container, nspid int, pipe *SyncPipe) error {
+// CleanupCgroups removes the cgroups of the container once its processes exited
+func (ns *linuxNs) CleanupCgroups(container *libcontainer.Container) error {
+	if container.Cgroups == nil {
+		return nil
+	}
+	cgroupRoot, err := cgroups.FindCgroupMountpoint("cpu")
+	if err != nil {
+		return err
+	}
+	return container.Cgroups.Cleanup(filepath.Dir(cgroupRoot))
+}
+
+// InitializeNetworking creates the networks of the container and sends the
+// context of the strategies to the child, the context is returned as well
+func (ns *linuxNs) InitializeNetworking(container *libcontainer.Container, nspid int, pipe *SyncPipe) (libcontainer.Context, error) {
 	context := libcontainer.Context{}
 	for _, config := range container.Networks {
 		strategy, err := network.GetStrategy(config.Type)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if err := strategy.Create(config, nspid, context); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return pipe.SendToChild(context)
+	return context, pipe.SendToChild(context)
+}
+
+// getState returns the state of the container once its init nspid is set up
+func (ns *linuxNs) getState(container *libcontainer.Container, nspid int, context libcontainer.Context) (*libcontainer.State, error) {
+	startTime, err := system.GetProcessStartTime(nspid)
+	if err != nil {
+		return nil, err
+	}
+	state := &libcontainer.State{
+		InitPid:           nspid,
+		InitStartTime:     startTime,
+		NamespacePaths:    make(map[string]string),
+		NetworkInterfaces: make(map[string]string),
+	}
+	for _, nsv := range container.Namespaces {
+		state.NamespacePaths[nsv.Key] = filepath.Join("/proc", strconv.Itoa(nspid), "ns", nsv.File)
+	}
+	if container.Cgroups != nil {
+		if state.CgroupPaths, err = container.Cgroups.Paths(); err != nil {
+			return nil, err
+		}
+	}
+	// the veth strategy names its interfaces in the context, the one
+	// placed in the container is renamed to eth0 by the child
+	if vethHost, exists := context["veth-host"]; exists {
+		state.NetworkInterfaces["veth-host"] = vethHost
+		state.NetworkInterfaces["veth-child"] = "eth0"
+	}
+	return state, nil
 }
@@ -13,10 +13,39 @@ import (
 )
 
 // ExecIn uses an existing pid and joins the pid's namespaces with the new command.
-func (ns *linuxNs) ExecIn(container *libcontainer.Container, nspid int, args []string) (int, error) {
+// If console is not empty it is setup as the controlling terminal of the new command.
+func (ns *linuxNs) ExecIn(container *libcontainer.Container, nspid int, console string, args []string) (int, error) {
+	if console != "" {
+		ns.logger.Printf("setting up %s as console\n", console)
+		slave, err := system.OpenTerminal(console, syscall.O_RDWR)
+		if err != nil {
+			return -1, fmt.Errorf("open terminal %s", err)
+		}
+		if err := dupSlave(slave); err != nil {
+			return -1, fmt.Errorf("dup2 slave %s", err)
+		}
+		if _, err := system.Setsid(); err != nil {
+			return -1, fmt.Errorf("setsid %s", err)
+		}
+		if err := system.Setctty(); err != nil {
+			return -1, fmt.Errorf("setctty %s", err)
+		}
+	}
+	if container.Namespaces.Contains("NEWUSER") {
+		// a multithreaded process can't join a user namespace, the user
+		// namespace is joined when the process starts, see nsenter_linux.go
+		joined, err := sameNamespace(nspid, "user")
+		if err != nil {
+			return -1, err
+		}
+		if !joined {
+			return -1, fmt.Errorf("the user namespace of %d is not joined, set %s", nspid, UsernsPidEnv)
+		}
+	}
 	for _, nsv := range container.Namespaces {
 		// skip the PID namespace on unshare because it it not supported
-		if nsv.Key != "NEWPID" {
+		// and the user namespace which is already joined
+		if nsv.Key != "NEWPID" && nsv.Key != "NEWUSER" {
 			if err := system.Unshare(nsv.Value); err != nil {
 				return -1, err
 			}
@@ -77,7 +106,7 @@ func (ns *linuxNs) ExecIn(container *libcontainer.Container, nspid int, args []s
 		os.Exit(state.Sys().(syscall.WaitStatus).ExitStatus())
 	}
 dropAndExec:
-	if err := finalizeNamespace(container); err != nil {
+	if err := ns.finalizeProcess(container); err != nil {
 		return -1, err
 	}
 	if err := system.Execv(args[0], args[0:], container.Env); err != nil {
@@ -86,9 +115,26 @@ dropAndExec:
 	panic("unreachable")
 }
 
+// sameNamespace returns true if the current process is in the namespace
+// file of pid
+func sameNamespace(pid int, file string) (bool, error) {
+	self, err := os.Readlink(filepath.Join("/proc/self/ns", file))
+	if err != nil {
+		return false, err
+	}
+	other, err := os.Readlink(filepath.Join("/proc/", strconv.Itoa(pid), "ns", file))
+	if err != nil {
+		return false, err
+	}
+	return self == other, nil
+}
+
 func (ns *linuxNs) getNsFds(pid int, container *libcontainer.Container) ([]uintptr, error) {
 	fds := make([]uintptr, len(container.Namespaces))
 	for i, ns := range container.Namespaces {
+		if ns.Key == "NEWUSER" {
+			continue
+		}
 		f, err := os.OpenFile(filepath.Join("/proc/", strconv.Itoa(pid), "ns", ns.File), os.O_RDONLY, 0)
 		if err != nil {
 			return fds, err
@@ -8,10 +8,13 @@ import (
 	"github.com/dotcloud/docker/pkg/libcontainer/apparmor"
 	"github.com/dotcloud/docker/pkg/libcontainer/capabilities"
 	"github.com/dotcloud/docker/pkg/libcontainer/network"
+	"github.com/dotcloud/docker/pkg/libcontainer/seccomp"
+	"github.com/dotcloud/docker/pkg/libcontainer/selinux"
 	"github.com/dotcloud/docker/pkg/libcontainer/utils"
 	"github.com/dotcloud/docker/pkg/system"
 	"github.com/dotcloud/docker/pkg/user"
 	"os"
+	"runtime"
 	"syscall"
 )
 
@@ -52,29 +55,26 @@ func (ns *linuxNs) Init(container *libcontainer.Container, uncleanRootfs, consol
 		}
 	}
 	// this is our best effort to let the process know that the parent has died and that it
-	// should it should act on it how it sees fit
-	if err := system.ParentDeathSignal(uintptr(syscall.SIGTERM)); err != nil {
-		return fmt.Errorf("parent death signal %s", err)
-	}
-	ns.logger.Println("setup mount namespace")
-	if err := setupNewMountNamespace(rootfs, container.Mounts, console, container.ReadonlyFs, container.NoPivotRoot); err != nil {
-		return fmt.Errorf("setup mount namespace %s", err)
+	// should it should act on it how it sees fit, unless it is restored by a new parent
+	if !container.Restorable {
+		if err := system.ParentDeathSignal(uintptr(syscall.SIGTERM)); err != nil {
+			return fmt.Errorf("parent death signal %s", err)
+		}
 	}
+	// the network is set up while /proc is still the one of the host, where
+	// the network namespace of another process can be joined
 	if err := setupNetwork(container, context); err != nil {
 		return fmt.Errorf("setup networking %s", err)
 	}
+	ns.logger.Println("setup mount namespace")
+	if err := setupNewMountNamespace(rootfs, console, container); err != nil {
+		return fmt.Errorf("setup mount namespace %s", err)
+	}
 	if err := system.Sethostname(container.Hostname); err != nil {
 		return fmt.Errorf("sethostname %s", err)
 	}
-	if err := finalizeNamespace(container); err != nil {
-		return fmt.Errorf("finalize namespace %s", err)
-	}
-
-	if profile := container.Context["apparmor_profile"]; profile != "" {
-		ns.logger.Printf("setting apparmor profile %s\n", profile)
-		if err := apparmor.ApplyProfile(os.Getpid(), profile); err != nil {
-			return err
-		}
+	if err := ns.finalizeProcess(container); err != nil {
+		return err
 	}
 	ns.logger.Printf("execing %s\n", args[0])
 	return system.Execv(args[0], args[0:], container.Env)
@@ -143,6 +143,75 @@ func setupNetwork(container *libcontainer.Container, context libcontainer.Contex
 	return nil
 }
 
+// setupRlimits sets the resource limits of the process, before the
+// capabilities are dropped so that the hard limits can be raised
+func setupRlimits(container *libcontainer.Container) error {
+	for _, rlimit := range container.Rlimits {
+		l := &syscall.Rlimit{Max: rlimit.Hard, Cur: rlimit.Soft}
+		if err := syscall.Setrlimit(rlimit.Type, l); err != nil {
+			return fmt.Errorf("setrlimit %d %s", rlimit.Type, err)
+		}
+	}
+	return nil
+}
+
+// finalizeProcess sets up the process right before it executes the process
+// of the container. The seccomp filter is installed while the process still
+// has CAP_SYS_ADMIN, before the capabilities are dropped and the user is
+// switched, so that it does not need no_new_privs. no_new_privs is only set
+// when the container asks for it, after the labels are set: it would keep
+// the setuid binaries of the container from working.
+func (ns *linuxNs) finalizeProcess(container *libcontainer.Container) error {
+	if err := setupRlimits(container); err != nil {
+		return fmt.Errorf("setup rlimits %s", err)
+	}
+	if err := setupSeccomp(container); err != nil {
+		return fmt.Errorf("setup seccomp %s", err)
+	}
+	if err := finalizeNamespace(container); err != nil {
+		return fmt.Errorf("finalize namespace %s", err)
+	}
+	if err := ns.setupLabels(container); err != nil {
+		return err
+	}
+	if container.NoNewPrivileges {
+		if err := system.SetNoNewPrivileges(); err != nil {
+			return fmt.Errorf("set no new privileges %s", err)
+		}
+	}
+	return nil
+}
+
+// setupSeccomp installs the syscall filter of the container. The filter
+// only applies to the current thread, which is locked to execute the process.
+func setupSeccomp(container *libcontainer.Container) error {
+	if container.Seccomp == nil {
+		return nil
+	}
+	runtime.LockOSThread()
+	return seccomp.InitSeccomp(container.Seccomp)
+}
+
+// setupLabels sets the AppArmor profile and the SELinux label
+// the process of the container is executed with
+func (ns *linuxNs) setupLabels(container *libcontainer.Container) error {
+	if profile := container.Context["apparmor_profile"]; profile != "" {
+		ns.logger.Printf("setting apparmor profile %s\n", profile)
+		if err := apparmor.ApplyProfile(os.Getpid(), profile); err != nil {
+			return err
+		}
+	}
+	if label := container.Context["process_label"]; label != "" {
+		ns.logger.Printf("setting exec label %s\n", label)
+		// the exec label only applies to the current thread
+		runtime.LockOSThread()
+		if err := selinux.SetExecLabel(label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // finalizeNamespace drops the caps and sets the correct user
 // and working dir before execing the command inside the namespace
 func finalizeNamespace(container *libcontainer.Container) error {
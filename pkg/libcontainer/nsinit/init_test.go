@@ -0,0 +1,85 @@
+// +build linux
+
+package nsinit
+
+import (
+	"fmt"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/pkg/libcontainer/seccomp"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+const finalizeEnv = "_NSINIT_TEST_FINALIZE"
+
+// the process is finalized from the main thread like the init of a
+// container, capset only changes the capabilities of the calling thread
+func init() {
+	mode := os.Getenv(finalizeEnv)
+	if mode == "" {
+		return
+	}
+	container := &libcontainer.Container{
+		Seccomp:          seccomp.DefaultProfile(),
+		CapabilitiesMask: libcontainer.Capabilities{libcontainer.GetCapability("SYS_ADMIN")},
+		NoNewPrivileges:  mode == "no_new_privs",
+	}
+	ns := &linuxNs{logger: log.New(ioutil.Discard, "", 0)}
+	if err := ns.finalizeProcess(container); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	status, err := ioutil.ReadFile("/proc/thread-self/status")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(status)
+	os.Exit(0)
+}
+
+// finalizeStatus finalizes a new test process in mode and returns the
+// fields of its status
+func finalizeStatus(t *testing.T, mode string) map[string]string {
+	if os.Getuid() != 0 {
+		t.Skip("finalizing the process needs root")
+	}
+	if !seccomp.IsSupported() {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), finalizeEnv+"="+mode)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to finalize the process: %s: %s", err, out)
+	}
+	fields := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		if parts := strings.SplitN(line, ":", 2); len(parts) == 2 {
+			fields[parts[0]] = strings.TrimSpace(parts[1])
+		}
+	}
+	return fields
+}
+
+func TestFinalizeProcessSeccompBeforeCapabilities(t *testing.T) {
+	status := finalizeStatus(t, "default")
+	// the filter is installed while the process has CAP_SYS_ADMIN
+	if status["Seccomp"] != "2" {
+		t.Fatalf("Expected the seccomp filter to be installed, received Seccomp: %s", status["Seccomp"])
+	}
+	if status["NoNewPrivs"] != "0" {
+		t.Fatalf("Expected no_new_privs not to be set, received NoNewPrivs: %s", status["NoNewPrivs"])
+	}
+}
+
+func TestFinalizeProcessNoNewPrivileges(t *testing.T) {
+	status := finalizeStatus(t, "no_new_privs")
+	if status["Seccomp"] != "2" || status["NoNewPrivs"] != "1" {
+		t.Fatalf("Expected the seccomp filter and no_new_privs, received Seccomp: %s NoNewPrivs: %s", status["Seccomp"], status["NoNewPrivs"])
+	}
+}
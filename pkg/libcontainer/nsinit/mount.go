@@ -5,10 +5,13 @@ package nsinit
 import (
 	"fmt"
 	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/pkg/libcontainer/selinux"
 	"github.com/dotcloud/docker/pkg/system"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
 )
 
@@ -20,9 +23,13 @@ const defaultMountFlags = syscall.MS_NOEXEC | syscall.MS_NOSUID | syscall.MS_NOD
 //
 // There is no need to unmount the new mounts because as soon as the mount namespace
 // is no longer in use, the mounts will be removed automatically
-func setupNewMountNamespace(rootfs string, bindMounts []libcontainer.Mount, console string, readonly, noPivotRoot bool) error {
+func setupNewMountNamespace(rootfs, console string, container *libcontainer.Container) error {
+	// mknod is not permitted in a user namespace, the nodes of the host are bind mounted
+	userns := container.Namespaces.Contains("NEWUSER")
+	mountLabel := container.Context["mount_label"]
+
 	flag := syscall.MS_PRIVATE
-	if noPivotRoot {
+	if container.NoPivotRoot {
 		flag = syscall.MS_SLAVE
 	}
 	if err := system.Mount("", "/", "", uintptr(flag|syscall.MS_REC), ""); err != nil {
@@ -31,16 +38,11 @@ func setupNewMountNamespace(rootfs string, bindMounts []libcontainer.Mount, cons
 	if err := system.Mount(rootfs, rootfs, "bind", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
 		return fmt.Errorf("mouting %s as bind %s", rootfs, err)
 	}
-	if readonly {
-		if err := system.Mount(rootfs, rootfs, "bind", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_REC, ""); err != nil {
-			return fmt.Errorf("mounting %s as readonly %s", rootfs, err)
-		}
-	}
-	if err := mountSystem(rootfs); err != nil {
+	if err := mountSystem(rootfs, mountLabel); err != nil {
 		return fmt.Errorf("mount system %s", err)
 	}
 
-	for _, m := range bindMounts {
+	for _, m := range container.Mounts {
 		flags := syscall.MS_BIND | syscall.MS_REC
 		if !m.Writable {
 			flags = flags | syscall.MS_RDONLY
@@ -55,8 +57,11 @@ func setupNewMountNamespace(rootfs string, bindMounts []libcontainer.Mount, cons
 			}
 		}
 	}
+	if err := mountTmpfs(rootfs, container.Tmpfs, mountLabel); err != nil {
+		return err
+	}
 
-	if err := copyDevNodes(rootfs); err != nil {
+	if err := copyDevNodes(rootfs, container.Devices, userns); err != nil {
 		return fmt.Errorf("copy dev nodes %s", err)
 	}
 	// In non-privileged mode, this fails. Discard the error.
@@ -64,14 +69,14 @@ func setupNewMountNamespace(rootfs string, bindMounts []libcontainer.Mount, cons
 	if err := setupDev(rootfs); err != nil {
 		return err
 	}
-	if err := setupPtmx(rootfs, console); err != nil {
+	if err := setupPtmx(rootfs, console, userns); err != nil {
 		return err
 	}
 	if err := system.Chdir(rootfs); err != nil {
 		return fmt.Errorf("chdir into %s %s", rootfs, err)
 	}
 
-	if noPivotRoot {
+	if container.NoPivotRoot {
 		if err := rootMsMove(rootfs); err != nil {
 			return err
 		}
@@ -81,6 +86,14 @@ func setupNewMountNamespace(rootfs string, bindMounts []libcontainer.Mount, cons
 		}
 	}
 
+	// the rootfs is set readonly once the nodes and the mount points are
+	// created in it, the mounts on top of it stay writable
+	if container.ReadonlyFs {
+		if err := system.Mount("/", "/", "bind", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY, ""); err != nil {
+			return fmt.Errorf("mounting / as readonly %s", err)
+		}
+	}
+
 	system.Umask(0022)
 
 	return nil
@@ -90,7 +103,7 @@ func setupNewMountNamespace(rootfs string, bindMounts []libcontainer.Mount, cons
 func rootPivot(rootfs string) error {
 	pivotDir, err := ioutil.TempDir(rootfs, ".pivot_root")
 	if err != nil {
-		return fmt.Errorf("can't create pivot_root dir %s", pivotDir, err)
+		return fmt.Errorf("can't create pivot_root dir %s %s", pivotDir, err)
 	}
 	if err := system.Pivotroot(rootfs, pivotDir); err != nil {
 		return fmt.Errorf("pivot_root %s", err)
@@ -123,8 +136,10 @@ func rootMsMove(rootfs string) error {
 	return nil
 }
 
-// copyDevNodes mknods the hosts devices so the new container has access to them
-func copyDevNodes(rootfs string) error {
+// copyDevNodes mknods the hosts devices so the new container has access to them,
+// the default ones and the devices of the container. With bind, the nodes of
+// the host are bind mounted instead.
+func copyDevNodes(rootfs string, devices []*libcontainer.Device, bind bool) error {
 	oldMask := system.Umask(0000)
 	defer system.Umask(oldMask)
 
@@ -136,13 +151,50 @@ func copyDevNodes(rootfs string) error {
 		"urandom",
 		"tty",
 	} {
-		if err := copyDevNode(rootfs, node); err != nil {
+		if bind {
+			if err := bindDevNode(filepath.Join("/dev", node), filepath.Join(rootfs, "dev", node)); err != nil {
+				return fmt.Errorf("bind %s %s", node, err)
+			}
+		} else if err := copyDevNode(rootfs, node); err != nil {
+			return err
+		}
+	}
+	for _, device := range devices {
+		if err := createDevice(rootfs, device, bind); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// createDevice mknods a device at its path in the container, the
+// existing node is replaced by the device given to the container
+func createDevice(rootfs string, device *libcontainer.Device, bind bool) error {
+	dest := filepath.Join(rootfs, device.Path)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("create parent of %s %s", device.Path, err)
+	}
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove %s %s", device.Path, err)
+	}
+	if bind {
+		if err := bindDevNode(device.HostPath, dest); err != nil {
+			return fmt.Errorf("bind %s %s", device.Path, err)
+		}
+		return nil
+	}
+	mode := uint32(device.FileMode)
+	if device.Type == "c" {
+		mode |= syscall.S_IFCHR
+	} else {
+		mode |= syscall.S_IFBLK
+	}
+	if err := system.Mknod(dest, mode, device.Mkdev()); err != nil {
+		return fmt.Errorf("mknod %s %s", device.Path, err)
+	}
+	return nil
+}
+
 func setupLoopbackDevices(rootfs string) error {
 	for i := 0; ; i++ {
 		if err := copyDevNode(rootfs, fmt.Sprintf("loop%d", i)); err != nil {
@@ -171,6 +223,17 @@ func copyDevNode(rootfs, node string) error {
 	return nil
 }
 
+// bindDevNode bind mounts the node of the host at src on
+// a file created at dest, the existing file is kept
+func bindDevNode(src, dest string) error {
+	f, err := os.OpenFile(dest, os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return system.Mount(src, dest, "bind", syscall.MS_BIND, "")
+}
+
 // setupDev symlinks the current processes pipes into the
 // appropriate destination on the containers rootfs
 func setupDev(rootfs string) error {
@@ -195,8 +258,9 @@ func setupDev(rootfs string) error {
 	return nil
 }
 
-// setupConsole ensures that the container has a proper /dev/console setup
-func setupConsole(rootfs, console string) error {
+// setupConsole ensures that the container has a proper /dev/console setup,
+// with bind the console is mounted on a file instead of a node
+func setupConsole(rootfs, console string, bind bool) error {
 	oldMask := system.Umask(0000)
 	defer system.Umask(oldMask)
 
@@ -217,6 +281,12 @@ func setupConsole(rootfs, console string) error {
 	if err := os.Chown(console, 0, 0); err != nil {
 		return err
 	}
+	if bind {
+		if err := bindDevNode(console, dest); err != nil {
+			return fmt.Errorf("bind %s to %s %s", console, dest, err)
+		}
+		return nil
+	}
 	if err := system.Mknod(dest, (st.Mode&^07777)|0600, int(st.Rdev)); err != nil {
 		return fmt.Errorf("mknod %s %s", dest, err)
 	}
@@ -227,8 +297,8 @@ func setupConsole(rootfs, console string) error {
 }
 
 // mountSystem sets up linux specific system mounts like sys, proc, shm, and devpts
-// inside the mount namespace
-func mountSystem(rootfs string) error {
+// inside the mount namespace, the files of shm and devpts get the mount label
+func mountSystem(rootfs, mountLabel string) error {
 	for _, m := range []struct {
 		source string
 		path   string
@@ -238,8 +308,8 @@ func mountSystem(rootfs string) error {
 	}{
 		{source: "proc", path: filepath.Join(rootfs, "proc"), device: "proc", flags: defaultMountFlags},
 		{source: "sysfs", path: filepath.Join(rootfs, "sys"), device: "sysfs", flags: defaultMountFlags},
-		{source: "shm", path: filepath.Join(rootfs, "dev", "shm"), device: "tmpfs", flags: defaultMountFlags, data: "mode=1777,size=65536k"},
-		{source: "devpts", path: filepath.Join(rootfs, "dev", "pts"), device: "devpts", flags: syscall.MS_NOSUID | syscall.MS_NOEXEC, data: "newinstance,ptmxmode=0666,mode=620,gid=5"},
+		{source: "shm", path: filepath.Join(rootfs, "dev", "shm"), device: "tmpfs", flags: defaultMountFlags, data: selinux.FormatMountLabel("mode=1777,size=65536k", mountLabel)},
+		{source: "devpts", path: filepath.Join(rootfs, "dev", "pts"), device: "devpts", flags: syscall.MS_NOSUID | syscall.MS_NOEXEC, data: selinux.FormatMountLabel("newinstance,ptmxmode=0666,mode=620,gid=5", mountLabel)},
 	} {
 		if err := os.MkdirAll(m.path, 0755); err != nil && !os.IsExist(err) {
 			return fmt.Errorf("mkdirall %s %s", m.path, err)
@@ -251,9 +321,80 @@ func mountSystem(rootfs string) error {
 	return nil
 }
 
+// mountTmpfs mounts a tmpfs at each of the paths in the container, the parents first
+func mountTmpfs(rootfs string, tmpfs map[string]string, mountLabel string) error {
+	paths := make([]string, 0, len(tmpfs))
+	for path := range tmpfs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		dest := filepath.Join(rootfs, path)
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return fmt.Errorf("mkdirall %s %s", dest, err)
+		}
+		flags, data := parseMountOptions(tmpfs[path])
+		if err := system.Mount("tmpfs", dest, "tmpfs", uintptr(flags), selinux.FormatMountLabel(data, mountLabel)); err != nil {
+			return fmt.Errorf("mounting tmpfs into %s %s", dest, err)
+		}
+	}
+	return nil
+}
+
+// parseMountOptions splits comma separated mount options into the flags of
+// the mount and the data given to the filesystem. The later options override
+// the earlier ones, as with mount(8).
+func parseMountOptions(options string) (int, string) {
+	var (
+		flags int
+		data  []string
+	)
+	for _, o := range strings.Split(options, ",") {
+		if o == "" {
+			continue
+		}
+		f, exists := mountFlags[o]
+		switch {
+		case !exists:
+			data = append(data, o)
+		case f.clear:
+			flags &^= f.flag
+		default:
+			flags |= f.flag
+		}
+	}
+	return flags, strings.Join(data, ",")
+}
+
+var mountFlags = map[string]struct {
+	clear bool
+	flag  int
+}{
+	"ro":          {false, syscall.MS_RDONLY},
+	"rw":          {true, syscall.MS_RDONLY},
+	"nosuid":      {false, syscall.MS_NOSUID},
+	"suid":        {true, syscall.MS_NOSUID},
+	"nodev":       {false, syscall.MS_NODEV},
+	"dev":         {true, syscall.MS_NODEV},
+	"noexec":      {false, syscall.MS_NOEXEC},
+	"exec":        {true, syscall.MS_NOEXEC},
+	"sync":        {false, syscall.MS_SYNCHRONOUS},
+	"async":       {true, syscall.MS_SYNCHRONOUS},
+	"noatime":     {false, syscall.MS_NOATIME},
+	"atime":       {true, syscall.MS_NOATIME},
+	"nodiratime":  {false, syscall.MS_NODIRATIME},
+	"diratime":    {true, syscall.MS_NODIRATIME},
+	"relatime":    {false, syscall.MS_RELATIME},
+	"norelatime":  {true, syscall.MS_RELATIME},
+	"strictatime": {false, syscall.MS_STRICTATIME},
+	"mand":        {false, syscall.MS_MANDLOCK},
+	"nomand":      {true, syscall.MS_MANDLOCK},
+}
+
 // setupPtmx adds a symlink to pts/ptmx for /dev/ptmx and
 // finishes setting up /dev/console
-func setupPtmx(rootfs, console string) error {
+func setupPtmx(rootfs, console string, bind bool) error {
 	ptmx := filepath.Join(rootfs, "dev/ptmx")
 	if err := os.Remove(ptmx); err != nil && !os.IsNotExist(err) {
 		return err
@@ -262,7 +403,7 @@ func setupPtmx(rootfs, console string) error {
 		return fmt.Errorf("symlink dev ptmx %s", err)
 	}
 	if console != "" {
-		if err := setupConsole(rootfs, console); err != nil {
+		if err := setupConsole(rootfs, console, bind); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,22 @@
+// +build linux
+
+package nsinit
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseMountOptions(t *testing.T) {
+	flags, data := parseMountOptions("nosuid,nodev,noexec,size=64m,exec,mode=1777")
+	if expected := syscall.MS_NOSUID | syscall.MS_NODEV; flags != expected {
+		t.Fatalf("Expected the flags %#x, received %#x", expected, flags)
+	}
+	if data != "size=64m,mode=1777" {
+		t.Fatalf("Expected the data size=64m,mode=1777, received %s", data)
+	}
+
+	if flags, data := parseMountOptions(""); flags != 0 || data != "" {
+		t.Fatalf("Expected no flags and no data, received %#x and %s", flags, data)
+	}
+}
@@ -0,0 +1,38 @@
+// +build linux,cgo
+
+package nsinit
+
+/*
+#define _GNU_SOURCE
+#include <fcntl.h>
+#include <sched.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <unistd.h>
+
+// nsenter_userns joins the user namespace of the process whose pid is in
+// _LIBCONTAINER_USERNS_PID. The kernel refuses setns into a user namespace
+// to a multithreaded process, so it runs before the Go runtime starts.
+__attribute__((constructor)) static void nsenter_userns(void)
+{
+	char path[64];
+	char *pid = getenv("_LIBCONTAINER_USERNS_PID");
+	int fd;
+
+	if (pid == NULL || *pid == '\0') {
+		return;
+	}
+	snprintf(path, sizeof(path), "/proc/%s/ns/user", pid);
+	fd = open(path, O_RDONLY);
+	if (fd < 0) {
+		fprintf(stderr, "nsenter: open %s: %m\n", path);
+		exit(1);
+	}
+	if (setns(fd, CLONE_NEWUSER) < 0) {
+		fprintf(stderr, "nsenter: setns %s: %m\n", path);
+		exit(1);
+	}
+	close(fd);
+}
+*/
+import "C"
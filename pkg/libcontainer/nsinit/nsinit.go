@@ -5,11 +5,15 @@ import (
 	"log"
 )
 
+// UsernsPidEnv is the environment variable with the pid of the container
+// whose user namespace ExecIn joins, before the Go runtime starts
+const UsernsPidEnv = "_LIBCONTAINER_USERNS_PID"
+
 // NsInit is an interface with the public facing methods to provide high level
 // exec operations on a container
 type NsInit interface {
 	Exec(container *libcontainer.Container, term Terminal, args []string) (int, error)
-	ExecIn(container *libcontainer.Container, nspid int, args []string) (int, error)
+	ExecIn(container *libcontainer.Container, nspid int, console string, args []string) (int, error)
 	Init(container *libcontainer.Container, uncleanRootfs, console string, syncPipe *SyncPipe, args []string) error
 }
 
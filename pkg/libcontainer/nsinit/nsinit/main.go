@@ -10,7 +10,6 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
 )
 
 var (
@@ -50,14 +49,14 @@ func main() {
 	switch flag.Arg(0) {
 	case "exec": // this is executed outside of the namespace in the cwd
 		var exitCode int
-		nspid, err := readPid()
+		state, err := nsinit.ReadState(root)
 		if err != nil {
 			if !os.IsNotExist(err) {
-				l.Fatalf("Unable to read pid: %s", err)
+				l.Fatalf("Unable to read state: %s", err)
 			}
 		}
-		if nspid > 0 {
-			exitCode, err = ns.ExecIn(container, nspid, flag.Args()[1:])
+		if state != nil && state.IsRunning() {
+			exitCode, err = ns.ExecIn(container, state.InitPid, console, flag.Args()[1:])
 		} else {
 			term := nsinit.NewTerminal(os.Stdin, os.Stdout, os.Stderr, container.Tty)
 			exitCode, err = ns.Exec(container, term, flag.Args()[1:])
@@ -100,18 +99,6 @@ func loadContainer() (*libcontainer.Container, error) {
 	return container, nil
 }
 
-func readPid() (int, error) {
-	data, err := ioutil.ReadFile(filepath.Join(root, "pid"))
-	if err != nil {
-		return -1, err
-	}
-	pid, err := strconv.Atoi(string(data))
-	if err != nil {
-		return -1, err
-	}
-	return pid, nil
-}
-
 func newNsInit(l *log.Logger) (nsinit.NsInit, error) {
 	return nsinit.NewNsInit(&nsinit.DefaultCommandFactory{root}, &nsinit.DefaultStateWriter{root}, l), nil
 }
@@ -1,28 +1,48 @@
 package nsinit
 
 import (
-	"fmt"
+	"encoding/json"
+	"github.com/dotcloud/docker/pkg/libcontainer"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 )
 
-// StateWriter handles writing and deleting the pid file
-// on disk
+// StateWriter handles writing and deleting the state of
+// the running container on disk
 type StateWriter interface {
-	WritePid(pid int) error
-	DeletePid() error
+	WriteState(state *libcontainer.State) error
+	DeleteState() error
 }
 
 type DefaultStateWriter struct {
 	Root string
 }
 
-// writePidFile writes the namespaced processes pid to pid in the rootfs for the container
-func (d *DefaultStateWriter) WritePid(pid int) error {
-	return ioutil.WriteFile(filepath.Join(d.Root, "pid"), []byte(fmt.Sprint(pid)), 0655)
+// WriteState writes the state of the container to state.json in the root of the container
+func (d *DefaultStateWriter) WriteState(state *libcontainer.State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(d.Root, "state.json"), data, 0655)
 }
 
-func (d *DefaultStateWriter) DeletePid() error {
-	return os.Remove(filepath.Join(d.Root, "pid"))
+func (d *DefaultStateWriter) DeleteState() error {
+	return os.Remove(filepath.Join(d.Root, "state.json"))
+}
+
+// ReadState returns the state written by the DefaultStateWriter in root
+func ReadState(root string) (*libcontainer.State, error) {
+	f, err := os.Open(filepath.Join(root, "state.json"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var state *libcontainer.State
+	if err := json.NewDecoder(f).Decode(&state); err != nil {
+		return nil, err
+	}
+	return state, nil
 }
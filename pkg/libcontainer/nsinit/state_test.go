@@ -0,0 +1,43 @@
+package nsinit
+
+import (
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestWriteState(t *testing.T) {
+	root, err := ioutil.TempDir("", "nsinit-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	state := &libcontainer.State{
+		InitPid:           4242,
+		InitStartTime:     "9876543",
+		NamespacePaths:    map[string]string{"NEWNET": "/proc/4242/ns/net"},
+		CgroupPaths:       map[string]string{"devices": "/sys/fs/cgroup/devices/docker/123"},
+		NetworkInterfaces: map[string]string{"veth-host": "veth1234", "veth-child": "eth0"},
+	}
+	w := &DefaultStateWriter{Root: root}
+	if err := w.WriteState(state); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadState(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, state) {
+		t.Fatalf("Expected the state %v, got %v", state, read)
+	}
+
+	if err := w.DeleteState(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadState(root); !os.IsNotExist(err) {
+		t.Fatalf("Expected the state to be deleted, got %v", err)
+	}
+}
@@ -10,7 +10,7 @@ func (ns *linuxNs) Exec(container *libcontainer.Container, term Terminal, args [
 	return -1, libcontainer.ErrUnsupported
 }
 
-func (ns *linuxNs) ExecIn(container *libcontainer.Container, nspid int, args []string) (int, error) {
+func (ns *linuxNs) ExecIn(container *libcontainer.Container, nspid int, console string, args []string) (int, error) {
 	return -1, libcontainer.ErrUnsupported
 }
 
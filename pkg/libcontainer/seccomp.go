@@ -0,0 +1,46 @@
+package libcontainer
+
+// Action is what the kernel does when a process makes a syscall
+type Action string
+
+const (
+	Allow Action = "allow" // run the syscall
+	Errno Action = "errno" // fail the syscall with EPERM
+	Trap  Action = "trap"  // send SIGSYS to the process
+	Kill  Action = "kill"  // kill the process
+)
+
+// Operator compares an argument of a syscall to a value
+type Operator string
+
+const (
+	EqualTo      Operator = "=="
+	NotEqualTo   Operator = "!="
+	GreaterThan  Operator = ">"
+	GreaterEqual Operator = ">="
+	LessThan     Operator = "<"
+	LessEqual    Operator = "<="
+	MaskedEqual  Operator = "&" // the argument masked with Value is equal to ValueTwo
+)
+
+// Seccomp is the syscall filter installed in the container
+type Seccomp struct {
+	DefaultAction Action     `json:"default_action"` // action of the syscalls matched by no rule
+	Syscalls      []*Syscall `json:"syscalls"`
+}
+
+// Syscall is a rule of a seccomp filter. The rules of a syscall are
+// evaluated in order and the first one whose arguments match applies.
+type Syscall struct {
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	Args   []*Arg `json:"args,omitempty"` // all the conditions must be true for the rule to match
+}
+
+// Arg is a condition on an argument of a syscall
+type Arg struct {
+	Index    uint     `json:"index"` // index of the argument, from 0 to 5
+	Value    uint64   `json:"value"`
+	ValueTwo uint64   `json:"value_two,omitempty"` // only used by the & operator
+	Op       Operator `json:"op"`
+}
@@ -0,0 +1,88 @@
+package seccomp
+
+import (
+	"github.com/dotcloud/docker/pkg/libcontainer"
+)
+
+const cloneNewUser = 0x10000000 // CLONE_NEWUSER
+
+// deniedSyscalls fail with EPERM in the default profile: they administer
+// the host, load kernel code, or give access to other processes and
+// to the keyring of the kernel
+var deniedSyscalls = []string{
+	"_sysctl",
+	"acct",
+	"add_key",
+	"bpf",
+	"clock_adjtime",
+	"clock_settime",
+	"create_module",
+	"delete_module",
+	"finit_module",
+	"get_kernel_syms",
+	"init_module",
+	"ioperm",
+	"iopl",
+	"kcmp",
+	"kexec_file_load",
+	"kexec_load",
+	"keyctl",
+	"lookup_dcookie",
+	"nfsservctl",
+	"open_by_handle_at",
+	"perf_event_open",
+	"process_vm_readv",
+	"process_vm_writev",
+	"ptrace",
+	"query_module",
+	"quotactl",
+	"reboot",
+	"request_key",
+	"settimeofday",
+	"swapoff",
+	"swapon",
+	"sysfs",
+	"uselib",
+	"userfaultfd",
+	"ustat",
+	"vhangup",
+}
+
+// DefaultProfile returns the seccomp profile of the containers which do
+// not select their own. It allows the syscalls by default and denies the
+// ones which are not useful in a container.
+func DefaultProfile() *libcontainer.Seccomp {
+	profile := &libcontainer.Seccomp{
+		DefaultAction: libcontainer.Allow,
+	}
+	for _, name := range deniedSyscalls {
+		profile.Syscalls = append(profile.Syscalls, &libcontainer.Syscall{
+			Name:   name,
+			Action: libcontainer.Errno,
+		})
+	}
+
+	// only the default linux personality, its 32 bit variant and
+	// the query of the current personality are allowed
+	for _, persona := range []uint64{0x0, 0x8, 0xffffffff} {
+		profile.Syscalls = append(profile.Syscalls, &libcontainer.Syscall{
+			Name:   "personality",
+			Action: libcontainer.Allow,
+			Args:   []*libcontainer.Arg{{Index: 0, Value: persona, Op: libcontainer.EqualTo}},
+		})
+	}
+	profile.Syscalls = append(profile.Syscalls, &libcontainer.Syscall{
+		Name:   "personality",
+		Action: libcontainer.Errno,
+	})
+
+	// user namespaces cannot be created in the container
+	for _, name := range []string{"clone", "unshare"} {
+		profile.Syscalls = append(profile.Syscalls, &libcontainer.Syscall{
+			Name:   name,
+			Action: libcontainer.Errno,
+			Args:   []*libcontainer.Arg{{Index: 0, Value: cloneNewUser, ValueTwo: cloneNewUser, Op: libcontainer.MaskedEqual}},
+		})
+	}
+	return profile
+}
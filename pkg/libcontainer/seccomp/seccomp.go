@@ -0,0 +1,323 @@
+package seccomp
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"os"
+)
+
+// instructions and return values of the seccomp BPF programs
+const (
+	bpfLoad = 0x20 // BPF_LD | BPF_W | BPF_ABS
+	bpfAnd  = 0x54 // BPF_ALU | BPF_AND | BPF_K
+	bpfJeq  = 0x15 // BPF_JMP | BPF_JEQ | BPF_K
+	bpfJgt  = 0x25 // BPF_JMP | BPF_JGT | BPF_K
+	bpfJge  = 0x35 // BPF_JMP | BPF_JGE | BPF_K
+	bpfJa   = 0x05 // BPF_JMP | BPF_JA
+	bpfRet  = 0x06 // BPF_RET | BPF_K
+
+	retKill  = 0x00000000
+	retTrap  = 0x00030000
+	retErrno = 0x00050000
+	retAllow = 0x7fff0000
+
+	// offsets in struct seccomp_data
+	offsetNr   = 0
+	offsetArch = 4
+	offsetArgs = 16
+
+	// the syscalls of the x32 ABI have this bit set on x86_64
+	x32SyscallBit = 0x40000000
+
+	maxInstructions = 4096
+	eperm           = 1
+)
+
+// sockFilter is a BPF instruction, struct sock_filter
+type sockFilter struct {
+	Code uint16
+	Jt   uint8
+	Jf   uint8
+	K    uint32
+}
+
+// IsSupported returns true if the seccomp filters can
+// be compiled for the architecture of the daemon
+func IsSupported() bool {
+	return syscallTable != nil
+}
+
+// LoadProfile reads a seccomp profile from a json file
+func LoadProfile(path string) (*libcontainer.Seccomp, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config := &libcontainer.Seccomp{}
+	if err := json.NewDecoder(f).Decode(config); err != nil {
+		return nil, fmt.Errorf("Invalid seccomp profile %s: %s", path, err)
+	}
+	if _, err := Compile(config); err != nil {
+		return nil, fmt.Errorf("Invalid seccomp profile %s: %s", path, err)
+	}
+	return config, nil
+}
+
+// Compile translates a seccomp profile into a BPF program for the
+// architecture of the daemon and, on x86_64, for its 32 bit processes
+func Compile(config *libcontainer.Seccomp) ([]sockFilter, error) {
+	if !IsSupported() {
+		return nil, fmt.Errorf("seccomp is not supported on this architecture")
+	}
+	defaultRet, err := actionRet(config.DefaultAction)
+	if err != nil {
+		return nil, err
+	}
+
+	// the rules of a syscall are grouped in the order of their first appearance
+	var (
+		names []string
+		rules = make(map[string][]*libcontainer.Syscall)
+	)
+	for _, s := range config.Syscalls {
+		if _, exists := syscallTable[s.Name]; !exists {
+			return nil, fmt.Errorf("Unknown syscall: %s", s.Name)
+		}
+		if _, exists := rules[s.Name]; !exists {
+			names = append(names, s.Name)
+		}
+		rules[s.Name] = append(rules[s.Name], s)
+	}
+
+	// the 32 bit processes have their own syscall numbers, the rules are
+	// compiled again for them. The syscalls of other architectures and
+	// ABIs kill the process, their numbers are not the ones of the tables.
+	p := &program{}
+	p.load(offsetArch)
+	if syscallTable32 != nil {
+		p.jump(bpfJeq, auditArch32, "", "native")
+		p.jumpAlways("compat")
+		p.label("native")
+	}
+	p.jump(bpfJeq, auditArch, "", "kill")
+	p.load(offsetNr)
+	p.jump(bpfJge, x32SyscallBit, "kill", "syscalls")
+	p.label("kill")
+	p.ret(retKill)
+	p.label("syscalls")
+	if err := p.syscalls("native", syscallTable, names, rules, defaultRet); err != nil {
+		return nil, err
+	}
+	if syscallTable32 != nil {
+		p.label("compat")
+		p.load(offsetNr)
+		if err := p.syscalls("compat", syscallTable32, names, rules, defaultRet); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.assemble()
+}
+
+// syscalls compiles the rules of the syscalls with their numbers in the
+// table, the syscalls missing from the table are skipped. The number of
+// the syscall must be loaded.
+func (p *program) syscalls(prefix string, table map[string]int, names []string, rules map[string][]*libcontainer.Syscall, defaultRet uint32) error {
+	for i, name := range names {
+		nr, exists := table[name]
+		if !exists {
+			continue
+		}
+		next := fmt.Sprintf("%s.syscall%d", prefix, i)
+		p.jump(bpfJeq, uint32(nr), "", next)
+		for j, rule := range rules[name] {
+			ret, err := actionRet(rule.Action)
+			if err != nil {
+				return err
+			}
+			fail := fmt.Sprintf("%s.rule%d.%d", prefix, i, j)
+			for _, arg := range rule.Args {
+				if err := p.condition(arg, fail); err != nil {
+					return fmt.Errorf("Invalid condition on %s: %s", name, err)
+				}
+			}
+			p.ret(ret)
+			p.label(fail)
+		}
+		// none of the rules of the syscall matched
+		p.ret(defaultRet)
+		p.label(next)
+	}
+	p.ret(defaultRet)
+	return nil
+}
+
+func actionRet(action libcontainer.Action) (uint32, error) {
+	switch action {
+	case libcontainer.Allow:
+		return retAllow, nil
+	case libcontainer.Errno:
+		return retErrno | eperm, nil
+	case libcontainer.Trap:
+		return retTrap, nil
+	case libcontainer.Kill:
+		return retKill, nil
+	}
+	return 0, fmt.Errorf("Unknown seccomp action: %s", action)
+}
+
+// instruction is a BPF instruction whose jumps target labels,
+// an empty label continues with the next instruction
+type instruction struct {
+	code   uint16
+	k      uint32
+	jt, jf string
+}
+
+// program assembles the BPF instructions once the positions
+// of the labels are known
+type program struct {
+	instructions []instruction
+	labels       map[string]int
+	count        int // number of the labels created by newLabel
+}
+
+func (p *program) load(offset uint32) {
+	p.instructions = append(p.instructions, instruction{code: bpfLoad, k: offset})
+}
+
+func (p *program) and(mask uint32) {
+	p.instructions = append(p.instructions, instruction{code: bpfAnd, k: mask})
+}
+
+func (p *program) jump(code uint16, k uint32, jt, jf string) {
+	p.instructions = append(p.instructions, instruction{code: code, k: k, jt: jt, jf: jf})
+}
+
+// jumpAlways jumps to the label, which may be further than 255 instructions
+func (p *program) jumpAlways(label string) {
+	p.instructions = append(p.instructions, instruction{code: bpfJa, jt: label})
+}
+
+func (p *program) ret(k uint32) {
+	p.instructions = append(p.instructions, instruction{code: bpfRet, k: k})
+}
+
+// label names the position of the next instruction
+func (p *program) label(name string) {
+	if p.labels == nil {
+		p.labels = make(map[string]int)
+	}
+	p.labels[name] = len(p.instructions)
+}
+
+// condition continues with the next instruction when the argument matches
+// the condition, and jumps to fail otherwise
+func (p *program) condition(arg *libcontainer.Arg, fail string) error {
+	if arg.Index > 5 {
+		return fmt.Errorf("the index of an argument must be between 0 and 5, not %d", arg.Index)
+	}
+	var (
+		// the arguments are 64 bit little endian values, compared 32 bits at a time
+		low   = uint32(offsetArgs + 8*arg.Index)
+		high  = low + 4
+		value = arg.Value
+		next  = p.newLabel()
+	)
+	if arg.Op == libcontainer.MaskedEqual {
+		value = arg.ValueTwo
+	}
+	vLow, vHigh := uint32(value), uint32(value>>32)
+
+	p.load(high)
+	switch arg.Op {
+	case libcontainer.EqualTo:
+		p.jump(bpfJeq, vHigh, "", fail)
+		p.load(low)
+		p.jump(bpfJeq, vLow, "", fail)
+	case libcontainer.NotEqualTo:
+		p.jump(bpfJeq, vHigh, "", next)
+		p.load(low)
+		p.jump(bpfJeq, vLow, fail, "")
+	case libcontainer.GreaterThan, libcontainer.GreaterEqual:
+		p.jump(bpfJgt, vHigh, next, "")
+		p.jump(bpfJeq, vHigh, "", fail)
+		p.load(low)
+		if arg.Op == libcontainer.GreaterThan {
+			p.jump(bpfJgt, vLow, "", fail)
+		} else {
+			p.jump(bpfJge, vLow, "", fail)
+		}
+	case libcontainer.LessThan, libcontainer.LessEqual:
+		p.jump(bpfJgt, vHigh, fail, "")
+		p.jump(bpfJeq, vHigh, "", next)
+		p.load(low)
+		if arg.Op == libcontainer.LessThan {
+			p.jump(bpfJge, vLow, fail, "")
+		} else {
+			p.jump(bpfJgt, vLow, fail, "")
+		}
+	case libcontainer.MaskedEqual:
+		p.and(uint32(arg.Value >> 32))
+		p.jump(bpfJeq, vHigh, "", fail)
+		p.load(low)
+		p.and(uint32(arg.Value))
+		p.jump(bpfJeq, vLow, "", fail)
+	default:
+		return fmt.Errorf("unknown operator %s", arg.Op)
+	}
+	p.label(next)
+	return nil
+}
+
+func (p *program) newLabel() string {
+	p.count++
+	return fmt.Sprintf("label%d", p.count)
+}
+
+// assemble resolves the jumps to the labels, BPF jumps forward
+// of at most 255 instructions, except for the unconditional ones
+func (p *program) assemble() ([]sockFilter, error) {
+	if len(p.instructions) > maxInstructions {
+		return nil, fmt.Errorf("The seccomp filter is too large: %d instructions", len(p.instructions))
+	}
+	filter := make([]sockFilter, len(p.instructions))
+	for i, ins := range p.instructions {
+		if ins.code == bpfJa {
+			target, exists := p.labels[ins.jt]
+			if !exists {
+				return nil, fmt.Errorf("Undefined label %s in the seccomp filter", ins.jt)
+			}
+			filter[i] = sockFilter{Code: ins.code, K: uint32(target - i - 1)}
+			continue
+		}
+		jt, err := p.offset(i, ins.jt)
+		if err != nil {
+			return nil, err
+		}
+		jf, err := p.offset(i, ins.jf)
+		if err != nil {
+			return nil, err
+		}
+		filter[i] = sockFilter{Code: ins.code, Jt: jt, Jf: jf, K: ins.k}
+	}
+	return filter, nil
+}
+
+func (p *program) offset(i int, label string) (uint8, error) {
+	if label == "" {
+		return 0, nil
+	}
+	target, exists := p.labels[label]
+	if !exists {
+		return 0, fmt.Errorf("Undefined label %s in the seccomp filter", label)
+	}
+	offset := target - i - 1
+	if offset < 0 || offset > 255 {
+		return 0, fmt.Errorf("Too many rules for a syscall in the seccomp filter")
+	}
+	return uint8(offset), nil
+}
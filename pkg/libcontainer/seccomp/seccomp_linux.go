@@ -0,0 +1,42 @@
+// +build linux
+
+package seccomp
+
+import (
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	prSetSeccomp      = 22
+	seccompModeFilter = 2
+)
+
+// sockFprog is a BPF program, struct sock_fprog
+type sockFprog struct {
+	Len    uint16
+	Filter *sockFilter
+}
+
+// InitSeccomp installs the seccomp filter of the profile for the current
+// thread and the processes it executes. The kernel only accepts the filter
+// with CAP_SYS_ADMIN or once no_new_privs is set, which is left to the caller.
+func InitSeccomp(config *libcontainer.Seccomp) error {
+	filter, err := Compile(config)
+	if err != nil {
+		return err
+	}
+	prog := &sockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+	return prctl(prSetSeccomp, seccompModeFilter, uintptr(unsafe.Pointer(prog)))
+}
+
+func prctl(option int, arg2, arg3 uintptr) error {
+	if _, _, err := syscall.RawSyscall(syscall.SYS_PRCTL, uintptr(option), arg2, arg3); err != 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package seccomp
+
+import (
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// run interprets the filter for a syscall of the architecture of the daemon
+func run(t *testing.T, filter []sockFilter, name string, args ...uint64) uint32 {
+	return runArch(t, filter, auditArch, syscallTable[name], args...)
+}
+
+// runArch interprets the filter for the syscall nr of the architecture arch
+func runArch(t *testing.T, filter []sockFilter, arch uint32, nr int, args ...uint64) uint32 {
+	var data [64]byte
+	put := func(offset int, v uint32) {
+		data[offset] = byte(v)
+		data[offset+1] = byte(v >> 8)
+		data[offset+2] = byte(v >> 16)
+		data[offset+3] = byte(v >> 24)
+	}
+	put(offsetNr, uint32(nr))
+	put(offsetArch, arch)
+	for i, arg := range args {
+		put(offsetArgs+8*i, uint32(arg))
+		put(offsetArgs+8*i+4, uint32(arg>>32))
+	}
+
+	var a uint32
+	for pc := 0; pc < len(filter); pc++ {
+		ins := filter[pc]
+		switch ins.Code {
+		case bpfLoad:
+			k := int(ins.K)
+			a = uint32(data[k]) | uint32(data[k+1])<<8 | uint32(data[k+2])<<16 | uint32(data[k+3])<<24
+		case bpfAnd:
+			a &= ins.K
+		case bpfJa:
+			pc += int(ins.K)
+		case bpfJeq, bpfJgt, bpfJge:
+			var match bool
+			switch ins.Code {
+			case bpfJeq:
+				match = a == ins.K
+			case bpfJgt:
+				match = a > ins.K
+			case bpfJge:
+				match = a >= ins.K
+			}
+			if match {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case bpfRet:
+			return ins.K
+		default:
+			t.Fatalf("Unknown instruction %#x", ins.Code)
+		}
+	}
+	t.Fatalf("The filter of the syscall %d of %#x does not return", nr, arch)
+	return 0
+}
+
+func compile(t *testing.T, config *libcontainer.Seccomp) []sockFilter {
+	if !IsSupported() {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	filter, err := Compile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filter
+}
+
+func TestDefaultProfile(t *testing.T) {
+	filter := compile(t, DefaultProfile())
+
+	for _, c := range []struct {
+		name     string
+		args     []uint64
+		expected uint32
+	}{
+		{"read", nil, retAllow},
+		{"ptrace", nil, retErrno | eperm},
+		{"reboot", nil, retErrno | eperm},
+		{"personality", []uint64{0x0}, retAllow},
+		{"personality", []uint64{0xffffffff}, retAllow},
+		{"personality", []uint64{0x0400000}, retErrno | eperm},
+		{"personality", []uint64{0x100000000}, retErrno | eperm},
+		{"clone", []uint64{0x01200011}, retAllow},
+		{"clone", []uint64{0x10000000 | 0x00020000}, retErrno | eperm},
+		{"unshare", []uint64{0x00020000}, retAllow},
+		{"unshare", []uint64{0x10000000}, retErrno | eperm},
+	} {
+		if ret := run(t, filter, c.name, c.args...); ret != c.expected {
+			t.Errorf("Expected %#x for %s%v, received %#x", c.expected, c.name, c.args, ret)
+		}
+	}
+}
+
+func TestDefaultProfileI386(t *testing.T) {
+	filter := compile(t, DefaultProfile())
+	if syscallTable32 == nil {
+		t.Skip("the architecture of the daemon has no 32 bit processes")
+	}
+
+	for _, c := range []struct {
+		name     string
+		args     []uint64
+		expected uint32
+	}{
+		{"read", nil, retAllow},
+		{"execve", nil, retAllow},
+		{"mmap2", nil, retAllow},
+		{"ptrace", nil, retErrno | eperm},
+		{"reboot", nil, retErrno | eperm},
+		{"personality", []uint64{0x8}, retAllow},
+		{"personality", []uint64{0x0400000}, retErrno | eperm},
+		{"clone", []uint64{0x10000000}, retErrno | eperm},
+		{"unshare", []uint64{0x00020000}, retAllow},
+	} {
+		if ret := runArch(t, filter, auditArch32, syscallTable32[c.name], c.args...); ret != c.expected {
+			t.Errorf("Expected %#x for the i386 %s%v, received %#x", c.expected, c.name, c.args, ret)
+		}
+	}
+
+	// the number of the i386 syscall is not the one of x86_64, 26 is msync
+	if ret := runArch(t, filter, auditArch32, syscallTable["msync"]); ret != retErrno|eperm {
+		t.Errorf("Expected the i386 ptrace to be denied, received %#x", ret)
+	}
+	// other architectures and the x32 ABI are killed
+	if ret := runArch(t, filter, 0x40000028, syscallTable["read"]); ret != retKill {
+		t.Errorf("Expected a syscall of ARM to be killed, received %#x", ret)
+	}
+	if ret := runArch(t, filter, auditArch, x32SyscallBit|syscallTable["read"]); ret != retKill {
+		t.Errorf("Expected an x32 syscall to be killed, received %#x", ret)
+	}
+}
+
+func TestCompileOperators(t *testing.T) {
+	rule := func(op libcontainer.Operator, value uint64) *libcontainer.Syscall {
+		return &libcontainer.Syscall{
+			Name:   "write",
+			Action: libcontainer.Allow,
+			Args:   []*libcontainer.Arg{{Index: 2, Value: value, Op: op}},
+		}
+	}
+	for _, c := range []struct {
+		op       libcontainer.Operator
+		value    uint64
+		arg      uint64
+		expected bool
+	}{
+		{libcontainer.EqualTo, 10, 10, true},
+		{libcontainer.EqualTo, 10, 1<<32 | 10, false},
+		{libcontainer.NotEqualTo, 10, 10, false},
+		{libcontainer.NotEqualTo, 10, 1<<32 | 10, true},
+		{libcontainer.GreaterThan, 10, 11, true},
+		{libcontainer.GreaterThan, 10, 10, false},
+		{libcontainer.GreaterThan, 1 << 32, 5, false},
+		{libcontainer.GreaterThan, 5, 1 << 32, true},
+		{libcontainer.GreaterEqual, 10, 10, true},
+		{libcontainer.GreaterEqual, 10, 9, false},
+		{libcontainer.LessThan, 10, 9, true},
+		{libcontainer.LessThan, 10, 10, false},
+		{libcontainer.LessThan, 1 << 32, 5, true},
+		{libcontainer.LessEqual, 10, 10, true},
+		{libcontainer.LessEqual, 10, 1<<32 | 1, false},
+	} {
+		filter := compile(t, &libcontainer.Seccomp{
+			DefaultAction: libcontainer.Errno,
+			Syscalls:      []*libcontainer.Syscall{rule(c.op, c.value)},
+		})
+		ret := run(t, filter, "write", 1, 0, c.arg)
+		if (ret == retAllow) != c.expected {
+			t.Errorf("Expected %v for %d %s %d, received %#x", c.expected, c.arg, c.op, c.value, ret)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	if !IsSupported() {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	for _, config := range []*libcontainer.Seccomp{
+		{DefaultAction: "ALLOW"},
+		{DefaultAction: libcontainer.Allow, Syscalls: []*libcontainer.Syscall{{Name: "nosuchsyscall", Action: libcontainer.Errno}}},
+		{DefaultAction: libcontainer.Allow, Syscalls: []*libcontainer.Syscall{{Name: "read", Action: "deny"}}},
+		{DefaultAction: libcontainer.Allow, Syscalls: []*libcontainer.Syscall{{
+			Name:   "read",
+			Action: libcontainer.Errno,
+			Args:   []*libcontainer.Arg{{Index: 6, Op: libcontainer.EqualTo}},
+		}}},
+		{DefaultAction: libcontainer.Allow, Syscalls: []*libcontainer.Syscall{{
+			Name:   "read",
+			Action: libcontainer.Errno,
+			Args:   []*libcontainer.Arg{{Index: 0, Op: "=~"}},
+		}}},
+	} {
+		if _, err := Compile(config); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	if !IsSupported() {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	tmp, err := ioutil.TempDir("", "docker-seccomp-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	path := filepath.Join(tmp, "profile.json")
+	profile := `{
+  "default_action": "errno",
+  "syscalls": [
+    {"name": "mkdir", "action": "allow"},
+    {"name": "kill", "action": "allow", "args": [{"index": 1, "value": 15, "op": "=="}]}
+  ]
+}`
+	if err := ioutil.WriteFile(path, []byte(profile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := compile(t, config)
+	if ret := run(t, filter, "mkdir"); ret != retAllow {
+		t.Fatalf("Expected mkdir to be allowed, received %#x", ret)
+	}
+	if ret := run(t, filter, "kill", 1, 9); ret != retErrno|eperm {
+		t.Fatalf("Expected kill -9 to be denied, received %#x", ret)
+	}
+	if ret := run(t, filter, "kill", 1, 15); ret != retAllow {
+		t.Fatalf("Expected kill -15 to be allowed, received %#x", ret)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"default_action": "allow", "syscalls": [{"name": "nosuchsyscall"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadProfile(path); err == nil {
+		t.Fatal("Expected an error for an unknown syscall")
+	}
+}
@@ -0,0 +1,11 @@
+// +build !linux
+
+package seccomp
+
+import (
+	"github.com/dotcloud/docker/pkg/libcontainer"
+)
+
+func InitSeccomp(config *libcontainer.Seccomp) error {
+	return libcontainer.ErrUnsupported
+}
@@ -0,0 +1,14 @@
+// +build !linux !amd64
+
+package seccomp
+
+const (
+	auditArch   = 0
+	auditArch32 = 0
+)
+
+// seccomp filters are only compiled for x86_64
+var (
+	syscallTable   map[string]int
+	syscallTable32 map[string]int
+)
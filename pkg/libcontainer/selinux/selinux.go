@@ -0,0 +1,274 @@
+package selinux
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"github.com/dotcloud/docker/pkg/mount"
+	"github.com/dotcloud/docker/pkg/system"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	xattrName    = "security.selinux"
+	selinuxDir   = "/etc/selinux"
+	maxCategory  = 1024
+	sharedLevel  = "s0"
+	labelOptions = "disable, user, role, type or level"
+)
+
+var (
+	// labels of the processes and the files of the containers when the
+	// policy does not define them in its lxc_contexts
+	defaultProcessLabel = "system_u:system_r:svirt_lxc_net_t:s0"
+	defaultFileLabel    = "system_u:object_r:svirt_sandbox_file_t:s0"
+
+	enabledOnce sync.Once
+	enabled     bool
+
+	// the levels in use, each container gets its own pair of categories
+	levelsLock sync.Mutex
+	levels     = make(map[string]bool)
+)
+
+// IsEnabled returns true if the selinuxfs is mounted on the host
+func IsEnabled() bool {
+	enabledOnce.Do(func() {
+		mounts, err := mount.GetMounts()
+		if err != nil {
+			return
+		}
+		for _, m := range mounts {
+			if m.Fstype == "selinuxfs" {
+				enabled = true
+				return
+			}
+		}
+	})
+	return enabled
+}
+
+// InitLabels returns the label of the processes of a container and the
+// label of its files, with their own level. The options change the user,
+// role, type or level of the labels, or disable them.
+func InitLabels(options []string) (processLabel string, mountLabel string, err error) {
+	if !IsEnabled() {
+		return "", "", nil
+	}
+	processLabel, mountLabel = getLxcContexts()
+	return initLabels(processLabel, mountLabel, options)
+}
+
+func initLabels(processLabel, mountLabel string, options []string) (string, string, error) {
+	var (
+		process  = newContext(processLabel)
+		file     = newContext(mountLabel)
+		hasLevel bool
+	)
+	for _, opt := range options {
+		if opt == "disable" {
+			return "", "", nil
+		}
+		parts := strings.SplitN(opt, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", "", fmt.Errorf("Bad label option %s, valid options: %s", opt, labelOptions)
+		}
+		switch parts[0] {
+		case "user":
+			process.user, file.user = parts[1], parts[1]
+		case "role":
+			process.role = parts[1]
+		case "type":
+			process.typ = parts[1]
+		case "level":
+			process.level, file.level = parts[1], parts[1]
+			hasLevel = true
+		default:
+			return "", "", fmt.Errorf("Bad label option %s, valid options: %s", opt, labelOptions)
+		}
+	}
+	if !hasLevel {
+		level, err := reserveUniqueLevel()
+		if err != nil {
+			return "", "", err
+		}
+		process.level, file.level = level, level
+	}
+	return process.String(), file.String(), nil
+}
+
+// ReserveLabel marks the level of a label in use, for the
+// containers which were started before the daemon
+func ReserveLabel(label string) {
+	if label == "" {
+		return
+	}
+	levelsLock.Lock()
+	levels[newContext(label).level] = true
+	levelsLock.Unlock()
+}
+
+// ReleaseLabel frees the level of a label for other containers
+func ReleaseLabel(label string) {
+	if label == "" {
+		return
+	}
+	levelsLock.Lock()
+	delete(levels, newContext(label).level)
+	levelsLock.Unlock()
+}
+
+// SharedLabel returns the label without the categories of its level,
+// for the files shared by several containers
+func SharedLabel(label string) string {
+	if label == "" {
+		return ""
+	}
+	c := newContext(label)
+	c.level = sharedLevel
+	return c.String()
+}
+
+// FormatMountLabel adds the context of the mount label to the
+// data of a mount, the files of the mount get the label
+func FormatMountLabel(data, mountLabel string) string {
+	if mountLabel == "" {
+		return data
+	}
+	context := fmt.Sprintf("context=%q", mountLabel)
+	if data == "" {
+		return context
+	}
+	return data + "," + context
+}
+
+// SetFileLabel sets the label of a file, the label of a symlink is
+// set instead of the label of its target
+func SetFileLabel(path, label string) error {
+	if label == "" {
+		return nil
+	}
+	return system.Lsetxattr(path, xattrName, []byte(label), 0)
+}
+
+// GetFileLabel returns the label of a file
+func GetFileLabel(path string) (string, error) {
+	label, err := system.Lgetxattr(path, xattrName)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(label), "\x00"), nil
+}
+
+// Relabel sets the label of all the files under root
+func Relabel(root, label string) error {
+	if label == "" {
+		return nil
+	}
+	return filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return SetFileLabel(p, label)
+	})
+}
+
+// context is a label split in its fields, user:role:type:level
+type context struct {
+	user, role, typ, level string
+}
+
+func newContext(label string) *context {
+	parts := strings.SplitN(label, ":", 4)
+	for len(parts) < 4 {
+		parts = append(parts, "")
+	}
+	return &context{user: parts[0], role: parts[1], typ: parts[2], level: parts[3]}
+}
+
+func (c *context) String() string {
+	return fmt.Sprintf("%s:%s:%s:%s", c.user, c.role, c.typ, c.level)
+}
+
+// getLxcContexts reads the labels of the processes and the files of the
+// containers in the lxc_contexts of the policy of the host
+func getLxcContexts() (processLabel string, fileLabel string) {
+	processLabel, fileLabel = defaultProcessLabel, defaultFileLabel
+
+	policy := readConfig(filepath.Join(selinuxDir, "config"), "SELINUXTYPE")
+	if policy == "" {
+		return
+	}
+	path := filepath.Join(selinuxDir, policy, "contexts", "lxc_contexts")
+	if label := readConfig(path, "process"); label != "" {
+		processLabel = label
+	}
+	if label := readConfig(path, "file"); label != "" {
+		fileLabel = label
+	}
+	return
+}
+
+// readConfig returns the value of a key = value line of a file,
+// without its quotes
+func readConfig(path, key string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
+			return strings.Trim(strings.TrimSpace(parts[1]), `"`)
+		}
+	}
+	return ""
+}
+
+// reserveUniqueLevel reserves a level with two random
+// categories which is not used by another container
+func reserveUniqueLevel() (string, error) {
+	levelsLock.Lock()
+	defer levelsLock.Unlock()
+
+	for {
+		c1, err := randomCategory()
+		if err != nil {
+			return "", err
+		}
+		c2, err := randomCategory()
+		if err != nil {
+			return "", err
+		}
+		if c1 == c2 {
+			continue
+		}
+		if c1 > c2 {
+			c1, c2 = c2, c1
+		}
+		level := fmt.Sprintf("%s:c%d,c%d", sharedLevel, c1, c2)
+		if !levels[level] {
+			levels[level] = true
+			return level, nil
+		}
+	}
+}
+
+func randomCategory() (uint32, error) {
+	var n uint32
+	if err := binary.Read(rand.Reader, binary.LittleEndian, &n); err != nil {
+		return 0, err
+	}
+	return n % maxCategory, nil
+}
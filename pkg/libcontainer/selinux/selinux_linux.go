@@ -0,0 +1,28 @@
+// +build linux
+
+package selinux
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// SetExecLabel sets the label of the processes executed by the current
+// thread, the caller must lock the thread until it executes the process
+func SetExecLabel(label string) error {
+	if label == "" || !IsEnabled() {
+		return nil
+	}
+	path := fmt.Sprintf("/proc/self/task/%d/attr/exec", syscall.Gettid())
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write([]byte(label)); err != nil {
+		return fmt.Errorf("Cannot set the exec label %s: %s", label, err)
+	}
+	return nil
+}
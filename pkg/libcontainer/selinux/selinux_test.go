@@ -0,0 +1,113 @@
+package selinux
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitLabels(t *testing.T) {
+	processLabel, mountLabel, err := initLabels(defaultProcessLabel, defaultFileLabel, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ReleaseLabel(processLabel)
+
+	process, file := newContext(processLabel), newContext(mountLabel)
+	if process.typ != "svirt_lxc_net_t" || file.typ != "svirt_sandbox_file_t" {
+		t.Fatalf("Unexpected labels %s and %s", processLabel, mountLabel)
+	}
+	if !strings.HasPrefix(process.level, "s0:c") || process.level != file.level {
+		t.Fatalf("Expected a unique level shared by %s and %s", processLabel, mountLabel)
+	}
+	if _, _, err := initLabels(defaultProcessLabel, defaultFileLabel, []string{"level:" + process.level}); err != nil {
+		t.Fatal(err)
+	}
+
+	processLabel, mountLabel, err = initLabels(defaultProcessLabel, defaultFileLabel, []string{"user:user_u", "role:user_r", "type:user_t", "level:s0:c1,c2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processLabel != "user_u:user_r:user_t:s0:c1,c2" {
+		t.Fatalf("Unexpected process label %s", processLabel)
+	}
+	if mountLabel != "user_u:object_r:svirt_sandbox_file_t:s0:c1,c2" {
+		t.Fatalf("Unexpected mount label %s", mountLabel)
+	}
+
+	processLabel, mountLabel, err = initLabels(defaultProcessLabel, defaultFileLabel, []string{"disable"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processLabel != "" || mountLabel != "" {
+		t.Fatalf("Expected no labels, received %s and %s", processLabel, mountLabel)
+	}
+
+	for _, opt := range []string{"type", "type:", "kind:user_t"} {
+		if _, _, err := initLabels(defaultProcessLabel, defaultFileLabel, []string{opt}); err == nil {
+			t.Errorf("Expected an error for %s", opt)
+		}
+	}
+}
+
+func TestReserveLabel(t *testing.T) {
+	label := "system_u:system_r:svirt_lxc_net_t:s0:c5,c10"
+	ReserveLabel(label)
+	if !levels["s0:c5,c10"] {
+		t.Fatal("Expected the level to be reserved")
+	}
+	ReleaseLabel(label)
+	if levels["s0:c5,c10"] {
+		t.Fatal("Expected the level to be released")
+	}
+}
+
+func TestSharedLabel(t *testing.T) {
+	if label := SharedLabel("system_u:object_r:svirt_sandbox_file_t:s0:c5,c10"); label != "system_u:object_r:svirt_sandbox_file_t:s0" {
+		t.Fatalf("Unexpected shared label %s", label)
+	}
+	if label := SharedLabel(""); label != "" {
+		t.Fatalf("Expected no label, received %s", label)
+	}
+}
+
+func TestFormatMountLabel(t *testing.T) {
+	label := "system_u:object_r:svirt_sandbox_file_t:s0:c5,c10"
+	for _, c := range []struct {
+		data, label, expected string
+	}{
+		{"", "", ""},
+		{"mode=755", "", "mode=755"},
+		{"", label, `context="` + label + `"`},
+		{"mode=755", label, `mode=755,context="` + label + `"`},
+	} {
+		if data := FormatMountLabel(c.data, c.label); data != c.expected {
+			t.Errorf("Expected %s, received %s", c.expected, data)
+		}
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "docker-selinux-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	path := filepath.Join(tmp, "lxc_contexts")
+	contexts := `# lxc contexts
+process = "system_u:system_r:svirt_lxc_net_t:s0"
+file = "system_u:object_r:svirt_sandbox_file_t:s0"
+`
+	if err := ioutil.WriteFile(path, []byte(contexts), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if value := readConfig(path, "file"); value != "system_u:object_r:svirt_sandbox_file_t:s0" {
+		t.Fatalf("Unexpected file context %s", value)
+	}
+	if value := readConfig(path, "content"); value != "" {
+		t.Fatalf("Expected no value, received %s", value)
+	}
+}
@@ -0,0 +1,7 @@
+// +build !linux
+
+package selinux
+
+func SetExecLabel(label string) error {
+	return nil
+}
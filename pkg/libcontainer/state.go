@@ -0,0 +1,33 @@
+package libcontainer
+
+import (
+	"github.com/dotcloud/docker/pkg/system"
+	"syscall"
+)
+
+// State is the state of a running container, written once the container is
+// set up so it can be found again by a process which did not start it
+type State struct {
+	InitPid       int    `json:"init_pid"`        // pid of the init of the container, on the host
+	InitStartTime string `json:"init_start_time"` // start time of the init, in clock ticks after the boot
+
+	NamespacePaths    map[string]string `json:"namespace_paths,omitempty"`    // files of the namespaces of the init, by namespace key
+	CgroupPaths       map[string]string `json:"cgroup_paths,omitempty"`       // directories of the cgroups of the container, by subsystem
+	NetworkInterfaces map[string]string `json:"network_interfaces,omitempty"` // interfaces created by the network strategies, such as veth-host
+}
+
+// IsRunning returns true while the init of the container is running,
+// false once it exited even if another process reused its pid
+func (s *State) IsRunning() bool {
+	if s.InitPid <= 0 {
+		return false
+	}
+	if err := syscall.Kill(s.InitPid, 0); err != nil && err != syscall.EPERM {
+		return false
+	}
+	startTime, err := system.GetProcessStartTime(s.InitPid)
+	if err != nil {
+		return false
+	}
+	return startTime == s.InitStartTime
+}
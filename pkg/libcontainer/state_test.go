@@ -0,0 +1,27 @@
+package libcontainer
+
+import (
+	"github.com/dotcloud/docker/pkg/system"
+	"os"
+	"testing"
+)
+
+func TestStateIsRunning(t *testing.T) {
+	startTime, err := system.GetProcessStartTime(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &State{InitPid: os.Getpid(), InitStartTime: startTime}
+	if !state.IsRunning() {
+		t.Fatal("Expected the state of the current process to be running")
+	}
+
+	// another process reused the pid
+	state.InitStartTime = "0"
+	if state.IsRunning() {
+		t.Fatal("Expected a state with another start time not to be running")
+	}
+	if (&State{}).IsRunning() {
+		t.Fatal("Expected a state without pid not to be running")
+	}
+}
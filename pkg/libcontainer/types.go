@@ -18,22 +18,45 @@ var (
 var (
 	namespaceList = Namespaces{}
 
+	// capabilityList holds all the capabilities known to the kernel
 	capabilityList = Capabilities{
+		{Key: "CHOWN", Value: capability.CAP_CHOWN},
+		{Key: "DAC_OVERRIDE", Value: capability.CAP_DAC_OVERRIDE},
+		{Key: "DAC_READ_SEARCH", Value: capability.CAP_DAC_READ_SEARCH},
+		{Key: "FOWNER", Value: capability.CAP_FOWNER},
+		{Key: "FSETID", Value: capability.CAP_FSETID},
+		{Key: "KILL", Value: capability.CAP_KILL},
+		{Key: "SETGID", Value: capability.CAP_SETGID},
+		{Key: "SETUID", Value: capability.CAP_SETUID},
 		{Key: "SETPCAP", Value: capability.CAP_SETPCAP},
+		{Key: "LINUX_IMMUTABLE", Value: capability.CAP_LINUX_IMMUTABLE},
+		{Key: "NET_BIND_SERVICE", Value: capability.CAP_NET_BIND_SERVICE},
+		{Key: "NET_BROADCAST", Value: capability.CAP_NET_BROADCAST},
+		{Key: "NET_ADMIN", Value: capability.CAP_NET_ADMIN},
+		{Key: "NET_RAW", Value: capability.CAP_NET_RAW},
+		{Key: "IPC_LOCK", Value: capability.CAP_IPC_LOCK},
+		{Key: "IPC_OWNER", Value: capability.CAP_IPC_OWNER},
 		{Key: "SYS_MODULE", Value: capability.CAP_SYS_MODULE},
 		{Key: "SYS_RAWIO", Value: capability.CAP_SYS_RAWIO},
+		{Key: "SYS_CHROOT", Value: capability.CAP_SYS_CHROOT},
+		{Key: "SYS_PTRACE", Value: capability.CAP_SYS_PTRACE},
 		{Key: "SYS_PACCT", Value: capability.CAP_SYS_PACCT},
 		{Key: "SYS_ADMIN", Value: capability.CAP_SYS_ADMIN},
+		{Key: "SYS_BOOT", Value: capability.CAP_SYS_BOOT},
 		{Key: "SYS_NICE", Value: capability.CAP_SYS_NICE},
 		{Key: "SYS_RESOURCE", Value: capability.CAP_SYS_RESOURCE},
 		{Key: "SYS_TIME", Value: capability.CAP_SYS_TIME},
 		{Key: "SYS_TTY_CONFIG", Value: capability.CAP_SYS_TTY_CONFIG},
 		{Key: "MKNOD", Value: capability.CAP_MKNOD},
+		{Key: "LEASE", Value: capability.CAP_LEASE},
 		{Key: "AUDIT_WRITE", Value: capability.CAP_AUDIT_WRITE},
 		{Key: "AUDIT_CONTROL", Value: capability.CAP_AUDIT_CONTROL},
+		{Key: "SETFCAP", Value: capability.CAP_SETFCAP},
 		{Key: "MAC_OVERRIDE", Value: capability.CAP_MAC_OVERRIDE},
 		{Key: "MAC_ADMIN", Value: capability.CAP_MAC_ADMIN},
-		{Key: "NET_ADMIN", Value: capability.CAP_NET_ADMIN},
+		{Key: "SYSLOG", Value: capability.CAP_SYSLOG},
+		{Key: "WAKE_ALARM", Value: capability.CAP_WAKE_ALARM},
+		{Key: "BLOCK_SUSPEND", Value: capability.CAP_BLOCK_SUSPEND},
 	}
 )
 
@@ -87,6 +110,17 @@ func (n Namespaces) Contains(ns string) bool {
 	return false
 }
 
+// Remove returns the namespaces without the specified Namespace
+func (n Namespaces) Remove(ns string) Namespaces {
+	out := Namespaces{}
+	for _, nsp := range n {
+		if nsp.Key != ns {
+			out = append(out, nsp)
+		}
+	}
+	return out
+}
+
 type (
 	Capability struct {
 		Key   string
@@ -125,6 +159,15 @@ func GetCapability(key string) *Capability {
 	return nil
 }
 
+// GetAllCapabilities returns the names of all the capabilities
+func GetAllCapabilities() []string {
+	names := make([]string, len(capabilityList))
+	for i, capp := range capabilityList {
+		names[i] = capp.Key
+	}
+	return names
+}
+
 // Contains returns true if the specified Capability is
 // in the slice
 func (c Capabilities) Contains(capp string) bool {
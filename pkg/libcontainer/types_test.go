@@ -20,6 +20,21 @@ func TestNamespacesContains(t *testing.T) {
 	}
 }
 
+func TestNamespacesRemove(t *testing.T) {
+	ns := Namespaces{
+		GetNamespace("NEWPID"),
+		GetNamespace("NEWNET"),
+	}
+
+	ns = ns.Remove("NEWNET")
+	if ns.Contains("NEWNET") {
+		t.Fatal("namespaces should not contain NEWNET once removed")
+	}
+	if !ns.Contains("NEWPID") {
+		t.Fatal("namespaces should still contain NEWPID")
+	}
+}
+
 func TestCapabilitiesContains(t *testing.T) {
 	caps := Capabilities{
 		GetCapability("MKNOD"),
@@ -11,6 +11,14 @@ import (
 type SysInfo struct {
 	MemoryLimit            bool
 	SwapLimit              bool
+	MemoryReservation      bool
+	KernelMemory           bool
+	CpuCfsPeriod           bool
+	CpuCfsQuota            bool
+	Cpuset                 bool
+	BlkioWeight            bool
+	BlkioThrottle          bool
+	PidsLimit              bool
 	IPv4ForwardingDisabled bool
 	AppArmor               bool
 }
@@ -34,6 +42,25 @@ func New(quiet bool) *SysInfo {
 		if !sysInfo.SwapLimit && !quiet {
 			log.Printf("WARNING: Your kernel does not support cgroup swap limit.")
 		}
+
+		sysInfo.MemoryReservation = err2 == nil
+		sysInfo.KernelMemory = cgroupFileExists(cgroupMemoryMountpoint, "memory.kmem.limit_in_bytes")
+	}
+
+	if cgroupCpuMountpoint, err := cgroups.FindCgroupMountpoint("cpu"); err == nil {
+		sysInfo.CpuCfsPeriod = cgroupFileExists(cgroupCpuMountpoint, "cpu.cfs_period_us")
+		sysInfo.CpuCfsQuota = cgroupFileExists(cgroupCpuMountpoint, "cpu.cfs_quota_us")
+	}
+	if cgroupCpusetMountpoint, err := cgroups.FindCgroupMountpoint("cpuset"); err == nil {
+		sysInfo.Cpuset = cgroupFileExists(cgroupCpusetMountpoint, "cpuset.cpus")
+	}
+	if cgroupBlkioMountpoint, err := cgroups.FindCgroupMountpoint("blkio"); err == nil {
+		sysInfo.BlkioWeight = cgroupFileExists(cgroupBlkioMountpoint, "blkio.weight")
+		sysInfo.BlkioThrottle = cgroupFileExists(cgroupBlkioMountpoint, "blkio.throttle.read_bps_device")
+	}
+	// the limits are set in the cgroups of the containers, the root cgroup has no pids.max
+	if _, err := cgroups.FindCgroupMountpoint("pids"); err == nil {
+		sysInfo.PidsLimit = true
 	}
 
 	// Check if AppArmor seems to be enabled on this system.
@@ -44,3 +71,8 @@ func New(quiet bool) *SysInfo {
 	}
 	return sysInfo
 }
+
+func cgroupFileExists(mountpoint, file string) bool {
+	_, err := os.Stat(path.Join(mountpoint, file))
+	return err == nil
+}
@@ -5,6 +5,8 @@ import (
 	"syscall"
 )
 
+const prSetNoNewPrivs = 38 // PR_SET_NO_NEW_PRIVS
+
 func Chroot(dir string) error {
 	return syscall.Chroot(dir)
 }
@@ -115,6 +117,15 @@ func Mknod(path string, mode uint32, dev int) error {
 	return syscall.Mknod(path, mode, dev)
 }
 
+// SetNoNewPrivileges keeps the current process and its children from gaining
+// privileges through execve, the setuid bits and the file capabilities are ignored
+func SetNoNewPrivileges() error {
+	if _, _, err := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); err != 0 {
+		return err
+	}
+	return nil
+}
+
 func ParentDeathSignal(sig uintptr) error {
 	if _, _, err := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_SET_PDEATHSIG, sig, 0); err != 0 {
 		return err
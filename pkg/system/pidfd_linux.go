@@ -0,0 +1,53 @@
+package system
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sysPidfdSendSignal = 424 // SYS_PIDFD_SEND_SIGNAL, the same on all architectures
+	sysPidfdOpen       = 434 // SYS_PIDFD_OPEN, the same on all architectures
+	pollIn       = 0x1 // POLLIN
+)
+
+type pollFd struct {
+	fd      int32
+	events  int16
+	revents int16
+}
+
+// PidfdOpen returns a file descriptor referring to the process pid, whether
+// or not it is a child of the caller. ENOSYS is returned before Linux 5.3.
+func PidfdOpen(pid int) (uintptr, error) {
+	fd, _, err := syscall.Syscall(sysPidfdOpen, uintptr(pid), 0, 0)
+	if err != 0 {
+		return 0, err
+	}
+	return fd, nil
+}
+
+// PidfdSendSignal sends sig to the process referred to by the pidfd, which
+// can't be another process reusing its pid
+func PidfdSendSignal(fd uintptr, sig syscall.Signal) error {
+	if _, _, err := syscall.Syscall6(sysPidfdSendSignal, fd, uintptr(sig), 0, 0, 0, 0); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// WaitPidfd blocks until the process referred to by the pidfd exits
+func WaitPidfd(fd uintptr) error {
+	fds := []pollFd{{fd: int32(fd), events: pollIn}}
+	for {
+		// a negative timeout waits forever
+		_, _, err := syscall.Syscall(syscall.SYS_POLL, uintptr(unsafe.Pointer(&fds[0])), 1, ^uintptr(0))
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != 0 {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,35 @@
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GetProcessStartTime returns the start time of the process pid in clock
+// ticks after the boot, as in /proc/<pid>/stat. A process reusing the pid
+// of another one has a different start time.
+func GetProcessStartTime(pid int) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return "", err
+	}
+	return parseStartTime(string(data))
+}
+
+func parseStartTime(stat string) (string, error) {
+	// the name of the command is between parentheses and may contain
+	// spaces, the fields after it start with the state, the 3rd field
+	i := strings.LastIndex(stat, ")")
+	if i < 0 {
+		return "", fmt.Errorf("invalid process stat %q", stat)
+	}
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 20 {
+		return "", fmt.Errorf("invalid process stat %q", stat)
+	}
+	// the start time is the 22nd field
+	return fields[19], nil
+}
@@ -0,0 +1,30 @@
+package system
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseStartTime(t *testing.T) {
+	stat := "4242 (docker run) S 1 4242 4242 0 -1 4202752 2052 0 0 0 7 2 0 0 20 0 1 0 9876543 15278080 1041 18446744073709551615 1 1 0 0 0 0 0 4096 0 0 0 0 17 1 0 0 0 0 0\n"
+	start, err := parseStartTime(stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != "9876543" {
+		t.Fatalf("Expected the start time 9876543, got %s", start)
+	}
+	if _, err := parseStartTime("4242 (docker) S 1"); err == nil {
+		t.Fatal("Expected an error for a truncated stat")
+	}
+}
+
+func TestGetProcessStartTime(t *testing.T) {
+	start, err := GetProcessStartTime(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := GetProcessStartTime(os.Getpid()); start == "" || again != start {
+		t.Fatalf("Expected a stable start time, got %q and %q", start, again)
+	}
+}
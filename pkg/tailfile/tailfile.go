@@ -0,0 +1,62 @@
+package tailfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+const blockSize = 1024
+
+var (
+	eol = []byte("\n")
+
+	ErrNonPositiveLinesNumber = errors.New("Lines number must be positive")
+)
+
+// TailFile returns the last n lines of f. The file is read backward by blocks,
+// so only its end is read no matter how large it is.
+func TailFile(f io.ReadSeeker, n int) ([][]byte, error) {
+	if n <= 0 {
+		return nil, ErrNonPositiveLinesNumber
+	}
+	size, err := f.Seek(0, os.SEEK_END)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		data   []byte
+		offset = size
+		count  int
+	)
+	// more than n line breaks are needed for the n-th last line to be complete,
+	// as the last line ends with a line break too
+	for offset > 0 && count <= n {
+		readSize := int64(blockSize)
+		if offset < readSize {
+			readSize = offset
+		}
+		offset -= readSize
+		if _, err := f.Seek(offset, os.SEEK_SET); err != nil {
+			return nil, err
+		}
+		block := make([]byte, readSize)
+		if _, err := io.ReadFull(f, block); err != nil {
+			return nil, err
+		}
+		count += bytes.Count(block, eol)
+		data = append(block, data...)
+	}
+
+	data = bytes.TrimSuffix(data, eol)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	lines := bytes.Split(data, eol)
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
@@ -0,0 +1,66 @@
+package tailfile
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTailFile(t *testing.T) {
+	f := strings.NewReader("first line\nsecond line\nthird line\nfourth line\n")
+	lines, err := TailFile(f, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"third line", "fourth line"}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, received %d", len(expected), len(lines))
+	}
+	for i, line := range lines {
+		if string(line) != expected[i] {
+			t.Fatalf("Expected line %q, received %q", expected[i], line)
+		}
+	}
+}
+
+func TestTailFileMoreLinesThanFile(t *testing.T) {
+	f := strings.NewReader("first line\nsecond line\n")
+	lines, err := TailFile(f, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || string(lines[0]) != "first line" {
+		t.Fatalf("Expected the 2 lines of the file, received %q", lines)
+	}
+}
+
+func TestTailFileAcrossBlocks(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(buf, "line %d\n", i)
+	}
+	lines, err := TailFile(bytes.NewReader(buf.Bytes()), 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 300 {
+		t.Fatalf("Expected 300 lines, received %d", len(lines))
+	}
+	if string(lines[0]) != "line 700" || string(lines[299]) != "line 999" {
+		t.Fatalf("Unexpected lines %q ... %q", lines[0], lines[299])
+	}
+}
+
+func TestTailFileEmpty(t *testing.T) {
+	lines, err := TailFile(strings.NewReader(""), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 0 {
+		t.Fatalf("Expected no lines, received %q", lines)
+	}
+	if _, err := TailFile(strings.NewReader(""), 0); err != ErrNonPositiveLinesNumber {
+		t.Fatalf("Expected ErrNonPositiveLinesNumber, received %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package ulimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Ulimit is a resource limit of the processes of a container,
+// a limit of -1 is unlimited
+type Ulimit struct {
+	Name string
+	Hard int64
+	Soft int64
+}
+
+// Rlimit is a ulimit as set by setrlimit, a limit of RLIM_INFINITY is unlimited
+type Rlimit struct {
+	Type int // resource, RLIMIT_NOFILE for example
+	Hard uint64
+	Soft uint64
+}
+
+const (
+	// resources of setrlimit missing from the syscall package,
+	// from the linux headers
+	rlimitRss        = 5
+	rlimitNproc      = 6
+	rlimitMemlock    = 8
+	rlimitLocks      = 10
+	rlimitSigpending = 11
+	rlimitMsgqueue   = 12
+	rlimitNice       = 13
+	rlimitRtprio     = 14
+	rlimitRttime     = 15
+
+	// rlimInfinity is the value of a limit without limit
+	rlimInfinity = ^uint64(0)
+)
+
+var ulimitNameMapping = map[string]int{
+	"as":         syscall.RLIMIT_AS,
+	"core":       syscall.RLIMIT_CORE,
+	"cpu":        syscall.RLIMIT_CPU,
+	"data":       syscall.RLIMIT_DATA,
+	"fsize":      syscall.RLIMIT_FSIZE,
+	"locks":      rlimitLocks,
+	"memlock":    rlimitMemlock,
+	"msgqueue":   rlimitMsgqueue,
+	"nice":       rlimitNice,
+	"nofile":     syscall.RLIMIT_NOFILE,
+	"nproc":      rlimitNproc,
+	"rss":        rlimitRss,
+	"rtprio":     rlimitRtprio,
+	"rttime":     rlimitRttime,
+	"sigpending": rlimitSigpending,
+	"stack":      syscall.RLIMIT_STACK,
+}
+
+// Parse parses a ulimit of the form name=soft[:hard], the hard limit
+// is the soft limit when it is omitted. A limit is a number or unlimited.
+func Parse(val string) (*Ulimit, error) {
+	parts := strings.SplitN(val, "=", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Invalid ulimit %s, the format is name=soft[:hard]", val)
+	}
+	if _, exists := ulimitNameMapping[parts[0]]; !exists {
+		return nil, fmt.Errorf("Invalid ulimit type: %s", parts[0])
+	}
+
+	limits := strings.SplitN(parts[1], ":", 2)
+	soft, err := parseLimit(limits[0])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid soft limit of the ulimit %s: %s", val, limits[0])
+	}
+	hard := soft
+	if len(limits) == 2 {
+		if hard, err = parseLimit(limits[1]); err != nil {
+			return nil, fmt.Errorf("Invalid hard limit of the ulimit %s: %s", val, limits[1])
+		}
+	}
+	u := &Ulimit{Name: parts[0], Soft: soft, Hard: hard}
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func parseLimit(val string) (int64, error) {
+	if val == "unlimited" {
+		return -1, nil
+	}
+	limit, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("Invalid limit: %s", val)
+	}
+	return limit, nil
+}
+
+// Validate checks the name of the ulimit and that
+// its soft limit is not greater than its hard limit
+func (u *Ulimit) Validate() error {
+	if _, exists := ulimitNameMapping[u.Name]; !exists {
+		return fmt.Errorf("Invalid ulimit type: %s", u.Name)
+	}
+	if u.Soft < -1 || u.Hard < -1 {
+		return fmt.Errorf("Invalid ulimit %s: the limits cannot be negative", u.Name)
+	}
+	if u.Hard != -1 && (u.Soft == -1 || u.Soft > u.Hard) {
+		return fmt.Errorf("Invalid ulimit %s: the soft limit is greater than the hard limit", u.Name)
+	}
+	return nil
+}
+
+// GetRlimit returns the rlimit of the ulimit
+func (u *Ulimit) GetRlimit() (*Rlimit, error) {
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return &Rlimit{Type: ulimitNameMapping[u.Name], Soft: rlimitValue(u.Soft), Hard: rlimitValue(u.Hard)}, nil
+}
+
+func rlimitValue(limit int64) uint64 {
+	if limit == -1 {
+		return rlimInfinity
+	}
+	return uint64(limit)
+}
+
+func (u *Ulimit) String() string {
+	return fmt.Sprintf("%s=%s:%s", u.Name, limitString(u.Soft), limitString(u.Hard))
+}
+
+func limitString(limit int64) string {
+	if limit == -1 {
+		return "unlimited"
+	}
+	return strconv.FormatInt(limit, 10)
+}
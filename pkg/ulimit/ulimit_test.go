@@ -0,0 +1,72 @@
+package ulimit
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	u, err := Parse("nofile=512:1024")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "nofile" || u.Soft != 512 || u.Hard != 1024 {
+		t.Fatalf("Expected nofile=512:1024, got %#v", u)
+	}
+
+	if u, err = Parse("memlock=unlimited"); err != nil {
+		t.Fatal(err)
+	}
+	if u.Soft != -1 || u.Hard != -1 {
+		t.Fatalf("Expected an unlimited memlock, got %#v", u)
+	}
+
+	for _, invalid := range []string{
+		"nofile",
+		"nofile=",
+		"notaulimit=1024",
+		"nofile=abc",
+		"nofile=1024:abc",
+		"nofile=-1",
+		"nofile=2048:1024",
+		"nofile=unlimited:1024",
+	} {
+		if _, err := Parse(invalid); err == nil {
+			t.Fatalf("Expected an error parsing %s", invalid)
+		}
+	}
+}
+
+func TestGetRlimit(t *testing.T) {
+	u := &Ulimit{Name: "nofile", Soft: 512, Hard: 1024}
+	r, err := u.GetRlimit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Type != syscall.RLIMIT_NOFILE || r.Soft != 512 || r.Hard != 1024 {
+		t.Fatalf("Expected the nofile rlimit 512:1024, got %#v", r)
+	}
+
+	u = &Ulimit{Name: "memlock", Soft: 4096, Hard: -1}
+	if r, err = u.GetRlimit(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Type != rlimitMemlock || r.Soft != 4096 || r.Hard != rlimInfinity {
+		t.Fatalf("Expected the memlock rlimit 4096:infinity, got %#v", r)
+	}
+
+	u = &Ulimit{Name: "notaulimit", Soft: 1, Hard: 1}
+	if _, err := u.GetRlimit(); err == nil {
+		t.Fatal("Expected an error for an invalid ulimit")
+	}
+}
+
+func TestString(t *testing.T) {
+	u := &Ulimit{Name: "nofile", Soft: 512, Hard: -1}
+	if s := u.String(); s != "nofile=512:unlimited" {
+		t.Fatalf("Expected nofile=512:unlimited, got %s", s)
+	}
+	if v, err := Parse(u.String()); err != nil || *v != *u {
+		t.Fatalf("Expected %s to parse back to %#v, got %#v (%v)", u, u, v, err)
+	}
+}
@@ -26,6 +26,13 @@ type Group struct {
 	List []string
 }
 
+// SubId is a range of subordinate ids of a user
+type SubId struct {
+	Name  string
+	SubId int
+	Count int
+}
+
 func parseLine(line string, v ...interface{}) {
 	if line == "" {
 		return
@@ -156,6 +163,57 @@ func parseGroupFile(r io.Reader, filter func(*Group) bool) ([]*Group, error) {
 	return out, nil
 }
 
+func ParseSubUidFilter(filter func(*SubId) bool) ([]*SubId, error) {
+	return parseSubIdFilter("/etc/subuid", filter)
+}
+
+func ParseSubGidFilter(filter func(*SubId) bool) ([]*SubId, error) {
+	return parseSubIdFilter("/etc/subgid", filter)
+}
+
+func parseSubIdFilter(path string, filter func(*SubId) bool) ([]*SubId, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseSubIdFile(f, filter)
+}
+
+func parseSubIdFile(r io.Reader, filter func(*SubId) bool) ([]*SubId, error) {
+	var (
+		s   = bufio.NewScanner(r)
+		out = []*SubId{}
+	)
+
+	for s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// see: man 5 subuid
+		//  login_name:first_id:count
+		// Name:SubId:Count
+		//  dockremap:100000:65536
+		p := &SubId{}
+		parseLine(
+			text,
+			&p.Name, &p.SubId, &p.Count,
+		)
+
+		if filter == nil || filter(p) {
+			out = append(out, p)
+		}
+	}
+
+	return out, nil
+}
+
 // Given a string like "user", "1000", "user:group", "1000:1000", returns the uid, gid, and list of supplementary group IDs, if possible.
 func GetUserGroupSupplementary(userSpec string, defaultUid int, defaultGid int) (int, int, []int, error) {
 	var (
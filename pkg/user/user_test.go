@@ -92,3 +92,22 @@ this is just some garbage data
 		t.Fatalf("Expected groups[1] to be 4 - adm - 3 members, got %v - %v - %v", groups[1].Gid, groups[1].Name, len(groups[1].List))
 	}
 }
+
+func TestUserParseSubId(t *testing.T) {
+	ids, err := parseSubIdFile(strings.NewReader(`
+# comment
+dockremap:100000:65536
+1000:165536:65536
+`), func(s *SubId) bool {
+		return s.Name == "dockremap"
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ids) != 1 {
+		t.Fatalf("Expected 1 range, got %v", len(ids))
+	}
+	if ids[0].SubId != 100000 || ids[0].Count != 65536 {
+		t.Fatalf("Expected ids[0] to be 100000 - 65536, got %v - %v", ids[0].SubId, ids[0].Count)
+	}
+}
@@ -13,6 +13,14 @@ func Compare(a, b *Config) bool {
 		a.Memory != b.Memory ||
 		a.MemorySwap != b.MemorySwap ||
 		a.CpuShares != b.CpuShares ||
+		a.MemoryReservation != b.MemoryReservation ||
+		a.KernelMemory != b.KernelMemory ||
+		a.CpuPeriod != b.CpuPeriod ||
+		a.CpuQuota != b.CpuQuota ||
+		a.CpusetCpus != b.CpusetCpus ||
+		a.CpusetMems != b.CpusetMems ||
+		a.BlkioWeight != b.BlkioWeight ||
+		a.PidsLimit != b.PidsLimit ||
 		a.OpenStdin != b.OpenStdin ||
 		a.Tty != b.Tty ||
 		a.VolumesFrom != b.VolumesFrom {
@@ -63,5 +71,23 @@ func Compare(a, b *Config) bool {
 			return false
 		}
 	}
+	if !compareThrottleDevices(a.BlkioDeviceReadBps, b.BlkioDeviceReadBps) ||
+		!compareThrottleDevices(a.BlkioDeviceWriteBps, b.BlkioDeviceWriteBps) ||
+		!compareThrottleDevices(a.BlkioDeviceReadIOps, b.BlkioDeviceReadIOps) ||
+		!compareThrottleDevices(a.BlkioDeviceWriteIOps, b.BlkioDeviceWriteIOps) {
+		return false
+	}
+	return true
+}
+
+func compareThrottleDevices(a, b []ThrottleDevice) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
 	return true
 }
@@ -3,6 +3,7 @@ package runconfig
 import (
 	"github.com/dotcloud/docker/engine"
 	"github.com/dotcloud/docker/nat"
+	"time"
 )
 
 // Note: the Config structure should hold only portable information about the container.
@@ -33,6 +34,40 @@ type Config struct {
 	Entrypoint      []string
 	NetworkDisabled bool
 	OnBuild         []string
+	Healthcheck     *HealthConfig
+
+	MemoryReservation int64  // Memory soft limit (in bytes)
+	KernelMemory      int64  // Kernel memory limit (in bytes)
+	CpuPeriod         int64  // CFS period (in microseconds)
+	CpuQuota          int64  // CPU time allowed in each CFS period (in microseconds)
+	CpusetCpus        string // CPUs the processes can run on (e.g. 0-2,6)
+	CpusetMems        string // Memory nodes the processes can allocate from (e.g. 0,1)
+	BlkioWeight       int64  // Block IO weight (relative weight vs. other containers, 10 to 1000)
+	PidsLimit         int64  // Maximum number of processes
+
+	BlkioDeviceReadBps   []ThrottleDevice // Bytes read per second from the devices
+	BlkioDeviceWriteBps  []ThrottleDevice // Bytes written per second to the devices
+	BlkioDeviceReadIOps  []ThrottleDevice // Reads per second from the devices
+	BlkioDeviceWriteIOps []ThrottleDevice // Writes per second to the devices
+}
+
+// ThrottleDevice limits the rate of the IO of a block device of the host
+type ThrottleDevice struct {
+	Path string
+	Rate uint64
+}
+
+// HealthConfig holds the configuration of the command checking the health of a container
+type HealthConfig struct {
+	// Test is the check to perform, one of:
+	// {} : inherit the health check of the image
+	// {"NONE"} : disable the health check
+	// {"CMD", args...} : exec the arguments directly
+	// {"CMD-SHELL", command} : run the command with the container's default shell
+	Test     []string
+	Interval time.Duration // Time to wait between two checks (0 means the default)
+	Timeout  time.Duration // Time to wait before considering the check to have hung (0 means the default)
+	Retries  int           // Number of consecutive failures needed to consider the container unhealthy (0 means the default)
 }
 
 func ContainerConfigFromJob(job *engine.Job) *Config {
@@ -53,9 +88,23 @@ func ContainerConfigFromJob(job *engine.Job) *Config {
 		VolumesFrom:     job.Getenv("VolumesFrom"),
 		WorkingDir:      job.Getenv("WorkingDir"),
 		NetworkDisabled: job.GetenvBool("NetworkDisabled"),
+
+		MemoryReservation: job.GetenvInt64("MemoryReservation"),
+		KernelMemory:      job.GetenvInt64("KernelMemory"),
+		CpuPeriod:         job.GetenvInt64("CpuPeriod"),
+		CpuQuota:          job.GetenvInt64("CpuQuota"),
+		CpusetCpus:        job.Getenv("CpusetCpus"),
+		CpusetMems:        job.Getenv("CpusetMems"),
+		BlkioWeight:       job.GetenvInt64("BlkioWeight"),
+		PidsLimit:         job.GetenvInt64("PidsLimit"),
 	}
 	job.GetenvJson("ExposedPorts", &config.ExposedPorts)
 	job.GetenvJson("Volumes", &config.Volumes)
+	job.GetenvJson("Healthcheck", &config.Healthcheck)
+	job.GetenvJson("BlkioDeviceReadBps", &config.BlkioDeviceReadBps)
+	job.GetenvJson("BlkioDeviceWriteBps", &config.BlkioDeviceWriteBps)
+	job.GetenvJson("BlkioDeviceReadIOps", &config.BlkioDeviceReadIOps)
+	job.GetenvJson("BlkioDeviceWriteIOps", &config.BlkioDeviceWriteIOps)
 	if PortSpecs := job.GetenvList("PortSpecs"); PortSpecs != nil {
 		config.PortSpecs = PortSpecs
 	}
@@ -0,0 +1,86 @@
+package runconfig
+
+import (
+	"github.com/dotcloud/docker/engine"
+	"github.com/dotcloud/docker/opts"
+	flag "github.com/dotcloud/docker/pkg/mflag"
+	"path"
+)
+
+// ExecConfig holds the configuration of a process executed
+// inside an already running container
+type ExecConfig struct {
+	User         string
+	Privileged   bool
+	Tty          bool
+	AttachStdin  bool
+	AttachStderr bool
+	AttachStdout bool
+	Detach       bool
+	Env          []string
+	WorkingDir   string
+	Cmd          []string
+}
+
+func ExecConfigFromJob(job *engine.Job) *ExecConfig {
+	execConfig := &ExecConfig{
+		User:         job.Getenv("User"),
+		Privileged:   job.GetenvBool("Privileged"),
+		Tty:          job.GetenvBool("Tty"),
+		AttachStdin:  job.GetenvBool("AttachStdin"),
+		AttachStderr: job.GetenvBool("AttachStderr"),
+		AttachStdout: job.GetenvBool("AttachStdout"),
+		Detach:       job.GetenvBool("Detach"),
+		WorkingDir:   job.Getenv("WorkingDir"),
+	}
+	if Env := job.GetenvList("Env"); Env != nil {
+		execConfig.Env = Env
+	}
+	if Cmd := job.GetenvList("Cmd"); Cmd != nil {
+		execConfig.Cmd = Cmd
+	}
+	return execConfig
+}
+
+// ParseExec parses the arguments of the exec command and returns the name
+// of the container along with the configuration of the process to execute.
+// An empty name is returned when the container or the command is missing.
+func ParseExec(cmd *flag.FlagSet, args []string) (string, *ExecConfig, error) {
+	var (
+		flEnv = opts.NewListOpts(opts.ValidateEnv)
+
+		flStdin      = cmd.Bool([]string{"i", "-interactive"}, false, "Keep stdin open even if not attached")
+		flTty        = cmd.Bool([]string{"t", "-tty"}, false, "Allocate a pseudo-tty")
+		flDetach     = cmd.Bool([]string{"d", "-detach"}, false, "Detached mode: run the command in the background")
+		flPrivileged = cmd.Bool([]string{"#privileged", "-privileged"}, false, "Give extended privileges to the command")
+		flUser       = cmd.String([]string{"u", "-user"}, "", "Username or UID")
+		flWorkingDir = cmd.String([]string{"w", "-workdir"}, "", "Working directory inside the container")
+	)
+	cmd.Var(&flEnv, []string{"e", "-env"}, "Set environment variables")
+
+	if err := cmd.Parse(args); err != nil {
+		return "", nil, err
+	}
+	if cmd.NArg() < 2 {
+		return "", nil, nil
+	}
+	if *flWorkingDir != "" && !path.IsAbs(*flWorkingDir) {
+		return "", nil, ErrInvalidWorikingDirectory
+	}
+
+	execConfig := &ExecConfig{
+		User:       *flUser,
+		Privileged: *flPrivileged,
+		Tty:        *flTty,
+		Detach:     *flDetach,
+		Env:        flEnv.GetAll(),
+		WorkingDir: *flWorkingDir,
+		Cmd:        cmd.Args()[1:],
+	}
+	if !*flDetach {
+		execConfig.AttachStdout = true
+		execConfig.AttachStderr = true
+		execConfig.AttachStdin = *flStdin
+	}
+	return cmd.Arg(0), execConfig, nil
+}
@@ -0,0 +1,56 @@
+package runconfig
+
+import (
+	flag "github.com/dotcloud/docker/pkg/mflag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func parseExec(args string) (string, *ExecConfig, error) {
+	cmd := flag.NewFlagSet("exec", flag.ContinueOnError)
+	cmd.SetOutput(ioutil.Discard)
+	cmd.Usage = nil
+	return ParseExec(cmd, strings.Split(args, " "))
+}
+
+func TestParseExec(t *testing.T) {
+	name, config, err := parseExec("-i -t -u daemon -e FOO=bar -w /tmp mycontainer ls -l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "mycontainer" {
+		t.Fatalf("Expected container mycontainer, received %s", name)
+	}
+	if !config.AttachStdin || !config.AttachStdout || !config.AttachStderr || !config.Tty {
+		t.Fatalf("Expected stdin, stdout, stderr and tty to be enabled, received %+v", config)
+	}
+	if config.User != "daemon" || config.WorkingDir != "/tmp" {
+		t.Fatalf("Unexpected user or working dir: %+v", config)
+	}
+	if len(config.Env) != 1 || config.Env[0] != "FOO=bar" {
+		t.Fatalf("Expected env [FOO=bar], received %v", config.Env)
+	}
+	if len(config.Cmd) != 2 || config.Cmd[0] != "ls" || config.Cmd[1] != "-l" {
+		t.Fatalf("Expected cmd [ls -l], received %v", config.Cmd)
+	}
+}
+
+func TestParseExecDetach(t *testing.T) {
+	_, config, err := parseExec("-d -i mycontainer top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.AttachStdin || config.AttachStdout || config.AttachStderr {
+		t.Fatalf("Expected no stream to be attached in detached mode, received %+v", config)
+	}
+}
+
+func TestParseExecInvalid(t *testing.T) {
+	if name, _, err := parseExec("mycontainer"); err != nil || name != "" {
+		t.Fatalf("Expected an empty name when no command is given, received %q (%v)", name, err)
+	}
+	if _, _, err := parseExec("-w tmp mycontainer ls"); err != ErrInvalidWorikingDirectory {
+		t.Fatalf("Expected ErrInvalidWorikingDirectory, received %v", err)
+	}
+}
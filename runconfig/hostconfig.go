@@ -3,8 +3,46 @@ package runconfig
 import (
 	"github.com/dotcloud/docker/engine"
 	"github.com/dotcloud/docker/nat"
+	"github.com/dotcloud/docker/pkg/ulimit"
+	"strings"
 )
 
+// NetworkMode is the networking of a container: bridge or the name of a
+// network created with 'docker network create' for an interface on the
+// bridge of the network, host for the network stack of the host, none for
+// a loopback only, or container:<name> to join the network namespace of
+// another container
+type NetworkMode string
+
+// IsBridge returns true when the container gets an interface on the bridge
+// of a network, the default bridge network when the mode is empty
+func (n NetworkMode) IsBridge() bool {
+	return !n.IsHost() && !n.IsNone() && !n.IsContainer()
+}
+
+func (n NetworkMode) IsHost() bool {
+	return n == "host"
+}
+
+func (n NetworkMode) IsNone() bool {
+	return n == "none"
+}
+
+// IsContainer returns true when the container joins the network namespace
+// of another container
+func (n NetworkMode) IsContainer() bool {
+	return strings.HasPrefix(string(n), "container:")
+}
+
+// Container returns the name or ID of the container whose network
+// namespace is joined, it is empty unless IsContainer
+func (n NetworkMode) Container() string {
+	if !n.IsContainer() {
+		return ""
+	}
+	return strings.TrimPrefix(string(n), "container:")
+}
+
 type HostConfig struct {
 	Binds           []string
 	ContainerIDFile string
@@ -13,6 +51,18 @@ type HostConfig struct {
 	PortBindings    nat.PortMap
 	Links           []string
 	PublishAllPorts bool
+	RestartPolicy   RestartPolicy
+	LogConfig       LogConfig
+	CapAdd          []string // capabilities kept in the container, ALL for every capability
+	CapDrop         []string // capabilities dropped from the container, ALL for every capability
+	Devices         []DeviceMapping
+	ReadonlyRootfs  bool
+	Tmpfs           map[string]string // tmpfs mounted in the container, by path, with their mount options
+	SeccompProfile  string            // seccomp profile file on the host of the daemon, unconfined to disable the filter
+	SecurityOpt     []string          // apparmor:PROFILE and label:OPTION security options
+	Ulimits         []*ulimit.Ulimit  // resource limits of the processes, over the defaults of the daemon
+	NetworkMode     NetworkMode       // networking of the container, bridge or empty for the default network
+	IPAddress       string            // static address of the container in the subnet of its network, allocated when empty
 }
 
 type KeyValuePair struct {
@@ -20,20 +70,57 @@ type KeyValuePair struct {
 	Value string
 }
 
+// DeviceMapping gives a device node of the host to the container
+type DeviceMapping struct {
+	PathOnHost        string
+	PathInContainer   string
+	CgroupPermissions string // any of r, w and m
+}
+
+// RestartPolicy defines when the daemon restarts a container after its process exited
+type RestartPolicy struct {
+	Name              string // "no", "always" or "on-failure"
+	MaximumRetryCount int    // only used by "on-failure", 0 means unlimited
+}
+
+// LogConfig selects the log driver of a container and its options
+type LogConfig struct {
+	Type   string            // "json-file", "syslog", "journald" or "none", the default of the daemon when empty
+	Config map[string]string // options of the log driver
+}
+
 func ContainerHostConfigFromJob(job *engine.Job) *HostConfig {
 	hostConfig := &HostConfig{
 		ContainerIDFile: job.Getenv("ContainerIDFile"),
 		Privileged:      job.GetenvBool("Privileged"),
+		ReadonlyRootfs:  job.GetenvBool("ReadonlyRootfs"),
+		SeccompProfile:  job.Getenv("SeccompProfile"),
 		PublishAllPorts: job.GetenvBool("PublishAllPorts"),
+		NetworkMode:     NetworkMode(job.Getenv("NetworkMode")),
+		IPAddress:       job.Getenv("IPAddress"),
 	}
 	job.GetenvJson("LxcConf", &hostConfig.LxcConf)
 	job.GetenvJson("PortBindings", &hostConfig.PortBindings)
+	job.GetenvJson("RestartPolicy", &hostConfig.RestartPolicy)
+	job.GetenvJson("LogConfig", &hostConfig.LogConfig)
+	job.GetenvJson("Devices", &hostConfig.Devices)
+	job.GetenvJson("Tmpfs", &hostConfig.Tmpfs)
+	job.GetenvJson("Ulimits", &hostConfig.Ulimits)
 	if Binds := job.GetenvList("Binds"); Binds != nil {
 		hostConfig.Binds = Binds
 	}
 	if Links := job.GetenvList("Links"); Links != nil {
 		hostConfig.Links = Links
 	}
+	if CapAdd := job.GetenvList("CapAdd"); CapAdd != nil {
+		hostConfig.CapAdd = CapAdd
+	}
+	if CapDrop := job.GetenvList("CapDrop"); CapDrop != nil {
+		hostConfig.CapDrop = CapDrop
+	}
+	if SecurityOpt := job.GetenvList("SecurityOpt"); SecurityOpt != nil {
+		hostConfig.SecurityOpt = SecurityOpt
+	}
 
 	return hostConfig
 }
@@ -109,6 +109,25 @@ func Merge(userConf, imageConf *Config) error {
 	if userConf.VolumesFrom == "" {
 		userConf.VolumesFrom = imageConf.VolumesFrom
 	}
+	if userConf.Healthcheck == nil || len(userConf.Healthcheck.Test) == 0 {
+		healthcheck := userConf.Healthcheck
+		if imageConf.Healthcheck != nil {
+			userConf.Healthcheck = &HealthConfig{}
+			*userConf.Healthcheck = *imageConf.Healthcheck
+			// the timings given by the user override the ones of the image
+			if healthcheck != nil {
+				if healthcheck.Interval != 0 {
+					userConf.Healthcheck.Interval = healthcheck.Interval
+				}
+				if healthcheck.Timeout != 0 {
+					userConf.Healthcheck.Timeout = healthcheck.Timeout
+				}
+				if healthcheck.Retries != 0 {
+					userConf.Healthcheck.Retries = healthcheck.Retries
+				}
+			}
+		}
+	}
 	if userConf.Volumes == nil || len(userConf.Volumes) == 0 {
 		userConf.Volumes = imageConf.Volumes
 	} else {
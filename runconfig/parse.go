@@ -6,16 +6,27 @@ import (
 	"github.com/dotcloud/docker/opts"
 	flag "github.com/dotcloud/docker/pkg/mflag"
 	"github.com/dotcloud/docker/pkg/sysinfo"
+	"github.com/dotcloud/docker/pkg/ulimit"
 	"github.com/dotcloud/docker/utils"
 	"io/ioutil"
+	"net"
 	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
-	ErrInvalidWorikingDirectory = fmt.Errorf("The working directory is invalid. It needs to be an absolute path.")
-	ErrConflictAttachDetach     = fmt.Errorf("Conflicting options: -a and -d")
-	ErrConflictDetachAutoRemove = fmt.Errorf("Conflicting options: --rm and -d")
+	ErrInvalidWorikingDirectory           = fmt.Errorf("The working directory is invalid. It needs to be an absolute path.")
+	ErrConflictAttachDetach               = fmt.Errorf("Conflicting options: -a and -d")
+	ErrConflictDetachAutoRemove           = fmt.Errorf("Conflicting options: --rm and -d")
+	ErrConflictRestartPolicyAndAutoRemove = fmt.Errorf("Conflicting options: --restart and --rm")
+	ErrConflictContainerNetworkHostname   = fmt.Errorf("Conflicting options: -h and --net container:<name>, the hostname is the one of the other container")
+	ErrConflictContainerNetworkDns        = fmt.Errorf("Conflicting options: --dns and --net container:<name>, the DNS servers are the ones of the other container")
+	ErrConflictNetworkPublish             = fmt.Errorf("Conflicting options: -p, -P and --net host, none or container:<name>, the ports can only be published from a bridge")
+	ErrConflictNetworkLinks               = fmt.Errorf("Conflicting options: --link and --net host, none or container:<name>, the containers can only be linked on a bridge")
+	ErrConflictNetworkIP                  = fmt.Errorf("Conflicting options: --ip and --net host, none or container:<name>, the address can only be assigned on a bridge")
 )
 
 //FIXME Only used in tests
@@ -44,11 +55,24 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flDns         opts.ListOpts
 		flVolumesFrom opts.ListOpts
 		flLxcOpts     opts.ListOpts
+		flLogOpts     opts.ListOpts
+		flCapAdd      opts.ListOpts
+		flCapDrop     opts.ListOpts
+		flDevices     opts.ListOpts
+		flTmpfs       opts.ListOpts
+		flSecurityOpt opts.ListOpts
+		flUlimits     opts.ListOpts
+
+		flDeviceReadBps   opts.ListOpts
+		flDeviceWriteBps  opts.ListOpts
+		flDeviceReadIOps  opts.ListOpts
+		flDeviceWriteIOps opts.ListOpts
 
 		flAutoRemove      = cmd.Bool([]string{"#rm", "-rm"}, false, "Automatically remove the container when it exits (incompatible with -d)")
 		flDetach          = cmd.Bool([]string{"d", "-detach"}, false, "Detached mode: Run container in the background, print new container id")
 		flNetwork         = cmd.Bool([]string{"n", "-networking"}, true, "Enable networking for this container")
 		flPrivileged      = cmd.Bool([]string{"#privileged", "-privileged"}, false, "Give extended privileges to this container")
+		flReadonlyRootfs  = cmd.Bool([]string{"-read-only"}, false, "Mount the container's root filesystem as read only")
 		flPublishAll      = cmd.Bool([]string{"P", "-publish-all"}, false, "Publish all exposed ports to the host interfaces")
 		flStdin           = cmd.Bool([]string{"i", "-interactive"}, false, "Keep stdin open even if not attached")
 		flTty             = cmd.Bool([]string{"t", "-tty"}, false, "Allocate a pseudo-tty")
@@ -59,6 +83,25 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flUser            = cmd.String([]string{"u", "-user"}, "", "Username or UID")
 		flWorkingDir      = cmd.String([]string{"w", "-workdir"}, "", "Working directory inside the container")
 		flCpuShares       = cmd.Int64([]string{"c", "-cpu-shares"}, 0, "CPU shares (relative weight)")
+		flRestartPolicy   = cmd.String([]string{"-restart"}, "", "Restart policy to apply when a container exits (no, on-failure[:max-retry], always)")
+		flHealthCmd       = cmd.String([]string{"-health-cmd"}, "", "Command to run to check the health of the container")
+		flHealthInterval  = cmd.String([]string{"-health-interval"}, "", "Time between running the health check (e.g. 30s, 1m)")
+		flHealthTimeout   = cmd.String([]string{"-health-timeout"}, "", "Maximum time to allow one health check to run (e.g. 30s, 1m)")
+		flHealthRetries   = cmd.Int([]string{"-health-retries"}, 0, "Consecutive failures needed to report the container as unhealthy")
+		flNoHealthcheck   = cmd.Bool([]string{"-no-healthcheck"}, false, "Disable any container-specified health check")
+		flLogDriver       = cmd.String([]string{"-log-driver"}, "", "Log driver of the container (json-file, syslog, journald or none), the default of the daemon when empty")
+		flSeccompProfile  = cmd.String([]string{"-seccomp-profile"}, "", "Seccomp profile file on the host of the daemon, unconfined to disable the syscall filter")
+
+		flMemoryReservation = cmd.String([]string{"-memory-reservation"}, "", "Memory soft limit (format: <number><optional unit>, where unit = b, k, m or g)")
+		flKernelMemory      = cmd.String([]string{"-kernel-memory"}, "", "Kernel memory limit (format: <number><optional unit>, where unit = b, k, m or g)")
+		flCpuPeriod         = cmd.Int64([]string{"-cpu-period"}, 0, "Length of the CFS period in microseconds (1000 to 1000000)")
+		flCpuQuota          = cmd.Int64([]string{"-cpu-quota"}, 0, "CPU time the container can use in each CFS period, in microseconds")
+		flCpusetCpus        = cmd.String([]string{"-cpuset-cpus"}, "", "CPUs in which to allow execution (e.g. 0-3, 0,1)")
+		flCpusetMems        = cmd.String([]string{"-cpuset-mems"}, "", "Memory nodes in which to allow allocation (e.g. 0-3, 0,1)")
+		flBlkioWeight       = cmd.Int64([]string{"-blkio-weight"}, 0, "Block IO weight (relative weight, 10 to 1000)")
+		flPidsLimit         = cmd.Int64([]string{"-pids-limit"}, 0, "Maximum number of processes in the container (0 for unlimited)")
+		flNetMode           = cmd.String([]string{"-net"}, "bridge", "Network of the container: bridge, the name of a network created with 'docker network create', host, none or container:<name|id>")
+		flIPAddress         = cmd.String([]string{"-ip"}, "", "IPv4 address of the container in the subnet of its network (e.g. 172.17.0.10)")
 
 		// For documentation purpose
 		_ = cmd.Bool([]string{"#sig-proxy", "-sig-proxy"}, true, "Proxify all received signal to the process (even in non-tty mode)")
@@ -70,11 +113,22 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 	cmd.Var(&flLinks, []string{"#link", "-link"}, "Add link to another container (name:alias)")
 	cmd.Var(&flEnv, []string{"e", "-env"}, "Set environment variables")
 
-	cmd.Var(&flPublish, []string{"p", "-publish"}, fmt.Sprintf("Publish a container's port to the host (format: %s) (use 'docker port' to see the actual mapping)", nat.PortSpecTemplateFormat))
-	cmd.Var(&flExpose, []string{"#expose", "-expose"}, "Expose a port from the container without publishing it to your host")
+	cmd.Var(&flPublish, []string{"p", "-publish"}, fmt.Sprintf("Publish a container's port or range of ports to the host (format: %s, the ports can be ranges) (use 'docker port' to see the actual mapping)", nat.PortSpecTemplateFormat))
+	cmd.Var(&flExpose, []string{"#expose", "-expose"}, "Expose a port or a range of ports (e.g. 7000-7010) from the container without publishing it to your host")
 	cmd.Var(&flDns, []string{"#dns", "-dns"}, "Set custom dns servers")
 	cmd.Var(&flVolumesFrom, []string{"#volumes-from", "-volumes-from"}, "Mount volumes from the specified container(s)")
 	cmd.Var(&flLxcOpts, []string{"#lxc-conf", "-lxc-conf"}, "Add custom lxc options --lxc-conf=\"lxc.cgroup.cpuset.cpus = 0,1\"")
+	cmd.Var(&flLogOpts, []string{"-log-opt"}, "Log driver options (e.g. --log-opt syslog-address=udp://10.0.0.1:514)")
+	cmd.Var(&flCapAdd, []string{"-cap-add"}, "Add Linux capabilities (e.g. --cap-add NET_ADMIN, or ALL)")
+	cmd.Var(&flCapDrop, []string{"-cap-drop"}, "Drop Linux capabilities (e.g. --cap-drop CHOWN, or ALL)")
+	cmd.Var(&flDevices, []string{"-device"}, "Add a host device to the container (e.g. --device=/dev/sdc:/dev/xvdc:rwm)")
+	cmd.Var(&flTmpfs, []string{"-tmpfs"}, "Mount a tmpfs in the container (e.g. --tmpfs /run:size=64m)")
+	cmd.Var(&flSecurityOpt, []string{"-security-opt"}, "Security options (e.g. --security-opt apparmor:PROFILE or --security-opt label:type:TYPE)")
+	cmd.Var(&flUlimits, []string{"-ulimit"}, "Ulimit options (e.g. --ulimit nofile=1024:2048)")
+	cmd.Var(&flDeviceReadBps, []string{"-device-read-bps"}, "Limit the read rate from a device (e.g. --device-read-bps=/dev/sda:1mb)")
+	cmd.Var(&flDeviceWriteBps, []string{"-device-write-bps"}, "Limit the write rate to a device (e.g. --device-write-bps=/dev/sda:1mb)")
+	cmd.Var(&flDeviceReadIOps, []string{"-device-read-iops"}, "Limit the reads per second from a device (e.g. --device-read-iops=/dev/sda:1000)")
+	cmd.Var(&flDeviceWriteIOps, []string{"-device-write-iops"}, "Limit the writes per second to a device (e.g. --device-write-iops=/dev/sda:1000)")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil, nil, cmd, err
@@ -96,6 +150,43 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		return nil, nil, cmd, ErrConflictDetachAutoRemove
 	}
 
+	restartPolicy, err := ParseRestartPolicy(*flRestartPolicy)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+	if *flAutoRemove && (restartPolicy.Name == "always" || restartPolicy.Name == "on-failure") {
+		return nil, nil, cmd, ErrConflictRestartPolicyAndAutoRemove
+	}
+
+	netMode := NetworkMode(*flNetMode)
+	if netMode.IsContainer() {
+		if netMode.Container() == "" {
+			return nil, nil, cmd, fmt.Errorf("Invalid network mode %s, the container is missing (container:<name>)", netMode)
+		}
+		if *flHostname != "" {
+			return nil, nil, cmd, ErrConflictContainerNetworkHostname
+		}
+		if flDns.Len() > 0 {
+			return nil, nil, cmd, ErrConflictContainerNetworkDns
+		}
+	}
+	if !netMode.IsBridge() {
+		if flPublish.Len() > 0 || *flPublishAll {
+			return nil, nil, cmd, ErrConflictNetworkPublish
+		}
+		if flLinks.Len() > 0 {
+			return nil, nil, cmd, ErrConflictNetworkLinks
+		}
+		if *flIPAddress != "" {
+			return nil, nil, cmd, ErrConflictNetworkIP
+		}
+	}
+	if *flIPAddress != "" {
+		if ip := net.ParseIP(*flIPAddress); ip == nil || ip.To4() == nil {
+			return nil, nil, cmd, fmt.Errorf("Invalid IPv4 address: %s", *flIPAddress)
+		}
+	}
+
 	// If neither -d or -a are set, attach to everything by default
 	if flAttach.Len() == 0 && !*flDetach {
 		if !*flDetach {
@@ -107,6 +198,11 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		}
 	}
 
+	healthcheck, err := parseHealthcheck(*flHealthCmd, *flHealthInterval, *flHealthTimeout, *flHealthRetries, *flNoHealthcheck)
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
 	var flMemory int64
 	if *flMemoryString != "" {
 		parsedMemory, err := utils.RAMInBytes(*flMemoryString)
@@ -116,6 +212,31 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		flMemory = parsedMemory
 	}
 
+	var memoryReservation int64
+	if *flMemoryReservation != "" {
+		if memoryReservation, err = utils.RAMInBytes(*flMemoryReservation); err != nil {
+			return nil, nil, cmd, err
+		}
+	}
+
+	var kernelMemory int64
+	if *flKernelMemory != "" {
+		if kernelMemory, err = utils.RAMInBytes(*flKernelMemory); err != nil {
+			return nil, nil, cmd, err
+		}
+	}
+
+	throttles := make([][]ThrottleDevice, 4)
+	for i, fl := range []opts.ListOpts{flDeviceReadBps, flDeviceWriteBps, flDeviceReadIOps, flDeviceWriteIOps} {
+		for _, spec := range fl.GetAll() {
+			device, err := ParseThrottleDevice(spec, i < 2)
+			if err != nil {
+				return nil, nil, cmd, err
+			}
+			throttles[i] = append(throttles[i], device)
+		}
+	}
+
 	var binds []string
 	// add any bind targets to the list of container volumes
 	for bind := range flVolumes.GetMap() {
@@ -153,6 +274,34 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		return nil, nil, cmd, err
 	}
 
+	logOpts, err := ParseLogOpts(flLogOpts.GetAll())
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
+	var devices []DeviceMapping
+	for _, device := range flDevices.GetAll() {
+		mapping, err := ParseDevice(device)
+		if err != nil {
+			return nil, nil, cmd, err
+		}
+		devices = append(devices, mapping)
+	}
+
+	tmpfs, err := ParseTmpfs(flTmpfs.GetAll())
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
+	if _, _, err := ParseSecurityOpt(flSecurityOpt.GetAll()); err != nil {
+		return nil, nil, cmd, err
+	}
+
+	ulimits, err := ParseUlimits(flUlimits.GetAll())
+	if err != nil {
+		return nil, nil, cmd, err
+	}
+
 	var (
 		domainname string
 		hostname   = *flHostname
@@ -173,9 +322,21 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		if strings.Contains(e, ":") {
 			return nil, nil, cmd, fmt.Errorf("Invalid port format for --expose: %s", e)
 		}
-		p := nat.NewPort(nat.SplitProtoPort(e))
-		if _, exists := ports[p]; !exists {
-			ports[p] = struct{}{}
+		proto, port := nat.SplitProtoPort(e)
+		start, end, err := nat.ParsePortRange(port)
+		if err != nil {
+			return nil, nil, cmd, fmt.Errorf("Invalid port format for --expose: %s", e)
+		}
+		// a single port is kept as it was specified
+		isRange := strings.Contains(port, "-")
+		for i := start; i <= end; i++ {
+			if isRange {
+				port = strconv.Itoa(i)
+			}
+			p := nat.NewPort(proto, port)
+			if _, exists := ports[p]; !exists {
+				ports[p] = struct{}{}
+			}
 		}
 	}
 
@@ -201,6 +362,24 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		VolumesFrom:     strings.Join(flVolumesFrom.GetAll(), ","),
 		Entrypoint:      entrypoint,
 		WorkingDir:      *flWorkingDir,
+		Healthcheck:     healthcheck,
+
+		MemoryReservation: memoryReservation,
+		KernelMemory:      kernelMemory,
+		CpuPeriod:         *flCpuPeriod,
+		CpuQuota:          *flCpuQuota,
+		CpusetCpus:        *flCpusetCpus,
+		CpusetMems:        *flCpusetMems,
+		BlkioWeight:       *flBlkioWeight,
+		PidsLimit:         *flPidsLimit,
+
+		BlkioDeviceReadBps:   throttles[0],
+		BlkioDeviceWriteBps:  throttles[1],
+		BlkioDeviceReadIOps:  throttles[2],
+		BlkioDeviceWriteIOps: throttles[3],
+	}
+	if err := ValidateResources(config); err != nil {
+		return nil, nil, cmd, err
 	}
 
 	hostConfig := &HostConfig{
@@ -211,6 +390,18 @@ func parseRun(cmd *flag.FlagSet, args []string, sysInfo *sysinfo.SysInfo) (*Conf
 		PortBindings:    portBindings,
 		Links:           flLinks.GetAll(),
 		PublishAllPorts: *flPublishAll,
+		RestartPolicy:   restartPolicy,
+		LogConfig:       LogConfig{Type: *flLogDriver, Config: logOpts},
+		CapAdd:          flCapAdd.GetAll(),
+		CapDrop:         flCapDrop.GetAll(),
+		Devices:         devices,
+		ReadonlyRootfs:  *flReadonlyRootfs,
+		Tmpfs:           tmpfs,
+		SeccompProfile:  *flSeccompProfile,
+		SecurityOpt:     flSecurityOpt.GetAll(),
+		Ulimits:         ulimits,
+		NetworkMode:     netMode,
+		IPAddress:       *flIPAddress,
 	}
 
 	if sysInfo != nil && flMemory > 0 && !sysInfo.SwapLimit {
@@ -244,3 +435,273 @@ func parseLxcOpt(opt string) (string, string, error) {
 	}
 	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
 }
+
+// ParseLogOpts parses the key=value options of a log driver
+func ParseLogOpts(opts []string) (map[string]string, error) {
+	if len(opts) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(opts))
+	for _, o := range opts {
+		parts := strings.SplitN(o, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid log option %s, the format is key=value", o)
+		}
+		out[parts[0]] = parts[1]
+	}
+	return out, nil
+}
+
+// ParseDevice parses a device of the form host-path[:container-path][:permissions],
+// the device has the same path in the container and rwm permissions by default
+func ParseDevice(device string) (DeviceMapping, error) {
+	mapping := DeviceMapping{CgroupPermissions: "rwm"}
+	parts := strings.Split(device, ":")
+	switch len(parts) {
+	case 3:
+		mapping.CgroupPermissions = parts[2]
+		fallthrough
+	case 2:
+		mapping.PathInContainer = parts[1]
+		fallthrough
+	case 1:
+		mapping.PathOnHost = parts[0]
+	default:
+		return mapping, fmt.Errorf("Invalid device %s, the format is host-path[:container-path][:permissions]", device)
+	}
+	// the permissions can follow the host path directly
+	if len(parts) == 2 && !path.IsAbs(mapping.PathInContainer) {
+		mapping.CgroupPermissions = mapping.PathInContainer
+		mapping.PathInContainer = ""
+	}
+	if mapping.PathInContainer == "" {
+		mapping.PathInContainer = mapping.PathOnHost
+	}
+	if !path.IsAbs(mapping.PathOnHost) || !path.IsAbs(mapping.PathInContainer) {
+		return mapping, fmt.Errorf("Invalid device %s, the paths must be absolute", device)
+	}
+	if mapping.CgroupPermissions == "" || strings.Trim(mapping.CgroupPermissions, "rwm") != "" {
+		return mapping, fmt.Errorf("Invalid device %s, the permissions are any of r, w and m", device)
+	}
+	return mapping, nil
+}
+
+// ParseTmpfs parses tmpfs mounts of the form /path[:options], the options
+// are comma separated mount options
+func ParseTmpfs(mounts []string) (map[string]string, error) {
+	if len(mounts) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(mounts))
+	for _, m := range mounts {
+		parts := strings.SplitN(m, ":", 2)
+		dest := path.Clean(parts[0])
+		if !path.IsAbs(dest) || dest == "/" {
+			return nil, fmt.Errorf("Invalid tmpfs %s, the path must be absolute and not /", m)
+		}
+		if _, exists := out[dest]; exists {
+			return nil, fmt.Errorf("Duplicate tmpfs %s", dest)
+		}
+		out[dest] = ""
+		if len(parts) == 2 {
+			out[dest] = parts[1]
+		}
+	}
+	return out, nil
+}
+
+// ParseSecurityOpt parses security options of the form apparmor:PROFILE and
+// label:disable, label:user:USER, label:role:ROLE, label:type:TYPE or
+// label:level:LEVEL. It returns the AppArmor profile and the SELinux label
+// options without their prefix.
+func ParseSecurityOpt(opts []string) (string, []string, error) {
+	var (
+		profile   string
+		labelOpts []string
+	)
+	for _, opt := range opts {
+		parts := strings.SplitN(opt, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", nil, fmt.Errorf("Invalid security option %s, expected apparmor:PROFILE or label:OPTION", opt)
+		}
+		switch parts[0] {
+		case "apparmor":
+			profile = parts[1]
+		case "label":
+			label := strings.SplitN(parts[1], ":", 2)
+			switch label[0] {
+			case "disable":
+				if len(label) != 1 {
+					return "", nil, fmt.Errorf("Invalid security option %s, label:disable has no value", opt)
+				}
+			case "user", "role", "type", "level":
+				if len(label) != 2 || label[1] == "" {
+					return "", nil, fmt.Errorf("Invalid security option %s, expected label:%s:VALUE", opt, label[0])
+				}
+			default:
+				return "", nil, fmt.Errorf("Invalid security option %s, the label options are disable, user, role, type and level", opt)
+			}
+			labelOpts = append(labelOpts, parts[1])
+		default:
+			return "", nil, fmt.Errorf("Invalid security option %s, expected apparmor:PROFILE or label:OPTION", opt)
+		}
+	}
+	return profile, labelOpts, nil
+}
+
+// ParseUlimits parses ulimits of the form name=soft[:hard], each resource
+// can only be limited once
+func ParseUlimits(opts []string) ([]*ulimit.Ulimit, error) {
+	var (
+		ulimits []*ulimit.Ulimit
+		names   = make(map[string]bool)
+	)
+	for _, opt := range opts {
+		u, err := ulimit.Parse(opt)
+		if err != nil {
+			return nil, err
+		}
+		if names[u.Name] {
+			return nil, fmt.Errorf("The ulimit %s is set more than once", u.Name)
+		}
+		names[u.Name] = true
+		ulimits = append(ulimits, u)
+	}
+	return ulimits, nil
+}
+
+// ParseThrottleDevice parses a rate limit of the form /dev/path:rate, the rate
+// is in bytes per second with an optional unit (k, m or g) for bps, and in
+// operations per second otherwise
+func ParseThrottleDevice(spec string, bps bool) (ThrottleDevice, error) {
+	i := strings.LastIndex(spec, ":")
+	if i < 0 || !path.IsAbs(spec[:i]) {
+		return ThrottleDevice{}, fmt.Errorf("Invalid device rate %s, expected /dev/path:rate", spec)
+	}
+	var (
+		rate uint64
+		err  error
+	)
+	if bps {
+		var bytes int64
+		bytes, err = utils.RAMInBytes(spec[i+1:])
+		rate = uint64(bytes)
+	} else {
+		rate, err = strconv.ParseUint(spec[i+1:], 10, 64)
+	}
+	if err != nil || rate == 0 {
+		return ThrottleDevice{}, fmt.Errorf("Invalid device rate %s, the rate must be a positive number", spec)
+	}
+	return ThrottleDevice{Path: spec[:i], Rate: rate}, nil
+}
+
+var cpusetRegexp = regexp.MustCompile(`^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$`)
+
+// ValidateResources checks the cgroup limits of a container config,
+// a zero value is no limit
+func ValidateResources(config *Config) error {
+	if config.MemoryReservation < 0 || config.KernelMemory < 0 || config.CpuPeriod < 0 || config.BlkioWeight < 0 || config.PidsLimit < 0 {
+		return fmt.Errorf("The resource limits cannot be negative")
+	}
+	if config.Memory > 0 && config.MemoryReservation > config.Memory {
+		return fmt.Errorf("The memory reservation (%d) cannot be larger than the memory limit (%d)", config.MemoryReservation, config.Memory)
+	}
+	if config.KernelMemory != 0 && config.KernelMemory < 4*1024*1024 {
+		return fmt.Errorf("Minimum kernel memory limit allowed is 4m")
+	}
+	if config.CpuPeriod != 0 && (config.CpuPeriod < 1000 || config.CpuPeriod > 1000000) {
+		return fmt.Errorf("The CPU period must be between 1000 and 1000000 microseconds")
+	}
+	if config.CpuQuota != 0 && config.CpuQuota < 1000 {
+		return fmt.Errorf("The CPU quota must be at least 1000 microseconds")
+	}
+	if config.BlkioWeight != 0 && (config.BlkioWeight < 10 || config.BlkioWeight > 1000) {
+		return fmt.Errorf("The block IO weight must be between 10 and 1000")
+	}
+	for _, cpuset := range []string{config.CpusetCpus, config.CpusetMems} {
+		if cpuset != "" && !cpusetRegexp.MatchString(cpuset) {
+			return fmt.Errorf("Invalid cpuset %s, expected a list of numbers and ranges (e.g. 0-3,6)", cpuset)
+		}
+	}
+	return nil
+}
+
+// ParseRestartPolicy parses a restart policy of the form no, always or on-failure[:max-retry]
+func ParseRestartPolicy(policy string) (RestartPolicy, error) {
+	p := RestartPolicy{}
+
+	if policy == "" {
+		return p, nil
+	}
+
+	var (
+		parts = strings.Split(policy, ":")
+		name  = parts[0]
+	)
+
+	switch name {
+	case "always", "no":
+		if len(parts) == 2 {
+			return p, fmt.Errorf("maximum restart count not valid with restart policy of \"%s\"", name)
+		}
+	case "on-failure":
+		if len(parts) == 2 {
+			count, err := strconv.Atoi(parts[1])
+			if err != nil || count < 0 {
+				return p, fmt.Errorf("invalid maximum restart count for on-failure: %s", parts[1])
+			}
+			p.MaximumRetryCount = count
+		}
+	default:
+		return p, fmt.Errorf("invalid restart policy %s", name)
+	}
+	if len(parts) > 2 {
+		return p, fmt.Errorf("invalid restart policy %s", policy)
+	}
+	p.Name = name
+	return p, nil
+}
+
+// parseHealthcheck builds the health check of the container from the run flags,
+// nil is returned when no flag is set so the health check of the image is used
+func parseHealthcheck(command, interval, timeout string, retries int, disable bool) (*HealthConfig, error) {
+	if disable {
+		if command != "" || interval != "" || timeout != "" || retries != 0 {
+			return nil, fmt.Errorf("--no-healthcheck conflicts with --health-* options")
+		}
+		return &HealthConfig{Test: []string{"NONE"}}, nil
+	}
+	if command == "" && interval == "" && timeout == "" && retries == 0 {
+		return nil, nil
+	}
+
+	if retries < 0 {
+		return nil, fmt.Errorf("--health-retries cannot be negative")
+	}
+
+	healthcheck := &HealthConfig{Retries: retries}
+	if command != "" {
+		healthcheck.Test = []string{"CMD-SHELL", command}
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"--health-interval", interval, &healthcheck.Interval},
+		{"--health-timeout", timeout, &healthcheck.Timeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value for %s: %s", d.name, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("%s must be positive", d.name)
+		}
+		*d.dest = duration
+	}
+	return healthcheck, nil
+}
@@ -1,7 +1,10 @@
 package runconfig
 
 import (
+	"github.com/dotcloud/docker/nat"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseLxcConfOpt(t *testing.T) {
@@ -20,3 +23,293 @@ func TestParseLxcConfOpt(t *testing.T) {
 		}
 	}
 }
+
+func TestParseRestartPolicy(t *testing.T) {
+	valid := map[string]RestartPolicy{
+		"":             {},
+		"no":           {Name: "no"},
+		"always":       {Name: "always"},
+		"on-failure":   {Name: "on-failure"},
+		"on-failure:5": {Name: "on-failure", MaximumRetryCount: 5},
+	}
+	for policy, expected := range valid {
+		p, err := ParseRestartPolicy(policy)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %s", policy, err)
+		}
+		if p != expected {
+			t.Fatalf("Expected %+v for %q, received %+v", expected, policy, p)
+		}
+	}
+
+	for _, policy := range []string{"sometimes", "always:3", "on-failure:x", "on-failure:-1", "on-failure:1:2"} {
+		if _, err := ParseRestartPolicy(policy); err == nil {
+			t.Fatalf("Expected an error parsing %q", policy)
+		}
+	}
+}
+
+func TestParseHealthcheck(t *testing.T) {
+	config, _, _, err := Parse([]string{"--health-cmd", "curl -f http://localhost/", "--health-interval", "5s", "--health-retries", "2", "ubuntu"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := config.Healthcheck
+	if h == nil || len(h.Test) != 2 || h.Test[0] != "CMD-SHELL" || h.Test[1] != "curl -f http://localhost/" {
+		t.Fatalf("Unexpected health check %+v", h)
+	}
+	if h.Interval != 5*time.Second || h.Timeout != 0 || h.Retries != 2 {
+		t.Fatalf("Unexpected health check timings %+v", h)
+	}
+
+	config, _, _, err = Parse([]string{"--no-healthcheck", "ubuntu"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := config.Healthcheck; h == nil || len(h.Test) != 1 || h.Test[0] != "NONE" {
+		t.Fatalf("Expected the health check to be disabled, received %+v", h)
+	}
+
+	for _, args := range [][]string{
+		{"--no-healthcheck", "--health-cmd", "true", "ubuntu"},
+		{"--health-interval", "soon", "ubuntu"},
+		{"--health-timeout", "-1s", "ubuntu"},
+	} {
+		if _, _, _, err := Parse(args, nil); err == nil {
+			t.Fatalf("Expected an error parsing %v", args)
+		}
+	}
+}
+
+func TestParseLogConfig(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--log-driver", "syslog", "--log-opt", "syslog-address=udp://10.0.0.1:514", "ubuntu"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.LogConfig.Type != "syslog" {
+		t.Fatalf("Expected the syslog log driver, received %q", hostConfig.LogConfig.Type)
+	}
+	if address := hostConfig.LogConfig.Config["syslog-address"]; address != "udp://10.0.0.1:514" {
+		t.Fatalf("Expected the syslog-address option, received %q", address)
+	}
+
+	if _, err := ParseLogOpts([]string{"syslog-address"}); err == nil {
+		t.Fatal("Expected an error for an option without value")
+	}
+}
+
+func TestParseDevice(t *testing.T) {
+	valids := map[string]DeviceMapping{
+		"/dev/fuse":                 {"/dev/fuse", "/dev/fuse", "rwm"},
+		"/dev/fuse:r":               {"/dev/fuse", "/dev/fuse", "r"},
+		"/dev/sdc:/dev/xvdc":        {"/dev/sdc", "/dev/xvdc", "rwm"},
+		"/dev/net/tun:/dev/tun0:rw": {"/dev/net/tun", "/dev/tun0", "rw"},
+	}
+	for device, expected := range valids {
+		mapping, err := ParseDevice(device)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", device, err)
+		}
+		if mapping != expected {
+			t.Fatalf("Expected %v for %s, received %v", expected, device, mapping)
+		}
+	}
+
+	for _, device := range []string{"dev/fuse", "/dev/sdc:xvdc:rwm", "/dev/fuse:rwx", "/dev/fuse:/dev/fuse:", "/a:/b:r:w"} {
+		if _, err := ParseDevice(device); err == nil {
+			t.Fatalf("Expected an error for %s", device)
+		}
+	}
+}
+
+func TestParseTmpfs(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--read-only", "--tmpfs", "/run:size=64m,exec", "--tmpfs", "/tmp/", "ubuntu"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hostConfig.ReadonlyRootfs {
+		t.Fatal("Expected the rootfs to be read only")
+	}
+	if len(hostConfig.Tmpfs) != 2 || hostConfig.Tmpfs["/run"] != "size=64m,exec" || hostConfig.Tmpfs["/tmp"] != "" {
+		t.Fatalf("Unexpected tmpfs %v", hostConfig.Tmpfs)
+	}
+
+	for _, mounts := range [][]string{{"run"}, {"/"}, {"/run", "/run/"}} {
+		if _, err := ParseTmpfs(mounts); err == nil {
+			t.Fatalf("Expected an error for %v", mounts)
+		}
+	}
+}
+
+func TestParseSecurityOpt(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--security-opt", "apparmor:docker-custom", "--security-opt", "label:type:svirt_apache_t", "--security-opt", "label:level:s0:c100,c200", "ubuntu"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, labelOpts, err := ParseSecurityOpt(hostConfig.SecurityOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile != "docker-custom" {
+		t.Fatalf("Expected the docker-custom profile, received %s", profile)
+	}
+	if len(labelOpts) != 2 || labelOpts[0] != "type:svirt_apache_t" || labelOpts[1] != "level:s0:c100,c200" {
+		t.Fatalf("Unexpected label options %v", labelOpts)
+	}
+
+	for _, opt := range []string{"apparmor", "apparmor:", "label:type", "label:disable:true", "label:kind:t", "selinux:type:t"} {
+		if _, _, err := ParseSecurityOpt([]string{opt}); err == nil {
+			t.Fatalf("Expected an error for %s", opt)
+		}
+	}
+}
+
+func TestParseResources(t *testing.T) {
+	config, _, _, err := Parse([]string{
+		"-m", "512m", "--memory-reservation", "256m", "--kernel-memory", "64m",
+		"--cpu-period", "100000", "--cpu-quota", "50000", "--cpuset-cpus", "0-2,6", "--cpuset-mems", "0",
+		"--blkio-weight", "300", "--device-read-bps", "/dev/sda:1mb", "--device-write-iops", "/dev/sdb:1000",
+		"--pids-limit", "100", "ubuntu",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MemoryReservation != 256*1024*1024 || config.KernelMemory != 64*1024*1024 {
+		t.Fatalf("Unexpected memory limits %d and %d", config.MemoryReservation, config.KernelMemory)
+	}
+	if config.CpuPeriod != 100000 || config.CpuQuota != 50000 || config.CpusetCpus != "0-2,6" || config.CpusetMems != "0" {
+		t.Fatalf("Unexpected cpu limits %+v", config)
+	}
+	if config.BlkioWeight != 300 || config.PidsLimit != 100 {
+		t.Fatalf("Unexpected blkio weight %d or pids limit %d", config.BlkioWeight, config.PidsLimit)
+	}
+	if len(config.BlkioDeviceReadBps) != 1 || config.BlkioDeviceReadBps[0] != (ThrottleDevice{"/dev/sda", 1024 * 1024}) {
+		t.Fatalf("Unexpected read rates %v", config.BlkioDeviceReadBps)
+	}
+	if len(config.BlkioDeviceWriteIOps) != 1 || config.BlkioDeviceWriteIOps[0] != (ThrottleDevice{"/dev/sdb", 1000}) {
+		t.Fatalf("Unexpected write rates %v", config.BlkioDeviceWriteIOps)
+	}
+
+	for _, args := range [][]string{
+		{"-m", "256m", "--memory-reservation", "512m"},
+		{"--kernel-memory", "1m"},
+		{"--cpu-period", "100"},
+		{"--cpu-quota", "10"},
+		{"--cpuset-cpus", "0-"},
+		{"--blkio-weight", "5"},
+		{"--pids-limit", "-1"},
+		{"--device-read-bps", "sda:1mb"},
+		{"--device-read-iops", "/dev/sda:1k"},
+		{"--device-write-bps", "/dev/sda:0"},
+	} {
+		if _, _, _, err := Parse(append(args, "ubuntu"), nil); err == nil {
+			t.Fatalf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestParseUlimits(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--ulimit", "nofile=1024:2048", "--ulimit", "memlock=unlimited", "ubuntu"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.Ulimits) != 2 {
+		t.Fatalf("Expected 2 ulimits, got %d", len(hostConfig.Ulimits))
+	}
+	if u := hostConfig.Ulimits[0]; u.Name != "nofile" || u.Soft != 1024 || u.Hard != 2048 {
+		t.Fatalf("Expected nofile=1024:2048, got %s", u)
+	}
+	if u := hostConfig.Ulimits[1]; u.Name != "memlock" || u.Soft != -1 || u.Hard != -1 {
+		t.Fatalf("Expected memlock=unlimited:unlimited, got %s", u)
+	}
+
+	if _, err := ParseUlimits([]string{"nofile=1024", "nofile=2048"}); err == nil {
+		t.Fatal("Expected an error for a ulimit set twice")
+	}
+	if _, _, _, err := Parse([]string{"--ulimit", "nofile=2048:1024", "ubuntu"}, nil); err == nil {
+		t.Fatal("Expected an error for a soft limit greater than the hard limit")
+	}
+}
+
+func TestParseNetworkMode(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"ubuntu"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.NetworkMode != "bridge" {
+		t.Fatalf("Expected the bridge network by default, got %s", hostConfig.NetworkMode)
+	}
+	if _, hostConfig, _, err = Parse([]string{"--net", "backend", "ubuntu"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.NetworkMode != "backend" {
+		t.Fatalf("Expected the network backend, got %s", hostConfig.NetworkMode)
+	}
+}
+
+func TestParseIPAddress(t *testing.T) {
+	_, hostConfig, _, err := Parse([]string{"--ip", "172.17.0.10", "ubuntu"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostConfig.IPAddress != "172.17.0.10" {
+		t.Fatalf("Expected the address 172.17.0.10, got %s", hostConfig.IPAddress)
+	}
+	for _, ip := range []string{"172.17.0", "2001:db8::10"} {
+		if _, _, _, err := Parse([]string{"--ip", ip, "ubuntu"}, nil); err == nil {
+			t.Fatalf("Expected an error for the address %s", ip)
+		}
+	}
+}
+
+func TestNetworkModes(t *testing.T) {
+	for mode, expected := range map[NetworkMode][4]bool{
+		"":                {true, false, false, false},
+		"bridge":          {true, false, false, false},
+		"backend":         {true, false, false, false},
+		"host":            {false, true, false, false},
+		"none":            {false, false, true, false},
+		"container:redis": {false, false, false, true},
+	} {
+		if got := [4]bool{mode.IsBridge(), mode.IsHost(), mode.IsNone(), mode.IsContainer()}; got != expected {
+			t.Fatalf("Expected the bridge, host, none and container checks of %q to be %v, got %v", mode, expected, got)
+		}
+	}
+	if name := NetworkMode("container:redis").Container(); name != "redis" {
+		t.Fatalf("Expected the container redis, got %s", name)
+	}
+
+	for args, expected := range map[string]error{
+		"--net container:redis -h db":         ErrConflictContainerNetworkHostname,
+		"--net container:redis --dns 8.8.8.8": ErrConflictContainerNetworkDns,
+		"--net host -p 80:80":                 ErrConflictNetworkPublish,
+		"--net none -P":                       ErrConflictNetworkPublish,
+		"--net host --link redis:db":          ErrConflictNetworkLinks,
+		"--net none --ip 172.17.0.10":         ErrConflictNetworkIP,
+	} {
+		if _, _, _, err := Parse(append(strings.Fields(args), "ubuntu"), nil); err != expected {
+			t.Fatalf("Expected %q for %s, got %v", expected, args, err)
+		}
+	}
+	if _, _, _, err := Parse([]string{"--net", "container:", "ubuntu"}, nil); err == nil {
+		t.Fatal("Expected an error for a container network mode without a container")
+	}
+}
+
+func TestParseExposeRange(t *testing.T) {
+	config, _, _, err := Parse([]string{"--expose", "7000-7010", "--expose", "53/udp", "ubuntu"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.ExposedPorts) != 12 {
+		t.Fatalf("Expected 12 exposed ports, got %v", config.ExposedPorts)
+	}
+	for _, port := range []nat.Port{"7000/tcp", "7005/tcp", "7010/tcp", "53/udp"} {
+		if _, exists := config.ExposedPorts[port]; !exists {
+			t.Fatalf("Expected %s to be exposed, got %v", port, config.ExposedPorts)
+		}
+	}
+	if _, _, _, err := Parse([]string{"--expose", "7010-7000", "ubuntu"}, nil); err == nil {
+		t.Fatal("Expected an error for an invalid range")
+	}
+}
@@ -9,15 +9,23 @@ import (
 	"github.com/dotcloud/docker/image"
 	"github.com/dotcloud/docker/links"
 	"github.com/dotcloud/docker/nat"
+	"github.com/dotcloud/docker/pkg/cgroups"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/pkg/libcontainer/selinux"
+	"github.com/dotcloud/docker/pkg/sysinfo"
+	"github.com/dotcloud/docker/pkg/ulimit"
 	"github.com/dotcloud/docker/runconfig"
 	"github.com/dotcloud/docker/runtime/execdriver"
 	"github.com/dotcloud/docker/runtime/graphdriver"
+	"github.com/dotcloud/docker/runtime/logger"
+	"github.com/dotcloud/docker/runtime/logger/jsonfilelog"
 	"github.com/dotcloud/docker/utils"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -33,6 +41,19 @@ var (
 	ErrContainerStartTimeout = errors.New("The container failed to start due to timed out.")
 )
 
+const (
+	// Delay before the first restart of a container by its restart policy,
+	// doubled after each consecutive restart up to maxRestartDelay
+	defaultRestartDelay = 100 * time.Millisecond
+	maxRestartDelay     = 1 * time.Minute
+	// A container running for longer than this is considered healthy
+	// and the restart delay is reset
+	restartDelayResetTime = 10 * time.Second
+)
+
+// Mount options of the tmpfs of the containers, before their own options
+const defaultTmpfsOptions = "nosuid,nodev,noexec"
+
 type Container struct {
 	sync.Mutex
 	root   string // Path to the "home" of the container, including metadata.
@@ -57,6 +78,13 @@ type Container struct {
 	Name           string
 	Driver         string
 	ExecDriver     string
+	RestartCount   int
+
+	// Security options applied to the process of the running container,
+	// the default AppArmor profile of the exec driver when empty
+	AppArmorProfile string
+	ProcessLabel    string
+	MountLabel      string
 
 	command   *execdriver.Command
 	stdout    *utils.WriteBroadcaster
@@ -74,6 +102,22 @@ type Container struct {
 	hostConfig *runconfig.HostConfig
 
 	activeLinks map[string]*links.Link
+
+	// logDriver receives the output of the running container
+	logDriver logger.Logger
+
+	// manualStop is set when the container is stopped by the user,
+	// the restart policy is not applied to the container afterwards
+	manualStop   bool
+	restartDelay time.Duration
+
+	// healthStop is closed to stop the health checks of the running container
+	healthStop chan struct{}
+
+	// reservedIP is the static address reserved for the container in the
+	// network reservedNetwork, until the container is destroyed
+	reservedIP      string
+	reservedNetwork string
 }
 
 // FIXME: move deprecated port stuff to nat to clean up the core.
@@ -84,8 +128,14 @@ type NetworkSettings struct {
 	IPPrefixLen int
 	Gateway     string
 	Bridge      string
+	Network     string                 // name of the network of the container
 	PortMapping map[string]PortMapping // Deprecated
 	Ports       nat.PortMap
+
+	// empty when the daemon does not give IPv6 addresses to the containers
+	GlobalIPv6Address   string
+	GlobalIPv6PrefixLen int
+	IPv6Gateway         string
 }
 
 func (settings *NetworkSettings) PortMappingAPI() *engine.Table {
@@ -358,7 +408,7 @@ func (container *Container) Attach(stdin io.ReadCloser, stdinCloser io.Closer, s
 	})
 }
 
-func populateCommand(c *Container) {
+func populateCommand(c *Container) error {
 	var (
 		en           *execdriver.Network
 		driverConfig []string
@@ -369,13 +419,28 @@ func populateCommand(c *Container) {
 		Interface: nil,
 	}
 
-	if !c.Config.NetworkDisabled {
+	mode := c.hostConfig.NetworkMode
+	switch {
+	case c.Config.NetworkDisabled || mode.IsNone():
+	case mode.IsHost():
+		en.HostNetworking = true
+	case mode.IsContainer():
+		nc, err := c.getNetworkedContainer()
+		if err != nil {
+			return err
+		}
+		en.ContainerID = nc.ID
+	default:
 		network := c.NetworkSettings
 		en.Interface = &execdriver.NetworkInterface{
 			Gateway:     network.Gateway,
 			Bridge:      network.Bridge,
 			IPAddress:   network.IPAddress,
 			IPPrefixLen: network.IPPrefixLen,
+
+			IPv6Address:   network.GlobalIPv6Address,
+			IPv6PrefixLen: network.GlobalIPv6PrefixLen,
+			IPv6Gateway:   network.IPv6Gateway,
 		}
 	}
 
@@ -384,10 +449,59 @@ func populateCommand(c *Container) {
 			driverConfig = append(driverConfig, fmt.Sprintf("%s = %s", pair.Key, pair.Value))
 		}
 	}
+	devices := make([]execdriver.Device, len(c.hostConfig.Devices))
+	for i, d := range c.hostConfig.Devices {
+		devices[i] = execdriver.Device{
+			PathOnHost:        d.PathOnHost,
+			PathInContainer:   d.PathInContainer,
+			CgroupPermissions: d.CgroupPermissions,
+		}
+	}
+	var tmpfs map[string]string
+	if len(c.hostConfig.Tmpfs) > 0 {
+		tmpfs = make(map[string]string, len(c.hostConfig.Tmpfs))
+		for path, options := range c.hostConfig.Tmpfs {
+			tmpfs[path] = defaultTmpfsOptions
+			if options != "" {
+				tmpfs[path] += "," + options
+			}
+		}
+	}
 	resources := &execdriver.Resources{
 		Memory:     c.Config.Memory,
 		MemorySwap: c.Config.MemorySwap,
 		CpuShares:  c.Config.CpuShares,
+
+		MemoryReservation: c.Config.MemoryReservation,
+		KernelMemory:      c.Config.KernelMemory,
+		CpuPeriod:         c.Config.CpuPeriod,
+		CpuQuota:          c.Config.CpuQuota,
+		CpusetCpus:        c.Config.CpusetCpus,
+		CpusetMems:        c.Config.CpusetMems,
+		BlkioWeight:       c.Config.BlkioWeight,
+		PidsLimit:         c.Config.PidsLimit,
+
+		Ulimits: c.Ulimits(),
+	}
+	for _, t := range []struct {
+		devices []runconfig.ThrottleDevice
+		out     *[]*cgroups.ThrottleDevice
+	}{
+		{c.Config.BlkioDeviceReadBps, &resources.BlkioThrottleReadBpsDevice},
+		{c.Config.BlkioDeviceWriteBps, &resources.BlkioThrottleWriteBpsDevice},
+		{c.Config.BlkioDeviceReadIOps, &resources.BlkioThrottleReadIOpsDevice},
+		{c.Config.BlkioDeviceWriteIOps, &resources.BlkioThrottleWriteIOpsDevice},
+	} {
+		for _, d := range t.devices {
+			device, err := libcontainer.GetDevice(d.Path, d.Path, "rwm")
+			if err != nil {
+				return err
+			}
+			if device.Type != "b" {
+				return fmt.Errorf("%s is not a block device, its IO cannot be limited", d.Path)
+			}
+			*t.out = append(*t.out, &cgroups.ThrottleDevice{Major: device.Major, Minor: device.Minor, Rate: d.Rate})
+		}
 	}
 	c.command = &execdriver.Command{
 		ID:         c.ID,
@@ -402,8 +516,80 @@ func populateCommand(c *Container) {
 		User:       c.Config.User,
 		Config:     driverConfig,
 		Resources:  resources,
+		CapAdd:     c.hostConfig.CapAdd,
+		CapDrop:    c.hostConfig.CapDrop,
+		Devices:    devices,
+
+		ReadonlyRootfs: c.hostConfig.ReadonlyRootfs,
+		Tmpfs:          tmpfs,
+		SeccompProfile: c.hostConfig.SeccompProfile,
+
+		UidMappings: c.runtime.uidMaps,
+		GidMappings: c.runtime.gidMaps,
+
+		AppArmorProfile: c.AppArmorProfile,
+		ProcessLabel:    c.ProcessLabel,
+		MountLabel:      c.MountLabel,
 	}
 	c.command.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	return nil
+}
+
+// discardUnsupportedResources drops the limits of the config
+// the kernel of the host does not support
+func discardUnsupportedResources(config *runconfig.Config, sysInfo *sysinfo.SysInfo) {
+	if config.MemoryReservation > 0 && !sysInfo.MemoryReservation {
+		log.Printf("WARNING: Your kernel does not support memory soft limit capabilities. Limitation discarded.\n")
+		config.MemoryReservation = 0
+	}
+	if config.KernelMemory > 0 && !sysInfo.KernelMemory {
+		log.Printf("WARNING: Your kernel does not support kernel memory limit capabilities. Limitation discarded.\n")
+		config.KernelMemory = 0
+	}
+	if config.CpuPeriod > 0 && !sysInfo.CpuCfsPeriod {
+		log.Printf("WARNING: Your kernel does not support CPU CFS period capabilities. Limitation discarded.\n")
+		config.CpuPeriod = 0
+	}
+	if config.CpuQuota > 0 && !sysInfo.CpuCfsQuota {
+		log.Printf("WARNING: Your kernel does not support CPU CFS quota capabilities. Limitation discarded.\n")
+		config.CpuQuota = 0
+	}
+	if (config.CpusetCpus != "" || config.CpusetMems != "") && !sysInfo.Cpuset {
+		log.Printf("WARNING: Your kernel does not support cpuset capabilities. Limitation discarded.\n")
+		config.CpusetCpus, config.CpusetMems = "", ""
+	}
+	if config.BlkioWeight > 0 && !sysInfo.BlkioWeight {
+		log.Printf("WARNING: Your kernel does not support block IO weight capabilities. Limitation discarded.\n")
+		config.BlkioWeight = 0
+	}
+	throttled := len(config.BlkioDeviceReadBps) + len(config.BlkioDeviceWriteBps) + len(config.BlkioDeviceReadIOps) + len(config.BlkioDeviceWriteIOps)
+	if throttled > 0 && !sysInfo.BlkioThrottle {
+		log.Printf("WARNING: Your kernel does not support block IO throttling capabilities. Limitation discarded.\n")
+		config.BlkioDeviceReadBps, config.BlkioDeviceWriteBps = nil, nil
+		config.BlkioDeviceReadIOps, config.BlkioDeviceWriteIOps = nil, nil
+	}
+	if config.PidsLimit > 0 && !sysInfo.PidsLimit {
+		log.Printf("WARNING: Your kernel does not support pids limit capabilities. Limitation discarded.\n")
+		config.PidsLimit = 0
+	}
+}
+
+// initSecurityLabels sets the AppArmor profile and reserves the SELinux
+// labels of the container according to its security options, the
+// privileged containers are not confined
+func (container *Container) initSecurityLabels() error {
+	profile, labelOpts, err := runconfig.ParseSecurityOpt(container.hostConfig.SecurityOpt)
+	if err != nil {
+		return err
+	}
+	if container.hostConfig.Privileged {
+		container.AppArmorProfile = "unconfined"
+		container.ProcessLabel, container.MountLabel = "", ""
+		return nil
+	}
+	container.AppArmorProfile = profile
+	container.ProcessLabel, container.MountLabel, err = selinux.InitLabels(labelOpts)
+	return err
 }
 
 func (container *Container) ArgsAsString() string {
@@ -418,13 +604,24 @@ func (container *Container) ArgsAsString() string {
 	return strings.Join(args, " ")
 }
 
-func (container *Container) Start() (err error) {
+func (container *Container) Start() error {
+	return container.start(true)
+}
+
+// start starts the container process, manual is false when the
+// container is restarted by the daemon according to its restart policy
+func (container *Container) start(manual bool) (err error) {
 	container.Lock()
 	defer container.Unlock()
 
 	if container.State.IsRunning() {
 		return fmt.Errorf("The container %s is already running.", container.ID)
 	}
+	if manual {
+		container.manualStop = false
+		container.RestartCount = 0
+		container.restartDelay = 0
+	}
 
 	defer func() {
 		if err != nil {
@@ -432,18 +629,28 @@ func (container *Container) Start() (err error) {
 		}
 	}()
 
+	if err := container.initSecurityLabels(); err != nil {
+		return err
+	}
 	if err := container.Mount(); err != nil {
 		return err
 	}
 
 	if container.runtime.config.DisableNetwork {
 		container.Config.NetworkDisabled = true
-		container.buildHostnameAndHostsFiles("127.0.1.1")
+		if err := container.buildHostnameAndHostsFiles("127.0.1.1"); err != nil {
+			return err
+		}
 	} else {
+		if err := container.reserveIP(); err != nil {
+			return err
+		}
 		if err := container.allocateNetwork(); err != nil {
 			return err
 		}
-		container.buildHostnameAndHostsFiles(container.NetworkSettings.IPAddress)
+		if err := container.buildHostnameAndHostsFiles(container.NetworkSettings.IPAddress); err != nil {
+			return err
+		}
 	}
 
 	// Make sure the config is compatible with the current kernel
@@ -456,6 +663,8 @@ func (container *Container) Start() (err error) {
 		container.Config.MemorySwap = -1
 	}
 
+	discardUnsupportedResources(container.Config, container.runtime.sysInfo)
+
 	if container.runtime.sysInfo.IPv4ForwardingDisabled {
 		log.Printf("WARNING: IPv4 forwarding is disabled. Networking will not work")
 	}
@@ -464,6 +673,18 @@ func (container *Container) Start() (err error) {
 		return err
 	}
 
+	// the remapped root of the container goes through the
+	// directories of its rootfs, its files and its volumes
+	traversable := []string{container.basefs, container.root}
+	for _, v := range container.Volumes {
+		traversable = append(traversable, v)
+	}
+	for _, dir := range traversable {
+		if err := container.runtime.makeTraversable(dir); err != nil {
+			return err
+		}
+	}
+
 	// Setup environment
 	env := []string{
 		"HOME=/",
@@ -499,6 +720,11 @@ func (container *Container) Start() (err error) {
 			if !child.State.IsRunning() {
 				return fmt.Errorf("Cannot link to a non running container: %s AS %s", child.Name, linkAlias)
 			}
+			// the traffic between the networks is dropped
+			if network := child.NetworkSettings.Network; network != "" && container.NetworkSettings.Network != "" && network != container.NetworkSettings.Network {
+				rollback()
+				return fmt.Errorf("Cannot link to a container on another network: %s AS %s is on the network %s, not %s", child.Name, linkAlias, network, container.NetworkSettings.Network)
+			}
 
 			link, err := links.NewLink(
 				container.NetworkSettings.IPAddress,
@@ -535,8 +761,15 @@ func (container *Container) Start() (err error) {
 
 	if container.Config.WorkingDir != "" {
 		container.Config.WorkingDir = path.Clean(container.Config.WorkingDir)
-		if err := os.MkdirAll(path.Join(container.basefs, container.Config.WorkingDir), 0755); err != nil {
-			return nil
+		workingDir := path.Join(container.basefs, container.Config.WorkingDir)
+		if _, err := os.Stat(workingDir); os.IsNotExist(err) {
+			if err := os.MkdirAll(workingDir, 0755); err != nil {
+				return nil
+			}
+			uid, gid := container.runtime.rootIds()
+			if err := os.Chown(workingDir, uid, gid); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -545,18 +778,17 @@ func (container *Container) Start() (err error) {
 		return err
 	}
 
-	populateCommand(container)
+	if err := populateCommand(container); err != nil {
+		return err
+	}
 	container.command.Env = env
 
 	if err := setupMountsForContainer(container, envPath); err != nil {
 		return err
 	}
 
-	// Setup logging of stdout and stderr to disk
-	if err := container.runtime.LogToDisk(container.stdout, container.logPath("json"), "stdout"); err != nil {
-		return err
-	}
-	if err := container.runtime.LogToDisk(container.stderr, container.logPath("json"), "stderr"); err != nil {
+	// Setup logging of stdout and stderr
+	if err := container.startLogging(); err != nil {
 		return err
 	}
 	container.waitLock = make(chan struct{})
@@ -564,6 +796,7 @@ func (container *Container) Start() (err error) {
 	callbackLock := make(chan struct{})
 	callback := func(command *execdriver.Command) {
 		container.State.SetRunning(command.Pid())
+		container.startHealthMonitor()
 		if command.Tty {
 			// The callback is called after the process Start()
 			// so we are in the parent process. In TTY mode, stdin/out/err is the PtySlace
@@ -637,10 +870,60 @@ func (container *Container) StderrPipe() (io.ReadCloser, error) {
 	return utils.NewBufReader(reader), nil
 }
 
-func (container *Container) buildHostnameAndHostsFiles(IP string) {
+// StdoutLogPipe returns the stdout of the container as json log lines,
+// like they are written to the log file
+func (container *Container) StdoutLogPipe() io.ReadCloser {
+	reader, writer := io.Pipe()
+	container.stdout.AddWriter(writer, "stdout")
+	return utils.NewBufReader(reader)
+}
+
+// StderrLogPipe returns the stderr of the container as json log lines,
+// like they are written to the log file
+func (container *Container) StderrLogPipe() io.ReadCloser {
+	reader, writer := io.Pipe()
+	container.stderr.AddWriter(writer, "stderr")
+	return utils.NewBufReader(reader)
+}
+
+// buildHostnameAndHostsFiles writes the hostname and hosts files of the
+// container, with IP as the address of its hostname. The container shares
+// them with the container whose network it joins, and has the hostname and
+// the hosts of the host when it uses the network stack of the host.
+func (container *Container) buildHostnameAndHostsFiles(IP string) error {
+	mode := container.hostConfig.NetworkMode
+	if mode.IsContainer() {
+		nc, err := container.getNetworkedContainer()
+		if err != nil {
+			return err
+		}
+		if !nc.State.IsRunning() {
+			return fmt.Errorf("Cannot join the network of %s, the container is not running", nc.ID)
+		}
+		container.Config.Hostname = nc.Config.Hostname
+		container.Config.Domainname = nc.Config.Domainname
+		container.HostnamePath = nc.HostnamePath
+		container.HostsPath = nc.HostsPath
+		container.ResolvConfPath = nc.ResolvConfPath
+		return nil
+	}
+	if mode.IsHost() {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		container.Config.Hostname = hostname
+		container.Config.Domainname = ""
+	}
+
 	container.HostnamePath = path.Join(container.root, "hostname")
 	ioutil.WriteFile(container.HostnamePath, []byte(container.Config.Hostname+"\n"), 0644)
 
+	if mode.IsHost() {
+		container.HostsPath = "/etc/hosts"
+		return nil
+	}
+
 	hostsContent := []byte(`
 127.0.0.1	localhost
 ::1		localhost ip6-localhost ip6-loopback
@@ -652,17 +935,76 @@ ff02::2		ip6-allrouters
 
 	container.HostsPath = path.Join(container.root, "hosts")
 
+	names := container.Config.Hostname
 	if container.Config.Domainname != "" {
-		hostsContent = append([]byte(fmt.Sprintf("%s\t%s.%s %s\n", IP, container.Config.Hostname, container.Config.Domainname, container.Config.Hostname)), hostsContent...)
-	} else if !container.Config.NetworkDisabled {
-		hostsContent = append([]byte(fmt.Sprintf("%s\t%s\n", IP, container.Config.Hostname)), hostsContent...)
+		names = fmt.Sprintf("%s.%s %s", container.Config.Hostname, container.Config.Domainname, container.Config.Hostname)
+	}
+	if ipv6 := container.NetworkSettings.GlobalIPv6Address; ipv6 != "" {
+		hostsContent = append([]byte(fmt.Sprintf("%s\t%s\n", ipv6, names)), hostsContent...)
+	}
+	if container.Config.Domainname != "" || (!container.Config.NetworkDisabled && !mode.IsNone()) {
+		hostsContent = append([]byte(fmt.Sprintf("%s\t%s\n", IP, names)), hostsContent...)
 	}
 
 	ioutil.WriteFile(container.HostsPath, hostsContent, 0644)
+	return nil
+}
+
+// getNetworkedContainer returns the container whose network namespace
+// the container joins with the container:<name> network mode
+func (container *Container) getNetworkedContainer() (*Container, error) {
+	name := container.hostConfig.NetworkMode.Container()
+	nc := container.runtime.Get(name)
+	if nc == nil {
+		return nil, fmt.Errorf("No such container to join the network of: %s", name)
+	}
+	if nc.ID == container.ID {
+		return nil, fmt.Errorf("The container %s can not join its own network", name)
+	}
+	return nc, nil
+}
+
+// reserveIP reserves the static address of the container in its network,
+// so that the address is not allocated to the other containers while the
+// container is stopped. The address reserved for a previous host config of
+// the container is given back.
+func (container *Container) reserveIP() error {
+	var ip, network string
+	if hc := container.hostConfig; hc != nil && hc.IPAddress != "" && hc.NetworkMode.IsBridge() && !container.Config.NetworkDisabled {
+		ip, network = hc.IPAddress, string(hc.NetworkMode)
+	}
+	if ip == container.reservedIP && network == container.reservedNetwork {
+		return nil
+	}
+	if ip != "" {
+		job := container.runtime.eng.Job("reserve_ip")
+		job.Setenv("Network", network)
+		job.Setenv("IP", ip)
+		if err := job.Run(); err != nil {
+			return err
+		}
+	}
+	container.unreserveIP()
+	container.reservedIP, container.reservedNetwork = ip, network
+	return nil
+}
+
+// unreserveIP gives back the static address reserved for the container
+func (container *Container) unreserveIP() {
+	if container.reservedIP == "" {
+		return
+	}
+	job := container.runtime.eng.Job("unreserve_ip")
+	job.Setenv("Network", container.reservedNetwork)
+	job.Setenv("IP", container.reservedIP)
+	if err := job.Run(); err != nil {
+		utils.Errorf("Error unreserving the address %s of %s: %s", container.reservedIP, container.ID, err)
+	}
+	container.reservedIP, container.reservedNetwork = "", ""
 }
 
 func (container *Container) allocateNetwork() error {
-	if container.Config.NetworkDisabled {
+	if container.Config.NetworkDisabled || !container.hostConfig.NetworkMode.IsBridge() {
 		return nil
 	}
 
@@ -679,8 +1021,12 @@ func (container *Container) allocateNetwork() error {
 			currentIP := container.NetworkSettings.IPAddress
 
 			job := eng.Job("allocate_interface", container.ID)
+			job.Setenv("Network", string(container.hostConfig.NetworkMode))
 			if currentIP != "" {
-				job.Setenv("RequestIP", currentIP)
+				job.Setenv("RequestedIP", currentIP)
+			}
+			if currentIPv6 := container.NetworkSettings.GlobalIPv6Address; currentIPv6 != "" {
+				job.Setenv("RequestedIPv6", currentIPv6)
 			}
 
 			env, err = job.Stdout.AddEnv()
@@ -694,6 +1040,11 @@ func (container *Container) allocateNetwork() error {
 		}
 	} else {
 		job := eng.Job("allocate_interface", container.ID)
+		job.Setenv("Network", string(container.hostConfig.NetworkMode))
+		// the static address is requested again each time the container starts
+		if ip := container.hostConfig.IPAddress; ip != "" {
+			job.Setenv("RequestedIP", ip)
+		}
 		env, err = job.Stdout.AddEnv()
 		if err != nil {
 			return err
@@ -737,13 +1088,30 @@ func (container *Container) allocateNetwork() error {
 
 	container.NetworkSettings.PortMapping = nil
 
+	// the ports are allocated in order, the bindings of consecutive ports
+	// on consecutive host ports are allocated at once as a range
+	ports := make([]nat.Port, 0, len(portSpecs))
 	for port := range portSpecs {
+		ports = append(ports, port)
+	}
+	nat.Sort(ports, func(ip, jp nat.Port) bool {
+		if ip.Proto() != jp.Proto() {
+			return ip.Proto() < jp.Proto()
+		}
+		return ip.Int() < jp.Int()
+	})
+	inRange := make(map[nat.Port]map[int]bool) // bindings allocated with the range of a previous port
+
+	for _, port := range ports {
 		binding := bindings[port]
 		if container.hostConfig.PublishAllPorts && len(binding) == 0 {
 			binding = append(binding, nat.PortBinding{})
 		}
 
 		for i := 0; i < len(binding); i++ {
+			if inRange[port][i] {
+				continue
+			}
 			b := binding[i]
 
 			portJob := eng.Job("allocate_port", container.ID)
@@ -752,6 +1120,15 @@ func (container *Container) allocateNetwork() error {
 			portJob.Setenv("Proto", port.Proto())
 			portJob.Setenv("ContainerPort", port.Port())
 
+			n := 1
+			if b.HostPort != "" {
+				n = portRangeLength(portSpecs, bindings, port, i)
+			}
+			if n > 1 {
+				hostPort, _ := nat.ParsePort(b.HostPort)
+				portJob.SetenvInt("HostPortEnd", hostPort+n-1)
+			}
+
 			portEnv, err := portJob.Stdout.AddEnv()
 			if err != nil {
 				return err
@@ -764,6 +1141,15 @@ func (container *Container) allocateNetwork() error {
 			b.HostPort = portEnv.Get("HostPort")
 
 			binding[i] = b
+
+			for offset := 1; offset < n; offset++ {
+				next := nat.NewPort(port.Proto(), strconv.Itoa(port.Int()+offset))
+				if inRange[next] == nil {
+					inRange[next] = make(map[int]bool)
+				}
+				inRange[next][i] = true
+				bindings[next][i].HostIp = b.HostIp
+			}
 		}
 		bindings[port] = binding
 	}
@@ -772,15 +1158,41 @@ func (container *Container) allocateNetwork() error {
 	container.NetworkSettings.Ports = bindings
 
 	container.NetworkSettings.Bridge = env.Get("Bridge")
+	container.NetworkSettings.Network = env.Get("Network")
 	container.NetworkSettings.IPAddress = env.Get("IP")
 	container.NetworkSettings.IPPrefixLen = env.GetInt("IPPrefixLen")
 	container.NetworkSettings.Gateway = env.Get("Gateway")
+	container.NetworkSettings.GlobalIPv6Address = env.Get("GlobalIPv6")
+	container.NetworkSettings.GlobalIPv6PrefixLen = env.GetInt("GlobalIPv6PrefixLen")
+	container.NetworkSettings.IPv6Gateway = env.Get("IPv6Gateway")
 
 	return nil
 }
 
+// portRangeLength returns the number of consecutive exposed ports from port
+// whose i-th bindings are on the same host IP and on consecutive host ports
+func portRangeLength(portSpecs nat.PortSet, bindings nat.PortMap, port nat.Port, i int) int {
+	b := bindings[port][i]
+	hostPort, err := nat.ParsePort(b.HostPort)
+	if err != nil || hostPort == 0 {
+		return 1
+	}
+	n := 1
+	for {
+		nextPort := nat.NewPort(port.Proto(), strconv.Itoa(port.Int()+n))
+		if _, exists := portSpecs[nextPort]; !exists {
+			return n
+		}
+		next := bindings[nextPort]
+		if len(next) <= i || next[i].HostIp != b.HostIp || next[i].HostPort != strconv.Itoa(hostPort+n) {
+			return n
+		}
+		n++
+	}
+}
+
 func (container *Container) releaseNetwork() {
-	if container.Config.NetworkDisabled {
+	if container.Config.NetworkDisabled || !container.hostConfig.NetworkMode.IsBridge() {
 		return
 	}
 	eng := container.runtime.eng
@@ -795,11 +1207,73 @@ func (container *Container) monitor(callback execdriver.StartCallback) error {
 		exitCode int
 	)
 
+	// started is only set once the process is running, a container which
+	// failed to start is not restarted by its restart policy
+	var started bool
+
 	pipes := execdriver.NewPipes(container.stdin, container.stdout, container.stderr, container.Config.OpenStdin)
-	exitCode, err = container.runtime.Run(container, pipes, callback)
+	exitCode, err = container.runtime.Run(container, pipes, func(command *execdriver.Command) {
+		started = true
+		callback(command)
+	})
 	if err != nil {
 		utils.Errorf("Error running container: %s", err)
 	}
+	container.exited(started, true, exitCode)
+	return err
+}
+
+// restore re-attaches the container to its process left running by a
+// previous daemon. The process is monitored until it exits as if it was
+// started by this daemon, its output written while no daemon was running
+// is kept, but its stdin is closed with the previous daemon. Its exit code
+// is unknown, the restart policy does not act on it.
+func (container *Container) restore() (err error) {
+	container.Lock()
+	defer container.Unlock()
+
+	defer func() {
+		if err != nil {
+			container.cleanup()
+		}
+	}()
+
+	if err := container.Mount(); err != nil {
+		return err
+	}
+	// the ghost gets the addresses and ports it had back
+	if !container.Config.NetworkDisabled && !container.runtime.config.DisableNetwork {
+		if err := container.allocateNetwork(); err != nil {
+			return err
+		}
+	}
+	if err := populateCommand(container); err != nil {
+		return err
+	}
+	container.State.SetGhost(false)
+	container.waitLock = make(chan struct{})
+	container.startHealthMonitor()
+
+	pipes := execdriver.NewPipes(container.stdin, container.stdout, container.stderr, container.Config.OpenStdin)
+	go func() {
+		exitCode, err := container.runtime.Restore(container, pipes)
+		if err != nil {
+			utils.Errorf("Error restoring container: %s", err)
+		}
+		container.exited(true, false, exitCode)
+	}()
+	return nil
+}
+
+// exited updates the state of the container once its process exited with
+// exitCode and restarts it according to its restart policy. started is false
+// when the process failed to start, known is false when exitCode is unknown.
+func (container *Container) exited(started, known bool, exitCode int) {
+	if started {
+		container.Lock()
+		container.stopHealthMonitor()
+		container.Unlock()
+	}
 
 	if container.runtime != nil && container.runtime.srv != nil && container.runtime.srv.IsRunning() {
 		container.State.SetStopped(exitCode)
@@ -824,17 +1298,84 @@ func (container *Container) monitor(callback execdriver.StartCallback) error {
 		container.stdin, container.stdinPipe = io.Pipe()
 	}
 
+	restart := started && known && container.shouldRestart(exitCode)
+
 	if container.runtime != nil && container.runtime.srv != nil {
 		container.runtime.srv.LogEvent("die", container.ID, container.runtime.repositories.ImageName(container.Image))
 	}
 
 	close(container.waitLock)
 
-	return err
+	if restart {
+		go container.autoRestart()
+	}
+}
+
+// shouldRestart returns true if the restart policy of the container
+// requires the process to be restarted after exiting with exitCode
+func (container *Container) shouldRestart(exitCode int) bool {
+	if container.runtime == nil || container.runtime.srv == nil || !container.runtime.srv.IsRunning() {
+		return false
+	}
+
+	container.Lock()
+	defer container.Unlock()
+
+	if container.manualStop || container.hostConfig == nil {
+		return false
+	}
+	policy := container.hostConfig.RestartPolicy
+	switch policy.Name {
+	case "always":
+		return true
+	case "on-failure":
+		if exitCode == 0 {
+			return false
+		}
+		return policy.MaximumRetryCount == 0 || container.RestartCount < policy.MaximumRetryCount
+	}
+	return false
+}
+
+// autoRestart waits for the back-off delay and starts the container again,
+// the delay doubles after each restart unless the container ran long enough
+func (container *Container) autoRestart() {
+	container.Lock()
+	if container.State.FinishedAt.Sub(container.State.StartedAt) > restartDelayResetTime {
+		container.restartDelay = 0
+	}
+	if container.restartDelay == 0 {
+		container.restartDelay = defaultRestartDelay
+	} else {
+		container.restartDelay *= 2
+		if container.restartDelay > maxRestartDelay {
+			container.restartDelay = maxRestartDelay
+		}
+	}
+	delay := container.restartDelay
+	container.Unlock()
+
+	utils.Debugf("Restarting container %s in %s", container.ID, delay)
+	time.Sleep(delay)
+
+	container.Lock()
+	if container.manualStop || container.State.IsRunning() {
+		container.Unlock()
+		return
+	}
+	container.RestartCount++
+	container.Unlock()
+
+	if err := container.start(false); err != nil {
+		utils.Errorf("Error restarting container %s: %s", container.ID, err)
+		return
+	}
+	container.runtime.srv.LogEvent("restart", container.ID, container.runtime.repositories.ImageName(container.Image))
 }
 
 func (container *Container) cleanup() {
 	container.releaseNetwork()
+	selinux.ReleaseLabel(container.ProcessLabel)
 
 	// Disable all active links
 	if container.activeLinks != nil {
@@ -853,6 +1394,12 @@ func (container *Container) cleanup() {
 	if err := container.stderr.CloseWriters(); err != nil {
 		utils.Errorf("%s: Error close stderr: %s", container.ID, err)
 	}
+	if container.logDriver != nil {
+		if err := container.logDriver.Close(); err != nil {
+			utils.Errorf("%s: Error closing log driver: %s", container.ID, err)
+		}
+		container.logDriver = nil
+	}
 	if container.command != nil && container.command.Terminal != nil {
 		if err := container.command.Terminal.Close(); err != nil {
 			utils.Errorf("%s: Error closing terminal: %s", container.ID, err)
@@ -871,10 +1418,120 @@ func (container *Container) KillSig(sig int) error {
 	if !container.State.IsRunning() {
 		return nil
 	}
+	// signals are not delivered to frozen processes
+	if container.State.IsPaused() {
+		return fmt.Errorf("Container %s is paused. Unpause the container before stopping", container.ID)
+	}
 	return container.runtime.Kill(container, sig)
 }
 
+// setManualStop prevents the restart policy from restarting the container
+func (container *Container) setManualStop() {
+	container.Lock()
+	container.manualStop = true
+	container.Unlock()
+}
+
+func (container *Container) Pause() error {
+	container.Lock()
+	defer container.Unlock()
+
+	if !container.State.IsRunning() {
+		return fmt.Errorf("Container %s is not running", container.ID)
+	}
+	if container.State.IsPaused() {
+		return fmt.Errorf("Container %s is already paused", container.ID)
+	}
+	if err := container.runtime.Pause(container); err != nil {
+		return err
+	}
+	container.State.SetPaused()
+	return container.ToDisk()
+}
+
+func (container *Container) Unpause() error {
+	container.Lock()
+	defer container.Unlock()
+
+	if !container.State.IsRunning() {
+		return fmt.Errorf("Container %s is not running", container.ID)
+	}
+	if !container.State.IsPaused() {
+		return fmt.Errorf("Container %s is not paused", container.ID)
+	}
+	if err := container.runtime.Unpause(container); err != nil {
+		return err
+	}
+	container.State.SetUnpaused()
+	return container.ToDisk()
+}
+
+// Update changes the memory and cpu limits of the container, a zero value keeps
+// the current limit. The limits of a running container are changed live, and the
+// new values are only saved once they were all applied.
+func (container *Container) Update(resources *execdriver.Resources) error {
+	container.Lock()
+	defer container.Unlock()
+
+	previous := execdriver.Resources{
+		Memory:     container.Config.Memory,
+		MemorySwap: container.Config.MemorySwap,
+		CpuShares:  container.Config.CpuShares,
+	}
+	running := container.State.IsRunning()
+	if running {
+		// the other limits of the running container are kept
+		previous = *container.command.Resources
+	}
+	updated := previous
+	if resources.Memory != 0 {
+		updated.Memory = resources.Memory
+	}
+	if resources.MemorySwap != 0 {
+		updated.MemorySwap = resources.MemorySwap
+	}
+	if resources.CpuShares != 0 {
+		updated.CpuShares = resources.CpuShares
+	}
+	if updated.MemorySwap > 0 && updated.MemorySwap < updated.Memory {
+		return fmt.Errorf("The memory + swap limit (%d) cannot be lower than the memory limit (%d)", updated.MemorySwap, updated.Memory)
+	}
+	// the limits which are not updated, such as the memory reservation,
+	// are validated against the new ones
+	config := *container.Config
+	config.Memory = updated.Memory
+	config.MemorySwap = updated.MemorySwap
+	config.CpuShares = updated.CpuShares
+	if err := runconfig.ValidateResources(&config); err != nil {
+		return err
+	}
+
+	if running {
+		if err := container.runtime.Update(container, &updated); err != nil {
+			return err
+		}
+		container.command.Resources = &updated
+	}
+	container.Config.Memory = updated.Memory
+	container.Config.MemorySwap = updated.MemorySwap
+	container.Config.CpuShares = updated.CpuShares
+	if err := container.ToDisk(); err != nil {
+		container.Config.Memory = previous.Memory
+		container.Config.MemorySwap = previous.MemorySwap
+		container.Config.CpuShares = previous.CpuShares
+		if running {
+			if err := container.runtime.Update(container, &previous); err != nil {
+				utils.Errorf("Error restoring the limits of %s: %s", container.ID, err)
+			}
+			container.command.Resources = &previous
+		}
+		return err
+	}
+	return nil
+}
+
 func (container *Container) Kill() error {
+	container.setManualStop()
 	if !container.State.IsRunning() {
 		return nil
 	}
@@ -900,6 +1557,7 @@ func (container *Container) Kill() error {
 }
 
 func (container *Container) Stop(seconds int) error {
+	container.setManualStop()
 	if !container.State.IsRunning() {
 		return nil
 	}
@@ -960,6 +1618,7 @@ func (container *Container) ExportRw() (archive.Archive, error) {
 		container.Unmount()
 		return nil, err
 	}
+	archive = container.unmapArchive(archive)
 	return utils.NewReadCloserWrapper(archive, func() error {
 		err := archive.Close()
 		container.Unmount()
@@ -977,6 +1636,7 @@ func (container *Container) Export() (archive.Archive, error) {
 		container.Unmount()
 		return nil, err
 	}
+	archive = container.unmapArchive(archive)
 	return utils.NewReadCloserWrapper(archive, func() error {
 		err := archive.Close()
 		container.Unmount()
@@ -984,6 +1644,12 @@ func (container *Container) Export() (archive.Archive, error) {
 	}), nil
 }
 
+// unmapArchive gives the files of an archive of the container back to the
+// ids they have in the container
+func (container *Container) unmapArchive(a archive.Archive) archive.Archive {
+	return graphdriver.UnmapArchive(a, container.runtime.uidMaps, container.runtime.gidMaps)
+}
+
 func (container *Container) WaitTimeout(timeout time.Duration) error {
 	done := make(chan bool)
 	go func() {
@@ -1022,10 +1688,77 @@ func (container *Container) logPath(name string) string {
 	return path.Join(container.root, fmt.Sprintf("%s-%s.log", container.ID, name))
 }
 
-func (container *Container) ReadLog(name string) (io.Reader, error) {
+func (container *Container) ReadLog(name string) (io.ReadCloser, error) {
 	return os.Open(container.logPath(name))
 }
 
+// LogConfig returns the log driver of the container and its options,
+// the default of the daemon unless the container selects its own driver
+func (container *Container) LogConfig() runconfig.LogConfig {
+	if container.hostConfig != nil && container.hostConfig.LogConfig.Type != "" {
+		return container.hostConfig.LogConfig
+	}
+	if container.runtime != nil && container.runtime.config != nil && container.runtime.config.LogConfig.Type != "" {
+		return container.runtime.config.LogConfig
+	}
+	return runconfig.LogConfig{Type: jsonfilelog.Name}
+}
+
+// Ulimits returns the ulimits of the processes of the container, the
+// defaults of the daemon with the ulimits of the container over them
+func (container *Container) Ulimits() []*ulimit.Ulimit {
+	var (
+		ulimits []*ulimit.Ulimit
+		names   = make(map[string]bool)
+	)
+	if container.hostConfig != nil {
+		for _, u := range container.hostConfig.Ulimits {
+			names[u.Name] = true
+			ulimits = append(ulimits, u)
+		}
+	}
+	if container.runtime != nil && container.runtime.config != nil {
+		for _, u := range container.runtime.config.Ulimits {
+			if !names[u.Name] {
+				ulimits = append(ulimits, u)
+			}
+		}
+	}
+	return ulimits
+}
+
+func (container *Container) logContext(config runconfig.LogConfig) *logger.Context {
+	return &logger.Context{
+		Config:        config.Config,
+		ContainerID:   container.ID,
+		ContainerName: container.Name,
+		LogPath:       container.logPath("json"),
+	}
+}
+
+// startLogging sends the output of the container to its log driver
+func (container *Container) startLogging() error {
+	config := container.LogConfig()
+	l, err := logger.New(config.Type, container.logContext(config))
+	if err != nil {
+		return fmt.Errorf("Failed to initialize the %s log driver: %s", config.Type, err)
+	}
+	if l == nil {
+		return nil
+	}
+	container.logDriver = l
+	container.stdout.AddWriter(logger.NewWriter(l, "stdout"), "")
+	container.stderr.AddWriter(logger.NewWriter(l, "stderr"), "")
+	return nil
+}
+
+// ReadLogs opens the json log files of the container, oldest first. It fails
+// when the log driver of the container cannot read its logs back.
+func (container *Container) ReadLogs() ([]*os.File, error) {
+	config := container.LogConfig()
+	return logger.ReadLogs(config.Type, container.logContext(config))
+}
+
 func (container *Container) hostConfigPath() string {
 	return path.Join(container.root, "hostconfig.json")
 }
@@ -1129,6 +1862,7 @@ func (container *Container) Copy(resource string) (io.ReadCloser, error) {
 	if err != nil {
 		return nil, err
 	}
+	archive = container.unmapArchive(archive)
 	return utils.NewReadCloserWrapper(archive, func() error {
 		err := archive.Close()
 		container.Unmount()
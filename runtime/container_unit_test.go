@@ -2,6 +2,8 @@ package runtime
 
 import (
 	"github.com/dotcloud/docker/nat"
+	"github.com/dotcloud/docker/runconfig"
+	"github.com/dotcloud/docker/runtime/execdriver"
 	"testing"
 )
 
@@ -143,3 +145,36 @@ func TestGetFullName(t *testing.T) {
 		t.Fatal("Error should not be nil")
 	}
 }
+
+func TestPortRangeLength(t *testing.T) {
+	ports, bindings, err := nat.ParsePortSpecs([]string{"10000-10002:20000-20002/udp", "127.0.0.1:10003:20003/udp", "20004/udp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := portRangeLength(ports, bindings, nat.NewPort("udp", "20000"), 0); n != 3 {
+		t.Fatalf("Expected a range of 3 ports from 20000/udp, got %d", n)
+	}
+	if n := portRangeLength(ports, bindings, nat.NewPort("udp", "20001"), 0); n != 2 {
+		t.Fatalf("Expected a range of 2 ports from 20001/udp, got %d", n)
+	}
+	// on another host IP
+	if n := portRangeLength(ports, bindings, nat.NewPort("udp", "20003"), 0); n != 1 {
+		t.Fatalf("Expected a single port from 20003/udp, got %d", n)
+	}
+	// on a dynamic host port
+	if n := portRangeLength(ports, bindings, nat.NewPort("udp", "20004"), 0); n != 1 {
+		t.Fatalf("Expected a single port from 20004/udp, got %d", n)
+	}
+}
+
+func TestUpdateValidatesReservation(t *testing.T) {
+	container := &Container{
+		Config: &runconfig.Config{Memory: 64 * 1024 * 1024, MemoryReservation: 32 * 1024 * 1024},
+	}
+	if err := container.Update(&execdriver.Resources{Memory: 16 * 1024 * 1024}); err == nil {
+		t.Fatal("Expected an error for a memory limit lower than the memory reservation")
+	}
+	if container.Config.Memory != 64*1024*1024 {
+		t.Fatalf("Expected the memory limit to be kept, got %d", container.Config.Memory)
+	}
+}
@@ -0,0 +1,230 @@
+package runtime
+
+import (
+	"fmt"
+	"github.com/dotcloud/docker/runconfig"
+	"github.com/dotcloud/docker/runtime/execdriver"
+	"github.com/dotcloud/docker/utils"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// execPruneTimeout is how long a process which is not running is kept when
+// its exit code is not read
+var execPruneTimeout = 5 * time.Minute
+
+// ExecConfig is a process executed inside an already running container
+type ExecConfig struct {
+	sync.Mutex
+	ID            string
+	Running       bool
+	ExitCode      int
+	ProcessConfig execdriver.ProcessConfig
+	OpenStdin     bool
+	OpenStderr    bool
+	OpenStdout    bool
+	Container     *Container
+	pid           int       // pid of the running process, the leader of its process group
+	exited        bool      // the process ran and exited with ExitCode
+	idleSince     time.Time // when the process was created or exited
+}
+
+type execStore struct {
+	s map[string]*ExecConfig
+	sync.Mutex
+}
+
+func newExecStore() *execStore {
+	return &execStore{s: make(map[string]*ExecConfig)}
+}
+
+func (e *execStore) Add(id string, execConfig *ExecConfig) {
+	e.Lock()
+	e.s[id] = execConfig
+	e.Unlock()
+}
+
+func (e *execStore) Get(id string) *ExecConfig {
+	e.Lock()
+	res := e.s[id]
+	e.Unlock()
+	return res
+}
+
+// prune removes the process once it has not been running for the prune
+// timeout, it is kept when it was started again since
+func (e *execStore) prune(execConfig *ExecConfig) {
+	execConfig.Lock()
+	idle := !execConfig.Running && time.Since(execConfig.idleSince) >= execPruneTimeout
+	execConfig.Unlock()
+	if idle {
+		e.remove(execConfig)
+	}
+}
+
+// remove removes the process, unless another one was added with its id
+func (e *execStore) remove(execConfig *ExecConfig) {
+	e.Lock()
+	if e.s[execConfig.ID] == execConfig {
+		delete(e.s, execConfig.ID)
+	}
+	e.Unlock()
+}
+
+// pruneLater prunes the process after the prune timeout
+func (e *execStore) pruneLater(execConfig *ExecConfig) {
+	time.AfterFunc(execPruneTimeout, func() {
+		e.prune(execConfig)
+	})
+}
+
+// DeleteContainer removes the processes executed inside the container
+func (e *execStore) DeleteContainer(container *Container) {
+	e.Lock()
+	for id, execConfig := range e.s {
+		if execConfig.Container == container {
+			delete(e.s, id)
+		}
+	}
+	e.Unlock()
+}
+
+// ExecCreate registers a new process to be executed inside the running container
+func (runtime *Runtime) ExecCreate(container *Container, config *runconfig.ExecConfig) (*ExecConfig, error) {
+	execConfig, err := newExecConfig(container, config)
+	if err != nil {
+		return nil, err
+	}
+	runtime.execCommands.Add(execConfig.ID, execConfig)
+	runtime.execCommands.pruneLater(execConfig)
+	return execConfig, nil
+}
+
+func newExecConfig(container *Container, config *runconfig.ExecConfig) (*ExecConfig, error) {
+	if !container.State.IsRunning() || container.command == nil {
+		return nil, fmt.Errorf("Container %s is not running", container.ID)
+	}
+	if container.State.IsPaused() {
+		return nil, fmt.Errorf("Container %s is paused, unpause the container before exec", container.ID)
+	}
+	if len(config.Cmd) == 0 {
+		return nil, fmt.Errorf("No command specified")
+	}
+
+	// the new process inherits the environment of the container
+	// with the user provided values taking precedence
+	env := make([]string, len(container.command.Env))
+	copy(env, container.command.Env)
+	if config.Tty {
+		env = utils.ReplaceOrAppendEnvValues(env, []string{"TERM=xterm"})
+	}
+	env = utils.ReplaceOrAppendEnvValues(env, config.Env)
+
+	execConfig := &ExecConfig{
+		ID: utils.GenerateRandomID(),
+		ProcessConfig: execdriver.ProcessConfig{
+			Privileged: config.Privileged,
+			User:       config.User,
+			Tty:        config.Tty,
+			Entrypoint: config.Cmd[0],
+			Arguments:  config.Cmd[1:],
+			WorkingDir: config.WorkingDir,
+			Env:        env,
+		},
+		OpenStdin:  config.AttachStdin,
+		OpenStdout: config.AttachStdout,
+		OpenStderr: config.AttachStderr,
+		Container:  container,
+		idleSince:  time.Now(),
+	}
+	return execConfig, nil
+}
+
+// GetExecConfig returns the process registered with the given id
+func (runtime *Runtime) GetExecConfig(id string) (*ExecConfig, error) {
+	execConfig := runtime.execCommands.Get(id)
+	if execConfig == nil {
+		return nil, fmt.Errorf("No such exec instance: %s", id)
+	}
+	return execConfig, nil
+}
+
+// Exec runs the process inside its container and blocks until it exits
+func (runtime *Runtime) Exec(execConfig *ExecConfig, pipes *execdriver.Pipes) (int, error) {
+	execConfig.Lock()
+	if execConfig.Running {
+		execConfig.Unlock()
+		return -1, fmt.Errorf("Exec %s is already running", execConfig.ID)
+	}
+	execConfig.Running = true
+	execConfig.Unlock()
+
+	container := execConfig.Container
+	if !container.State.IsRunning() || container.command == nil {
+		execConfig.Lock()
+		execConfig.Running = false
+		execConfig.Unlock()
+		return -1, fmt.Errorf("Container %s is not running", container.ID)
+	}
+
+	// the driver sets up its own copy of the process, the terminal is set
+	// under the lock once the process started, for Resize
+	execConfig.Lock()
+	processConfig := execConfig.ProcessConfig
+	execConfig.Unlock()
+	exitCode, err := runtime.execDriver.Exec(container.command, &processConfig, pipes, func(processConfig *execdriver.ProcessConfig) {
+		execConfig.Lock()
+		execConfig.pid = processConfig.Process.Pid
+		execConfig.ProcessConfig.Terminal = processConfig.Terminal
+		execConfig.Unlock()
+	})
+
+	execConfig.Lock()
+	execConfig.pid = 0
+	execConfig.ProcessConfig.Terminal = nil
+	execConfig.Running = false
+	execConfig.ExitCode = exitCode
+	execConfig.exited = true
+	execConfig.idleSince = time.Now()
+	execConfig.Unlock()
+	runtime.execCommands.pruneLater(execConfig)
+
+	return exitCode, err
+}
+
+// ExecExitCodeRead removes the process once its exit code was read, the
+// process is kept while it did not run yet or is running
+func (runtime *Runtime) ExecExitCodeRead(execConfig *ExecConfig) {
+	execConfig.Lock()
+	exited := execConfig.exited && !execConfig.Running
+	execConfig.Unlock()
+	if exited {
+		runtime.execCommands.remove(execConfig)
+	}
+}
+
+// kill kills the running process and the processes of its process group
+func (execConfig *ExecConfig) kill() error {
+	execConfig.Lock()
+	defer execConfig.Unlock()
+	if execConfig.pid == 0 {
+		return fmt.Errorf("Exec %s is not running", execConfig.ID)
+	}
+	err := syscall.Kill(-execConfig.pid, syscall.SIGKILL)
+	if err == syscall.ESRCH {
+		// the process did not create its process group yet
+		err = syscall.Kill(execConfig.pid, syscall.SIGKILL)
+	}
+	return err
+}
+
+// Resize changes the size of the tty of the running process
+func (execConfig *ExecConfig) Resize(h, w int) error {
+	execConfig.Lock()
+	defer execConfig.Unlock()
+	if execConfig.ProcessConfig.Terminal == nil {
+		return fmt.Errorf("Exec %s is not running", execConfig.ID)
+	}
+	return execConfig.ProcessConfig.Terminal.Resize(h, w)
+}
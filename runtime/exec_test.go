@@ -0,0 +1,34 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExecStorePrune(t *testing.T) {
+	runtime := &Runtime{execCommands: newExecStore()}
+	idle := &ExecConfig{ID: "idle", idleSince: time.Now().Add(-execPruneTimeout)}
+	running := &ExecConfig{ID: "running", Running: true, idleSince: time.Now().Add(-execPruneTimeout)}
+	recent := &ExecConfig{ID: "recent", idleSince: time.Now()}
+	for _, execConfig := range []*ExecConfig{idle, running, recent} {
+		runtime.execCommands.Add(execConfig.ID, execConfig)
+		runtime.execCommands.prune(execConfig)
+	}
+	if runtime.execCommands.Get("idle") != nil {
+		t.Fatal("Expected the idle exec to be pruned")
+	}
+	if runtime.execCommands.Get("running") == nil || runtime.execCommands.Get("recent") == nil {
+		t.Fatal("Expected the running and recent execs to be kept")
+	}
+
+	// the exit code of a process which did not run yet is not read
+	runtime.ExecExitCodeRead(recent)
+	if runtime.execCommands.Get("recent") == nil {
+		t.Fatal("Expected the exec which did not run to be kept")
+	}
+	recent.exited = true
+	runtime.ExecExitCodeRead(recent)
+	if runtime.execCommands.Get("recent") != nil {
+		t.Fatal("Expected the exec to be removed once its exit code is read")
+	}
+}
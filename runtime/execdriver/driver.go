@@ -2,6 +2,9 @@ package execdriver
 
 import (
 	"errors"
+	"github.com/dotcloud/docker/pkg/cgroups"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/pkg/ulimit"
 	"io"
 	"os"
 	"os/exec"
@@ -17,8 +20,9 @@ var (
 var dockerInitFcts map[string]InitFunc
 
 type (
-	StartCallback func(*Command)
-	InitFunc      func(i *InitArgs) error
+	StartCallback     func(*Command)
+	ExecStartCallback func(*ProcessConfig) // called once the process of an exec started
+	InitFunc          func(i *InitArgs) error
 )
 
 func RegisterInitFunc(name string, fct InitFunc) error {
@@ -47,6 +51,10 @@ type InitArgs struct {
 	Ip         string
 	WorkDir    string
 	Privileged bool
+	CapAdd     []string
+	CapDrop    []string
+	Readonly   bool
+	Ulimits    []string
 	Env        []string
 	Args       []string
 	Mtu        int
@@ -54,6 +62,9 @@ type InitArgs struct {
 	Console    string
 	Pipe       int
 	Root       string
+
+	IPv6        string // in CIDR notation
+	IPv6Gateway string
 }
 
 // Driver specific information based on
@@ -80,12 +91,29 @@ type Driver interface {
 	Name() string                                 // Driver name
 	Info(id string) Info                          // "temporary" hack (until we move state from core to plugins)
 	GetPidsForContainer(id string) ([]int, error) // Returns a list of pids for the given container.
+	// Exec executes an additional process inside the already running container c,
+	// blocks until the process exits and returns its exit code. The process is
+	// the leader of its own process group.
+	Exec(c *Command, processConfig *ProcessConfig, pipes *Pipes, startCallback ExecStartCallback) (int, error)
+	Pause(c *Command) error                  // Freezes all the processes of the container
+	Unpause(c *Command) error                // Thaws all the processes of the container
+	Stats(id string) (*cgroups.Stats, error) // Returns the cgroups resource usage of the container
+	// Update applies the resource limits to the running container c, either all
+	// the limits are changed or none of them
+	Update(c *Command, resources *Resources) error
+	// Restore re-attaches to the process of the container c left running by a
+	// previous daemon, copies its output into the pipes until the process
+	// exits and returns its exit code, -1 when it is unknown
+	Restore(c *Command, pipes *Pipes) (int, error)
 }
 
 // Network settings of the container
 type Network struct {
 	Interface *NetworkInterface `json:"interface"` // if interface is nil then networking is disabled
 	Mtu       int               `json:"mtu"`
+
+	HostNetworking bool   `json:"host_networking"` // the container uses the network stack of the host
+	ContainerID    string `json:"container_id"`    // the container joins the network namespace of this container
 }
 
 type NetworkInterface struct {
@@ -93,12 +121,33 @@ type NetworkInterface struct {
 	IPAddress   string `json:"ip"`
 	Bridge      string `json:"bridge"`
 	IPPrefixLen int    `json:"ip_prefix_len"`
+
+	// empty when the container has no IPv6 address
+	IPv6Address   string `json:"ipv6"`
+	IPv6PrefixLen int    `json:"ipv6_prefix_len"`
+	IPv6Gateway   string `json:"ipv6_gateway"`
 }
 
 type Resources struct {
 	Memory     int64 `json:"memory"`
 	MemorySwap int64 `json:"memory_swap"`
 	CpuShares  int64 `json:"cpu_shares"`
+
+	MemoryReservation int64  `json:"memory_reservation"`
+	KernelMemory      int64  `json:"kernel_memory"`
+	CpuPeriod         int64  `json:"cpu_period"`
+	CpuQuota          int64  `json:"cpu_quota"`
+	CpusetCpus        string `json:"cpuset_cpus"`
+	CpusetMems        string `json:"cpuset_mems"`
+	BlkioWeight       int64  `json:"blkio_weight"`
+	PidsLimit         int64  `json:"pids_limit"`
+
+	BlkioThrottleReadBpsDevice   []*cgroups.ThrottleDevice `json:"blkio_throttle_read_bps_device"`
+	BlkioThrottleWriteBpsDevice  []*cgroups.ThrottleDevice `json:"blkio_throttle_write_bps_device"`
+	BlkioThrottleReadIOpsDevice  []*cgroups.ThrottleDevice `json:"blkio_throttle_read_iops_device"`
+	BlkioThrottleWriteIOpsDevice []*cgroups.ThrottleDevice `json:"blkio_throttle_write_iops_device"`
+
+	Ulimits []*ulimit.Ulimit `json:"ulimits"` // resource limits set with setrlimit before executing the process
 }
 
 type Mount struct {
@@ -108,6 +157,30 @@ type Mount struct {
 	Private     bool   `json:"private"`
 }
 
+// Device is a device node of the host given to the container
+type Device struct {
+	PathOnHost        string `json:"path_on_host"`
+	PathInContainer   string `json:"path_in_container"`
+	CgroupPermissions string `json:"cgroup_permissions"` // any of r, w and m
+}
+
+// ProcessConfig describes an additional process that is executed
+// inside an already running container
+type ProcessConfig struct {
+	exec.Cmd `json:"-"`
+
+	Privileged bool     `json:"privileged"`
+	User       string   `json:"user"`
+	Tty        bool     `json:"tty"`
+	Entrypoint string   `json:"entrypoint"`
+	Arguments  []string `json:"arguments"`
+	WorkingDir string   `json:"working_dir"`
+	Env        []string `json:"env"`
+
+	Terminal Terminal `json:"-"` // standard or tty terminal
+	Console  string   `json:"-"` // dev/console path
+}
+
 // Process wrapps an os/exec.Cmd to add more metadata
 type Command struct {
 	exec.Cmd `json:"-"`
@@ -126,10 +199,28 @@ type Command struct {
 	Config     []string   `json:"config"` //  generic values that specific drivers can consume
 	Resources  *Resources `json:"resources"`
 	Mounts     []Mount    `json:"mounts"`
+	CapAdd     []string   `json:"cap_add"`  // capabilities kept in the container
+	CapDrop    []string   `json:"cap_drop"` // capabilities dropped from the container
+	Devices    []Device   `json:"devices"`  // devices of the host given to the container
+
+	ReadonlyRootfs bool              `json:"readonly_rootfs"`
+	Tmpfs          map[string]string `json:"tmpfs"`           // tmpfs mounted in the container, by path, with their mount options
+	SeccompProfile string            `json:"seccomp_profile"` // file of the seccomp profile, the default profile when empty or unconfined
+
+	UidMappings []libcontainer.IdMap `json:"uid_mappings"` // uids of the host the container runs as in a user namespace, none without user namespace
+	GidMappings []libcontainer.IdMap `json:"gid_mappings"` // gids of the host the container runs as in a user namespace
+
+	AppArmorProfile string `json:"apparmor_profile"` // AppArmor profile of the processes, the default profile when empty
+	ProcessLabel    string `json:"process_label"`    // SELinux label of the processes, none when empty
+	MountLabel      string `json:"mount_label"`      // SELinux label of the files of the mounts
 
 	Terminal     Terminal `json:"-"`             // standard or tty terminal
 	Console      string   `json:"-"`             // dev/console path
 	ContainerPid int      `json:"container_pid"` // the pid for the process inside a container
+
+	// HostInterface is the host end of the veth of the container once it is
+	// started, empty when the driver does not report it
+	HostInterface string `json:"host_interface"`
 }
 
 // Return the pid of the process
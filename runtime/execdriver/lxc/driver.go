@@ -5,6 +5,7 @@ import (
 	"github.com/dotcloud/docker/pkg/cgroups"
 	"github.com/dotcloud/docker/runtime/execdriver"
 	"github.com/dotcloud/docker/utils"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
@@ -17,7 +18,13 @@ import (
 	"time"
 )
 
-const DriverName = "lxc"
+const (
+	DriverName = "lxc"
+
+	// execInitName is the name of the init function used to execute
+	// additional processes inside a running container
+	execInitName = "lxc-exec"
+)
 
 func init() {
 	execdriver.RegisterInitFunc(DriverName, func(args *execdriver.InitArgs) error {
@@ -33,6 +40,14 @@ func init() {
 			return err
 		}
 
+		if err := setupReadonlyRootfs(args); err != nil {
+			return err
+		}
+
+		if err := setupRlimits(args); err != nil {
+			return err
+		}
+
 		if err := setupCapabilities(args); err != nil {
 			return err
 		}
@@ -44,19 +59,43 @@ func init() {
 		if err := changeUser(args); err != nil {
 			return err
 		}
+		return execProgram(args)
+	})
 
-		path, err := exec.LookPath(args.Args[0])
-		if err != nil {
-			log.Printf("Unable to locate %v", args.Args[0])
-			os.Exit(127)
+	// the exec init function is run by lxc-attach inside an already running
+	// container, the environment is inherited from the lxc-attach process
+	execdriver.RegisterInitFunc(execInitName, func(args *execdriver.InitArgs) error {
+		if err := setupRlimits(args); err != nil {
+			return err
 		}
-		if err := syscall.Exec(path, args.Args, os.Environ()); err != nil {
-			return fmt.Errorf("dockerinit unable to execute %s - %s", path, err)
+
+		if err := setupCapabilities(args); err != nil {
+			return err
+		}
+
+		if err := setupWorkingDirectory(args); err != nil {
+			return err
 		}
-		panic("Unreachable")
+
+		if err := changeUser(args); err != nil {
+			return err
+		}
+		return execProgram(args)
 	})
 }
 
+func execProgram(args *execdriver.InitArgs) error {
+	path, err := exec.LookPath(args.Args[0])
+	if err != nil {
+		log.Printf("Unable to locate %v", args.Args[0])
+		os.Exit(127)
+	}
+	if err := syscall.Exec(path, args.Args, os.Environ()); err != nil {
+		return fmt.Errorf("dockerinit unable to execute %s - %s", path, err)
+	}
+	panic("Unreachable")
+}
+
 type driver struct {
 	root       string // root path for the driver to use
 	apparmor   bool
@@ -81,6 +120,15 @@ func (d *driver) Name() string {
 }
 
 func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallback execdriver.StartCallback) (int, error) {
+	if c.SeccompProfile != "" && c.SeccompProfile != "unconfined" {
+		return -1, fmt.Errorf("The seccomp profiles are only supported by the native driver")
+	}
+	if c.UidMappings != nil {
+		return -1, fmt.Errorf("The user namespaces are only supported by the native driver")
+	}
+	if c.Network.ContainerID != "" {
+		return -1, fmt.Errorf("Joining the network of another container is only supported by the native driver")
+	}
 	if err := execdriver.SetTerminal(c, pipes); err != nil {
 		return -1, err
 	}
@@ -103,6 +151,12 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 			"-g", c.Network.Interface.Gateway,
 			"-i", fmt.Sprintf("%s/%d", c.Network.Interface.IPAddress, c.Network.Interface.IPPrefixLen),
 		)
+		if c.Network.Interface.IPv6Address != "" {
+			params = append(params,
+				"-gateway6", c.Network.Interface.IPv6Gateway,
+				"-ipv6", fmt.Sprintf("%s/%d", c.Network.Interface.IPv6Address, c.Network.Interface.IPv6PrefixLen),
+			)
+		}
 	}
 	params = append(params,
 		"-mtu", strconv.Itoa(c.Network.Mtu),
@@ -119,6 +173,12 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 		}
 		params = append(params, "-privileged")
 	}
+	params = append(params, capabilitiesParams(c)...)
+	params = append(params, ulimitsParams(c)...)
+
+	if c.ReadonlyRootfs {
+		params = append(params, "-readonly")
+	}
 
 	if c.WorkingDir != "" {
 		params = append(params, "-w", c.WorkingDir)
@@ -190,6 +250,73 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 	return getExitCode(c), waitErr
 }
 
+func (d *driver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessConfig, pipes *execdriver.Pipes, startCallback execdriver.ExecStartCallback) (int, error) {
+	if !d.Info(c.ID).IsRunning() {
+		return -1, fmt.Errorf("Container %s is not running", c.ID)
+	}
+	processConfig.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := execdriver.SetProcessTerminal(processConfig, pipes); err != nil {
+		return -1, err
+	}
+	defer processConfig.Terminal.Close()
+
+	params := execParams(c, processConfig)
+	path, err := exec.LookPath(params[0])
+	if err != nil {
+		return -1, err
+	}
+	processConfig.Path = path
+	processConfig.Args = params
+	processConfig.Cmd.Env = processConfig.Env
+
+	if err := processConfig.Start(); err != nil {
+		return -1, err
+	}
+	if startCallback != nil {
+		startCallback(processConfig)
+	}
+	if processConfig.Tty {
+		// the slave pty is now owned by the attached process
+		if c, ok := processConfig.Stdout.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	if err := processConfig.Wait(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return -1, err
+		}
+	}
+	return processConfig.ProcessState.Sys().(syscall.WaitStatus).ExitStatus(), nil
+}
+
+// execParams returns the command attaching the process of processConfig
+// to the running container c through the exec init function of dockerinit
+func execParams(c *execdriver.Command, processConfig *execdriver.ProcessConfig) []string {
+	params := []string{
+		"lxc-attach",
+		"-n", c.ID,
+		"--keep-env",
+		"--",
+		c.InitPath,
+		"-driver",
+		execInitName,
+	}
+	if processConfig.User != "" {
+		params = append(params, "-u", processConfig.User)
+	}
+	if processConfig.Privileged {
+		params = append(params, "-privileged")
+	}
+	params = append(params, capabilitiesParams(c)...)
+	// the executed processes have the ulimits of the container as well
+	params = append(params, ulimitsParams(c)...)
+	if processConfig.WorkingDir != "" {
+		params = append(params, "-w", processConfig.WorkingDir)
+	}
+	params = append(params, "--", processConfig.Entrypoint)
+	return append(params, processConfig.Arguments...)
+}
+
 /// Return the exit code of the process
 // if the process has not exited -1 will be returned
 func getExitCode(c *execdriver.Command) int {
@@ -203,6 +330,44 @@ func (d *driver) Kill(c *execdriver.Command, sig int) error {
 	return KillLxc(c.ID, sig)
 }
 
+func (d *driver) Pause(c *execdriver.Command) error {
+	return freezeLxc("lxc-freeze", c.ID)
+}
+
+func (d *driver) Unpause(c *execdriver.Command) error {
+	return freezeLxc("lxc-unfreeze", c.ID)
+}
+
+func (d *driver) Stats(id string) (*cgroups.Stats, error) {
+	return cgroups.GetStats(id, "lxc")
+}
+
+func (d *driver) Update(c *execdriver.Command, resources *execdriver.Resources) error {
+	cgroup := &cgroups.Cgroup{
+		Name:       c.ID,
+		Parent:     "lxc",
+		Memory:     resources.Memory,
+		MemorySwap: resources.MemorySwap,
+		CpuShares:  resources.CpuShares,
+
+		MemoryReservation: resources.MemoryReservation,
+	}
+	return cgroup.Update()
+}
+
+// Restore is not supported, the ghosts of the lxc containers are
+// killed when the daemon starts
+func (d *driver) Restore(c *execdriver.Command, pipes *execdriver.Pipes) (int, error) {
+	return -1, fmt.Errorf("Restore is not supported by the lxc driver")
+}
+
+func freezeLxc(name, id string) error {
+	if output, err := exec.Command(name, "-n", id).CombinedOutput(); err != nil {
+		return fmt.Errorf("Err: %s Output: %s", err, output)
+	}
+	return nil
+}
+
 func (d *driver) version() string {
 	var (
 		version string
@@ -396,3 +561,29 @@ func (d *driver) generateLXCConfig(c *execdriver.Command) (string, error) {
 	}
 	return root, nil
 }
+
+// capabilitiesParams returns the arguments of dockerinit which
+// select the capabilities of the container
+func capabilitiesParams(c *execdriver.Command) []string {
+	var params []string
+	if len(c.CapAdd) > 0 {
+		params = append(params, "-cap-add", strings.Join(c.CapAdd, ","))
+	}
+	if len(c.CapDrop) > 0 {
+		params = append(params, "-cap-drop", strings.Join(c.CapDrop, ","))
+	}
+	return params
+}
+
+// ulimitsParams returns the arguments of dockerinit which
+// set the ulimits of the container
+func ulimitsParams(c *execdriver.Command) []string {
+	if c.Resources == nil || len(c.Resources.Ulimits) == 0 {
+		return nil
+	}
+	var ulimits []string
+	for _, u := range c.Resources.Ulimits {
+		ulimits = append(ulimits, u.String())
+	}
+	return []string{"-ulimit", strings.Join(ulimits, ",")}
+}
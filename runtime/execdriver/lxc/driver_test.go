@@ -0,0 +1,25 @@
+package lxc
+
+import (
+	"github.com/dotcloud/docker/pkg/ulimit"
+	"github.com/dotcloud/docker/runtime/execdriver"
+	"strings"
+	"testing"
+)
+
+func TestExecParamsUlimits(t *testing.T) {
+	c := &execdriver.Command{
+		ID:       "1",
+		InitPath: "/.dockerinit",
+		Resources: &execdriver.Resources{
+			Ulimits: []*ulimit.Ulimit{{Name: "nofile", Soft: 512, Hard: 1024}, {Name: "core", Soft: -1, Hard: -1}},
+		},
+	}
+	processConfig := &execdriver.ProcessConfig{Entrypoint: "ls", Arguments: []string{"-l"}}
+
+	params := strings.Join(execParams(c, processConfig), " ")
+	// the ulimits are dockerinit arguments, before the command of the process
+	if expected := "-driver lxc-exec -ulimit nofile=512:1024,core=unlimited:unlimited -- ls -l"; !strings.HasSuffix(params, expected) {
+		t.Fatalf("Expected the params to end with %q, received %q", expected, params)
+	}
+}
@@ -3,7 +3,9 @@ package lxc
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/dotcloud/docker/pkg/libcontainer"
 	"github.com/dotcloud/docker/pkg/netlink"
+	"github.com/dotcloud/docker/pkg/ulimit"
 	"github.com/dotcloud/docker/pkg/user"
 	"github.com/dotcloud/docker/runtime/execdriver"
 	"github.com/syndtr/gocapability/capability"
@@ -65,6 +67,15 @@ func setupNetworking(args *execdriver.InitArgs) error {
 		if err := netlink.NetworkLinkAddIp(iface, ip, ipNet); err != nil {
 			return fmt.Errorf("Unable to set up networking: %v", err)
 		}
+		if args.IPv6 != "" {
+			ip, ipNet, err := net.ParseCIDR(args.IPv6)
+			if err != nil {
+				return fmt.Errorf("Unable to set up networking: %v", err)
+			}
+			if err := netlink.NetworkLinkAddIp(iface, ip, ipNet); err != nil {
+				return fmt.Errorf("Unable to set up networking: %v", err)
+			}
+		}
 		if err := netlink.NetworkSetMTU(iface, args.Mtu); err != nil {
 			return fmt.Errorf("Unable to set MTU: %v", err)
 		}
@@ -91,6 +102,16 @@ func setupNetworking(args *execdriver.InitArgs) error {
 			return fmt.Errorf("Unable to set up networking: %v", err)
 		}
 	}
+	if args.IPv6Gateway != "" {
+		gw := net.ParseIP(args.IPv6Gateway)
+		if gw == nil {
+			return fmt.Errorf("Unable to set up networking, %s is not a valid IPv6 gateway IP", args.IPv6Gateway)
+		}
+
+		if err := netlink.AddDefaultGw(gw); err != nil {
+			return fmt.Errorf("Unable to set up networking: %v", err)
+		}
+	}
 
 	return nil
 }
@@ -129,27 +150,48 @@ func changeUser(args *execdriver.InitArgs) error {
 	return nil
 }
 
+// Remount the rootfs readonly, the mounts on top of it stay writable
+func setupReadonlyRootfs(args *execdriver.InitArgs) error {
+	if !args.Readonly {
+		return nil
+	}
+	if err := remountReadonly("/"); err != nil {
+		return fmt.Errorf("Unable to mount the rootfs readonly: %v", err)
+	}
+	return nil
+}
+
+// Set the ulimits, before the capabilities are dropped
+// so that the hard limits can be raised
+func setupRlimits(args *execdriver.InitArgs) error {
+	for _, val := range args.Ulimits {
+		u, err := ulimit.Parse(val)
+		if err != nil {
+			return err
+		}
+		rlimit, err := u.GetRlimit()
+		if err != nil {
+			return err
+		}
+		if err := syscall.Setrlimit(rlimit.Type, &syscall.Rlimit{Max: rlimit.Hard, Cur: rlimit.Soft}); err != nil {
+			return fmt.Errorf("Unable to set the ulimit %s: %v", val, err)
+		}
+	}
+	return nil
+}
+
 func setupCapabilities(args *execdriver.InitArgs) error {
 	if args.Privileged {
 		return nil
 	}
 
-	drop := []capability.Cap{
-		capability.CAP_SETPCAP,
-		capability.CAP_SYS_MODULE,
-		capability.CAP_SYS_RAWIO,
-		capability.CAP_SYS_PACCT,
-		capability.CAP_SYS_ADMIN,
-		capability.CAP_SYS_NICE,
-		capability.CAP_SYS_RESOURCE,
-		capability.CAP_SYS_TIME,
-		capability.CAP_SYS_TTY_CONFIG,
-		capability.CAP_MKNOD,
-		capability.CAP_AUDIT_WRITE,
-		capability.CAP_AUDIT_CONTROL,
-		capability.CAP_MAC_OVERRIDE,
-		capability.CAP_MAC_ADMIN,
-		capability.CAP_NET_ADMIN,
+	mask, err := execdriver.TweakCapabilities(execdriver.DefaultCapabilityMask, args.CapAdd, args.CapDrop)
+	if err != nil {
+		return err
+	}
+	drop := []capability.Cap{}
+	for _, name := range mask {
+		drop = append(drop, libcontainer.GetCapability(name).Value)
 	}
 
 	c, err := capability.NewPid(os.Getpid())
@@ -9,3 +9,7 @@ import (
 func setHostname(hostname string) error {
 	return syscall.Sethostname([]byte(hostname))
 }
+
+func remountReadonly(path string) error {
+	return syscall.Mount(path, path, "bind", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY, "")
+}
@@ -5,3 +5,7 @@ package lxc
 func setHostname(hostname string) error {
 	panic("Not supported on darwin")
 }
+
+func remountReadonly(path string) error {
+	panic("Not supported on darwin")
+}
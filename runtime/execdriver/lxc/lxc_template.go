@@ -1,13 +1,17 @@
 package lxc
 
 import (
+	"github.com/dotcloud/docker/pkg/libcontainer"
 	"github.com/dotcloud/docker/runtime/execdriver"
 	"strings"
 	"text/template"
 )
 
 const LxcTemplate = `
-{{if .Network.Interface}}
+{{if .Network.HostNetworking}}
+# network of the host (--net host)
+lxc.network.type = none
+{{else if .Network.Interface}}
 # network configuration
 lxc.network.type = veth
 lxc.network.link = {{.Network.Interface.Bridge}}
@@ -17,7 +21,9 @@ lxc.network.name = eth0
 lxc.network.type = empty
 lxc.network.flags = up
 {{end}}
+{{if not .Network.HostNetworking}}
 lxc.network.mtu = {{.Network.Mtu}}
+{{end}}
 
 # root filesystem
 {{$ROOTFS := .Rootfs}}
@@ -65,6 +71,11 @@ lxc.cgroup.devices.allow = c 10:200 rwm
 
 # rtc
 #lxc.cgroup.devices.allow = c 254:0 rwm
+
+# devices given to the container (--device)
+{{range $device := .Devices}}
+lxc.cgroup.devices.allow = {{deviceCgroup $device}}
+{{end}}
 {{end}}
 
 # standard mount point
@@ -96,26 +107,83 @@ lxc.mount.entry = {{$value.Source}} {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabS
 {{end}}
 {{end}}
 
+{{range $path, $options := .Tmpfs}}
+lxc.mount.entry = tmpfs {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $path}} tmpfs {{$options}},create=dir 0 0
+{{end}}
+
+{{range $device := .Devices}}
+lxc.mount.entry = {{escapeFstabSpaces $device.PathOnHost}} {{escapeFstabSpaces $ROOTFS}}/{{escapeFstabSpaces $device.PathInContainer}} none bind,create=file 0 0
+{{end}}
+
 {{if .Privileged}}
 {{if .AppArmor}}
 lxc.aa_profile = unconfined
 {{else}}
 #lxc.aa_profile = unconfined
 {{end}}
+{{else}}
+{{if .AppArmorProfile}}
+{{if .AppArmor}}
+lxc.aa_profile = {{.AppArmorProfile}}
+{{else}}
+#lxc.aa_profile = {{.AppArmorProfile}}
+{{end}}
+{{end}}
+{{if .ProcessLabel}}
+lxc.se_context = {{.ProcessLabel}}
+{{end}}
 {{end}}
 
 # limits
 {{if .Resources}}
 {{if .Resources.Memory}}
 lxc.cgroup.memory.limit_in_bytes = {{.Resources.Memory}}
+{{if not .Resources.MemoryReservation}}
 lxc.cgroup.memory.soft_limit_in_bytes = {{.Resources.Memory}}
+{{end}}
 {{with $memSwap := getMemorySwap .Resources}}
 lxc.cgroup.memory.memsw.limit_in_bytes = {{$memSwap}}
 {{end}}
 {{end}}
+{{if .Resources.MemoryReservation}}
+lxc.cgroup.memory.soft_limit_in_bytes = {{.Resources.MemoryReservation}}
+{{end}}
+{{if .Resources.KernelMemory}}
+lxc.cgroup.memory.kmem.limit_in_bytes = {{.Resources.KernelMemory}}
+{{end}}
 {{if .Resources.CpuShares}}
 lxc.cgroup.cpu.shares = {{.Resources.CpuShares}}
 {{end}}
+{{if .Resources.CpuPeriod}}
+lxc.cgroup.cpu.cfs_period_us = {{.Resources.CpuPeriod}}
+{{end}}
+{{if .Resources.CpuQuota}}
+lxc.cgroup.cpu.cfs_quota_us = {{.Resources.CpuQuota}}
+{{end}}
+{{if .Resources.CpusetCpus}}
+lxc.cgroup.cpuset.cpus = {{.Resources.CpusetCpus}}
+{{end}}
+{{if .Resources.CpusetMems}}
+lxc.cgroup.cpuset.mems = {{.Resources.CpusetMems}}
+{{end}}
+{{if .Resources.BlkioWeight}}
+lxc.cgroup.blkio.weight = {{.Resources.BlkioWeight}}
+{{end}}
+{{range .Resources.BlkioThrottleReadBpsDevice}}
+lxc.cgroup.blkio.throttle.read_bps_device = {{.}}
+{{end}}
+{{range .Resources.BlkioThrottleWriteBpsDevice}}
+lxc.cgroup.blkio.throttle.write_bps_device = {{.}}
+{{end}}
+{{range .Resources.BlkioThrottleReadIOpsDevice}}
+lxc.cgroup.blkio.throttle.read_iops_device = {{.}}
+{{end}}
+{{range .Resources.BlkioThrottleWriteIOpsDevice}}
+lxc.cgroup.blkio.throttle.write_iops_device = {{.}}
+{{end}}
+{{if .Resources.PidsLimit}}
+lxc.cgroup.pids.max = {{.Resources.PidsLimit}}
+{{end}}
 {{end}}
 
 {{if .Config}}
@@ -139,14 +207,27 @@ func getMemorySwap(v *execdriver.Resources) int64 {
 	if v.MemorySwap < 0 {
 		return 0
 	}
+	if v.MemorySwap > 0 {
+		return v.MemorySwap
+	}
 	return v.Memory * 2
 }
 
+// deviceCgroup returns the devices.allow entry of a device of the host
+func deviceCgroup(device execdriver.Device) (string, error) {
+	d, err := libcontainer.GetDevice(device.PathOnHost, device.PathInContainer, device.CgroupPermissions)
+	if err != nil {
+		return "", err
+	}
+	return d.CgroupString(), nil
+}
+
 func init() {
 	var err error
 	funcMap := template.FuncMap{
 		"getMemorySwap":     getMemorySwap,
 		"escapeFstabSpaces": escapeFstabSpaces,
+		"deviceCgroup":      deviceCgroup,
 	}
 	LxcTemplateCompiled, err = template.New("lxc").Funcs(funcMap).Parse(LxcTemplate)
 	if err != nil {
@@ -4,20 +4,143 @@ import (
 	"fmt"
 	"github.com/dotcloud/docker/pkg/cgroups"
 	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/pkg/libcontainer/nsinit"
+	"github.com/dotcloud/docker/pkg/libcontainer/seccomp"
 	"github.com/dotcloud/docker/runtime/execdriver"
 	"os"
+	"path/filepath"
 )
 
 // createContainer populates and configures the container type with the
 // data provided by the execdriver.Command
-func createContainer(c *execdriver.Command) *libcontainer.Container {
+func (d *driver) createContainer(c *execdriver.Command) (*libcontainer.Container, error) {
 	container := getDefaultTemplate()
 
 	container.Hostname = getEnv("HOSTNAME", c.Env)
 	container.Tty = c.Tty
+	// the output of the containers without a tty is kept in files a new
+	// daemon follows again, they keep running when the daemon exits
+	container.Restorable = !c.Tty
 	container.User = c.User
 	container.WorkingDir = c.WorkingDir
 	container.Env = c.Env
+	container.ReadonlyFs = c.ReadonlyRootfs
+	container.Tmpfs = c.Tmpfs
+
+	if c.UidMappings != nil {
+		if c.Privileged {
+			return nil, fmt.Errorf("Privileged containers cannot run in a user namespace")
+		}
+		container.Namespaces = append(container.Namespaces, libcontainer.GetNamespace("NEWUSER"))
+		container.UidMappings = c.UidMappings
+		container.GidMappings = c.GidMappings
+	}
+
+	if err := d.createNetwork(container, c); err != nil {
+		return nil, err
+	}
+
+	container.Cgroups.Name = c.ID
+	if c.Privileged {
+		container.CapabilitiesMask = nil
+		container.Cgroups.DeviceAccess = true
+		container.Context["apparmor_profile"] = "unconfined"
+		container.Seccomp = nil
+	} else {
+		mask, err := execdriver.TweakCapabilities(execdriver.DefaultCapabilityMask, c.CapAdd, c.CapDrop)
+		if err != nil {
+			return nil, err
+		}
+		container.CapabilitiesMask = getCapabilities(mask)
+		if c.AppArmorProfile != "" {
+			container.Context["apparmor_profile"] = c.AppArmorProfile
+		}
+		if c.ProcessLabel != "" {
+			container.Context["process_label"] = c.ProcessLabel
+			container.Context["mount_label"] = c.MountLabel
+		}
+		switch c.SeccompProfile {
+		case "":
+		case "unconfined":
+			container.Seccomp = nil
+		default:
+			if container.Seccomp, err = seccomp.LoadProfile(c.SeccompProfile); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, d := range c.Devices {
+		device, err := libcontainer.GetDevice(d.PathOnHost, d.PathInContainer, d.CgroupPermissions)
+		if err != nil {
+			return nil, err
+		}
+		container.Devices = append(container.Devices, device)
+		container.Cgroups.AllowedDevices = append(container.Cgroups.AllowedDevices, device.CgroupString())
+	}
+	if c.Resources != nil {
+		container.Cgroups.CpuShares = c.Resources.CpuShares
+		container.Cgroups.Memory = c.Resources.Memory
+		container.Cgroups.MemorySwap = c.Resources.MemorySwap
+		container.Cgroups.MemoryReservation = c.Resources.MemoryReservation
+		container.Cgroups.KernelMemory = c.Resources.KernelMemory
+		container.Cgroups.CpuPeriod = c.Resources.CpuPeriod
+		container.Cgroups.CpuQuota = c.Resources.CpuQuota
+		container.Cgroups.CpusetCpus = c.Resources.CpusetCpus
+		container.Cgroups.CpusetMems = c.Resources.CpusetMems
+		container.Cgroups.BlkioWeight = c.Resources.BlkioWeight
+		container.Cgroups.BlkioThrottleReadBpsDevice = c.Resources.BlkioThrottleReadBpsDevice
+		container.Cgroups.BlkioThrottleWriteBpsDevice = c.Resources.BlkioThrottleWriteBpsDevice
+		container.Cgroups.BlkioThrottleReadIOpsDevice = c.Resources.BlkioThrottleReadIOpsDevice
+		container.Cgroups.BlkioThrottleWriteIOpsDevice = c.Resources.BlkioThrottleWriteIOpsDevice
+		container.Cgroups.PidsLimit = c.Resources.PidsLimit
+		for _, u := range c.Resources.Ulimits {
+			rlimit, err := u.GetRlimit()
+			if err != nil {
+				return nil, err
+			}
+			container.Rlimits = append(container.Rlimits, libcontainer.Rlimit{Type: rlimit.Type, Hard: rlimit.Hard, Soft: rlimit.Soft})
+		}
+	}
+	// check to see if we are running in ramdisk to disable pivot root
+	container.NoPivotRoot = os.Getenv("DOCKER_RAMDISK") != ""
+
+	for _, m := range c.Mounts {
+		container.Mounts = append(container.Mounts, libcontainer.Mount{m.Source, m.Destination, m.Writable, m.Private})
+	}
+
+	return container, nil
+}
+
+// createNetwork sets up the networking of the container: the network stack
+// of the host, the network namespace of another container, or a loopback
+// and a veth on the bridge of the container in a new network namespace
+func (d *driver) createNetwork(container *libcontainer.Container, c *execdriver.Command) error {
+	if c.Network.HostNetworking {
+		container.Namespaces = container.Namespaces.Remove("NEWNET")
+		container.Networks = nil
+		return nil
+	}
+
+	if c.Network.ContainerID != "" {
+		if c.UidMappings != nil {
+			return fmt.Errorf("Cannot join the network of another container from a user namespace")
+		}
+		state, err := nsinit.ReadState(filepath.Join(d.root, c.Network.ContainerID))
+		if err != nil {
+			return fmt.Errorf("Cannot join the network of the container %s, it is not running", c.Network.ContainerID)
+		}
+		nspath, exists := state.NamespacePaths["NEWNET"]
+		if !exists {
+			return fmt.Errorf("The container %s has no network namespace to join", c.Network.ContainerID)
+		}
+		container.Networks = []*libcontainer.Network{
+			{
+				Type:    "netns",
+				Context: libcontainer.Context{"nspath": nspath},
+			},
+		}
+		return nil
+	}
 
 	loopbackNetwork := libcontainer.Network{
 		Mtu:     c.Network.Mtu,
@@ -42,51 +165,29 @@ func createContainer(c *execdriver.Command) *libcontainer.Container {
 				"bridge": c.Network.Interface.Bridge,
 			},
 		}
+		if c.Network.Interface.IPv6Address != "" {
+			vethNetwork.IPv6Address = fmt.Sprintf("%s/%d", c.Network.Interface.IPv6Address, c.Network.Interface.IPv6PrefixLen)
+			vethNetwork.IPv6Gateway = c.Network.Interface.IPv6Gateway
+		}
 		container.Networks = append(container.Networks, &vethNetwork)
 	}
+	return nil
+}
 
-	container.Cgroups.Name = c.ID
-	if c.Privileged {
-		container.CapabilitiesMask = nil
-		container.Cgroups.DeviceAccess = true
-		container.Context["apparmor_profile"] = "unconfined"
-	}
-	if c.Resources != nil {
-		container.Cgroups.CpuShares = c.Resources.CpuShares
-		container.Cgroups.Memory = c.Resources.Memory
-		container.Cgroups.MemorySwap = c.Resources.MemorySwap
-	}
-	// check to see if we are running in ramdisk to disable pivot root
-	container.NoPivotRoot = os.Getenv("DOCKER_RAMDISK") != ""
-
-	for _, m := range c.Mounts {
-		container.Mounts = append(container.Mounts, libcontainer.Mount{m.Source, m.Destination, m.Writable, m.Private})
+// getCapabilities returns the libcontainer capabilities of the names
+func getCapabilities(names []string) libcontainer.Capabilities {
+	capabilities := libcontainer.Capabilities{}
+	for _, name := range names {
+		capabilities = append(capabilities, libcontainer.GetCapability(name))
 	}
-
-	return container
+	return capabilities
 }
 
 // getDefaultTemplate returns the docker default for
 // the libcontainer configuration file
 func getDefaultTemplate() *libcontainer.Container {
 	return &libcontainer.Container{
-		CapabilitiesMask: libcontainer.Capabilities{
-			libcontainer.GetCapability("SETPCAP"),
-			libcontainer.GetCapability("SYS_MODULE"),
-			libcontainer.GetCapability("SYS_RAWIO"),
-			libcontainer.GetCapability("SYS_PACCT"),
-			libcontainer.GetCapability("SYS_ADMIN"),
-			libcontainer.GetCapability("SYS_NICE"),
-			libcontainer.GetCapability("SYS_RESOURCE"),
-			libcontainer.GetCapability("SYS_TIME"),
-			libcontainer.GetCapability("SYS_TTY_CONFIG"),
-			libcontainer.GetCapability("MKNOD"),
-			libcontainer.GetCapability("AUDIT_WRITE"),
-			libcontainer.GetCapability("AUDIT_CONTROL"),
-			libcontainer.GetCapability("MAC_OVERRIDE"),
-			libcontainer.GetCapability("MAC_ADMIN"),
-			libcontainer.GetCapability("NET_ADMIN"),
-		},
+		CapabilitiesMask: getCapabilities(execdriver.DefaultCapabilityMask),
 		Namespaces: libcontainer.Namespaces{
 			libcontainer.GetNamespace("NEWNS"),
 			libcontainer.GetNamespace("NEWUTS"),
@@ -101,5 +202,15 @@ func getDefaultTemplate() *libcontainer.Container {
 		Context: libcontainer.Context{
 			"apparmor_profile": "docker-default",
 		},
+		Seccomp: defaultSeccompProfile(),
+	}
+}
+
+// defaultSeccompProfile returns the default seccomp profile,
+// or nil when seccomp filters are not supported
+func defaultSeccompProfile() *libcontainer.Seccomp {
+	if !seccomp.IsSupported() {
+		return nil
 	}
+	return seccomp.DefaultProfile()
 }
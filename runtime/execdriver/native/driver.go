@@ -18,28 +18,26 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
 const (
 	DriverName = "native"
 	Version    = "0.1"
+
+	// restorePollInterval is how often Restore checks that the init of
+	// a container started by a previous daemon is still running, when
+	// the kernel can't notify its exit
+	restorePollInterval = 100 * time.Millisecond
 )
 
 func init() {
 	execdriver.RegisterInitFunc(DriverName, func(args *execdriver.InitArgs) error {
-		var (
-			container *libcontainer.Container
-			ns        = nsinit.NewNsInit(&nsinit.DefaultCommandFactory{}, &nsinit.DefaultStateWriter{args.Root}, createLogger(""))
-		)
-		f, err := os.Open(filepath.Join(args.Root, "container.json"))
+		ns := nsinit.NewNsInit(&nsinit.DefaultCommandFactory{}, &nsinit.DefaultStateWriter{Root: args.Root}, createLogger(""))
+		container, err := loadContainer(args.Root)
 		if err != nil {
 			return err
 		}
-		if err := json.NewDecoder(f).Decode(&container); err != nil {
-			f.Close()
-			return err
-		}
-		f.Close()
 
 		cwd, err := os.Getwd()
 		if err != nil {
@@ -57,11 +55,12 @@ func init() {
 }
 
 type driver struct {
-	root     string
-	initPath string
+	root        string
+	initPath    string
+	cgroupSlice string // systemd slice of the scopes of the containers
 }
 
-func NewDriver(root, initPath string) (*driver, error) {
+func NewDriver(root, initPath, cgroupSlice string) (*driver, error) {
 	if err := os.MkdirAll(root, 0700); err != nil {
 		return nil, err
 	}
@@ -69,8 +68,9 @@ func NewDriver(root, initPath string) (*driver, error) {
 		return nil, err
 	}
 	return &driver{
-		root:     root,
-		initPath: initPath,
+		root:        root,
+		initPath:    initPath,
+		cgroupSlice: cgroupSlice,
 	}, nil
 }
 
@@ -78,14 +78,21 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 	if err := d.validateCommand(c); err != nil {
 		return -1, err
 	}
+	container, err := d.createContainer(c)
+	if err != nil {
+		return -1, err
+	}
+	if container.Cgroups != nil && cgroups.UseSystemd() {
+		container.Cgroups.Systemd = true
+		container.Cgroups.Slice = d.cgroupSlice
+	}
 	var (
 		term        nsinit.Terminal
-		container   = createContainer(c)
 		factory     = &dockerCommandFactory{c: c, driver: d}
 		stateWriter = &dockerStateWriter{
 			callback: startCallback,
 			c:        c,
-			dsw:      &nsinit.DefaultStateWriter{filepath.Join(d.root, c.ID)},
+			dsw:      &nsinit.DefaultStateWriter{Root: filepath.Join(d.root, c.ID)},
 		}
 		ns   = nsinit.NewNsInit(factory, stateWriter, createLogger(os.Getenv("DEBUG")))
 		args = append([]string{c.Entrypoint}, c.Arguments...)
@@ -100,7 +107,8 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 			pipes: pipes,
 		}
 	} else {
-		term = &dockerStdTerm{
+		term = &dockerLogTerm{
+			root:  filepath.Join(d.root, c.ID),
 			pipes: pipes,
 		}
 	}
@@ -111,10 +119,155 @@ func (d *driver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallba
 	return ns.Exec(container, term, args)
 }
 
+// Kill signals the init of the container, which is found with its state
+// when the container was started by a previous daemon. The init is signaled
+// through a pidfd when the kernel supports it, so that a process reusing its
+// pid is never signaled.
 func (d *driver) Kill(p *execdriver.Command, sig int) error {
-	err := syscall.Kill(p.Process.Pid, syscall.Signal(sig))
-	d.removeContainerRoot(p.ID)
-	return err
+	state, err := nsinit.ReadState(filepath.Join(d.root, p.ID))
+	if err != nil {
+		if p.Process == nil {
+			return err
+		}
+		// the container is still being set up
+		return syscall.Kill(p.Process.Pid, syscall.Signal(sig))
+	}
+	fd, err := system.PidfdOpen(state.InitPid)
+	if err != nil && err != syscall.ENOSYS && err != syscall.ESRCH {
+		return err
+	}
+	if err == nil {
+		defer system.Closefd(fd)
+	}
+	// the pid may have been reused before it was opened, the pidfd keeps
+	// referring to the same process once it is checked
+	if err == syscall.ESRCH || !state.IsRunning() {
+		return fmt.Errorf("Container %s is not running", p.ID)
+	}
+	if err == syscall.ENOSYS {
+		return syscall.Kill(state.InitPid, syscall.Signal(sig))
+	}
+	return system.PidfdSendSignal(fd, syscall.Signal(sig))
+}
+
+// Restore follows the output of the container, started by a previous daemon,
+// into the pipes until its init exits and cleans up after it. The exit code
+// of a process which is not a child of the daemon is unknown, -1 is returned.
+func (d *driver) Restore(c *execdriver.Command, pipes *execdriver.Pipes) (int, error) {
+	root := filepath.Join(d.root, c.ID)
+	state, err := nsinit.ReadState(root)
+	if err != nil {
+		return -1, err
+	}
+	c.ContainerPid = state.InitPid
+	c.HostInterface = state.NetworkInterfaces["veth-host"]
+
+	term := &dockerLogTerm{
+		root:  root,
+		pipes: pipes,
+	}
+	if err := term.follow(filepath.Join(root, "stdout"), pipes.Stdout); err != nil {
+		return -1, err
+	}
+	if err := term.follow(filepath.Join(root, "stderr"), pipes.Stderr); err != nil {
+		term.Close()
+		return -1, err
+	}
+	c.Terminal = term
+	err = waitInit(state)
+	term.Close()
+	if err != nil {
+		return -1, err
+	}
+
+	if container, err := loadContainer(root); err == nil && container.Cgroups != nil {
+		if cgroupRoot, err := cgroups.FindCgroupMountpoint("cpu"); err == nil {
+			if err := container.Cgroups.Cleanup(filepath.Dir(cgroupRoot)); err != nil {
+				return -1, err
+			}
+		}
+	}
+	return -1, d.removeContainerRoot(c.ID)
+}
+
+// waitInit blocks until the init of the state exits
+func waitInit(state *libcontainer.State) error {
+	fd, err := system.PidfdOpen(state.InitPid)
+	if err == syscall.ENOSYS {
+		// the kernel can't notify the exit of a process which is not
+		// a child, the process is polled instead
+		for state.IsRunning() {
+			time.Sleep(restorePollInterval)
+		}
+		return nil
+	}
+	if err != nil {
+		if err == syscall.ESRCH {
+			return nil
+		}
+		return err
+	}
+	defer system.Closefd(fd)
+	// the pid may have been reused before it was opened
+	if !state.IsRunning() {
+		return nil
+	}
+	return system.WaitPidfd(fd)
+}
+
+func (d *driver) Pause(c *execdriver.Command) error {
+	return d.setFreezerState(c, cgroups.Frozen)
+}
+
+func (d *driver) Unpause(c *execdriver.Command) error {
+	return d.setFreezerState(c, cgroups.Thawed)
+}
+
+func (d *driver) Stats(id string) (*cgroups.Stats, error) {
+	if container, err := loadContainer(filepath.Join(d.root, id)); err == nil && container.Cgroups != nil && container.Cgroups.Systemd {
+		return container.Cgroups.Stats()
+	}
+	return cgroups.GetStats(id, "docker")
+}
+
+func (d *driver) Update(c *execdriver.Command, resources *execdriver.Resources) error {
+	container, err := loadContainer(filepath.Join(d.root, c.ID))
+	if err != nil {
+		return err
+	}
+	if container.Cgroups == nil {
+		return fmt.Errorf("Container %s has no cgroups", c.ID)
+	}
+	previous := container.Cgroups
+	cgroup := *container.Cgroups
+	cgroup.Memory = resources.Memory
+	cgroup.MemorySwap = resources.MemorySwap
+	cgroup.CpuShares = resources.CpuShares
+	// the container file is written first, it is written back with the
+	// previous limits when they are kept by the cgroup
+	container.Cgroups = &cgroup
+	if err := d.writeContainerFile(container, c.ID); err != nil {
+		return err
+	}
+	if err := cgroup.Update(); err != nil {
+		container.Cgroups = previous
+		if werr := d.writeContainerFile(container, c.ID); werr != nil {
+			return fmt.Errorf("%s, the previous limits of %s cannot be restored: %s", err, c.ID, werr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (d *driver) setFreezerState(c *execdriver.Command, state cgroups.FreezerState) error {
+	container, err := loadContainer(filepath.Join(d.root, c.ID))
+	if err != nil {
+		return err
+	}
+	if container.Cgroups == nil {
+		return fmt.Errorf("Container %s has no cgroups", c.ID)
+	}
+	return container.Cgroups.Freeze(state)
 }
 
 func (d *driver) Info(id string) execdriver.Info {
@@ -147,6 +300,13 @@ func (d *driver) GetPidsForContainer(id string) ([]int, error) {
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		filename = filepath.Join(cgroupRoot, cgroupDir, "docker", id, "tasks")
 	}
+	// the state of the container has the directories of its cgroups,
+	// the scopes of systemd are below their slice from the root
+	if state, err := nsinit.ReadState(filepath.Join(d.root, id)); err == nil {
+		if dir, exists := state.CgroupPaths[subsystem]; exists {
+			filename = filepath.Join(dir, "tasks")
+		}
+	}
 
 	output, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -165,6 +325,22 @@ func (d *driver) GetPidsForContainer(id string) ([]int, error) {
 	return pids, nil
 }
 
+// loadContainer reads the container.json written by the driver for
+// the container in root
+func loadContainer(root string) (*libcontainer.Container, error) {
+	f, err := os.Open(filepath.Join(root, "container.json"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var container *libcontainer.Container
+	if err := json.NewDecoder(f).Decode(&container); err != nil {
+		return nil, err
+	}
+	return container, nil
+}
+
 func (d *driver) writeContainerFile(container *libcontainer.Container, id string) error {
 	data, err := json.Marshal(container)
 	if err != nil {
@@ -241,17 +417,18 @@ type dockerStateWriter struct {
 	callback execdriver.StartCallback
 }
 
-func (d *dockerStateWriter) WritePid(pid int) error {
-	d.c.ContainerPid = pid
-	err := d.dsw.WritePid(pid)
+func (d *dockerStateWriter) WriteState(state *libcontainer.State) error {
+	d.c.ContainerPid = state.InitPid
+	d.c.HostInterface = state.NetworkInterfaces["veth-host"]
+	err := d.dsw.WriteState(state)
 	if d.callback != nil {
 		d.callback(d.c)
 	}
 	return err
 }
 
-func (d *dockerStateWriter) DeletePid() error {
-	return d.dsw.DeletePid()
+func (d *dockerStateWriter) DeleteState() error {
+	return d.dsw.DeleteState()
 }
 
 func createLogger(debug string) *log.Logger {
@@ -0,0 +1,90 @@
+package native
+
+import (
+	"github.com/dotcloud/docker/pkg/cgroups"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/pkg/libcontainer/nsinit"
+	"github.com/dotcloud/docker/pkg/system"
+	"github.com/dotcloud/docker/runtime/execdriver"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestKillState(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-native-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	startTime, err := system.GetProcessStartTime(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &driver{root: root}
+	c := &execdriver.Command{ID: "ghost"}
+	w := &nsinit.DefaultStateWriter{Root: filepath.Join(root, c.ID)}
+	if err := os.MkdirAll(w.Root, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	// a process reusing the pid of the init is not signaled
+	if err := w.WriteState(&libcontainer.State{InitPid: cmd.Process.Pid, InitStartTime: "0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Kill(c, 9); err == nil {
+		t.Fatal("Expected an error for a process with another start time")
+	}
+
+	if err := w.WriteState(&libcontainer.State{InitPid: cmd.Process.Pid, InitStartTime: startTime}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Kill(c, 9); err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Wait()
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); !ok || !status.Signaled() || status.Signal() != syscall.SIGKILL {
+		t.Fatalf("Expected the init to be killed, received %v", err)
+	}
+}
+
+func TestUpdateRestoresContainerFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-native-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	d := &driver{root: root}
+	c := &execdriver.Command{ID: "update"}
+	if err := d.createContainerRoot(c.ID); err != nil {
+		t.Fatal(err)
+	}
+	// the cgroup of the container doesn't exist, its limits can't be updated
+	container := &libcontainer.Container{
+		Cgroups: &cgroups.Cgroup{Name: "docker-test-missing", Parent: "docker-test-missing", Memory: 1048576},
+	}
+	if err := d.writeContainerFile(container, c.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Update(c, &execdriver.Resources{Memory: 2097152}); err == nil {
+		t.Fatal("Expected an error when the cgroup cannot be updated")
+	}
+	container, err = loadContainer(filepath.Join(root, c.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.Cgroups.Memory != 1048576 {
+		t.Fatalf("Expected the previous memory limit to be kept, received %d", container.Cgroups.Memory)
+	}
+}
@@ -0,0 +1,122 @@
+package native
+
+import (
+	"fmt"
+	"github.com/dotcloud/docker/pkg/libcontainer/nsinit"
+	"github.com/dotcloud/docker/pkg/system"
+	"github.com/dotcloud/docker/runtime/execdriver"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// execInitName is the name of the init function that joins the
+// namespaces of a running container and executes a new process
+const execInitName = "native-exec"
+
+func init() {
+	execdriver.RegisterInitFunc(execInitName, func(args *execdriver.InitArgs) error {
+		container, err := loadContainer(args.Root)
+		if err != nil {
+			return err
+		}
+		state, err := nsinit.ReadState(args.Root)
+		if err != nil {
+			return err
+		}
+		if args.User != "" {
+			container.User = args.User
+		}
+		if args.WorkDir != "" {
+			container.WorkingDir = args.WorkDir
+		}
+		if args.Privileged {
+			container.CapabilitiesMask = nil
+		}
+		// the environment of the new process is provided by the daemon
+		os.Unsetenv(nsinit.UsernsPidEnv)
+		container.Env = os.Environ()
+
+		ns := nsinit.NewNsInit(&nsinit.DefaultCommandFactory{}, &nsinit.DefaultStateWriter{Root: args.Root}, createLogger(""))
+		exitCode, err := ns.ExecIn(container, state.InitPid, args.Console, args.Args)
+		if err != nil {
+			return err
+		}
+		os.Exit(exitCode)
+		return nil
+	})
+}
+
+func (d *driver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessConfig, pipes *execdriver.Pipes, startCallback execdriver.ExecStartCallback) (int, error) {
+	if !d.Info(c.ID).IsRunning() {
+		return -1, fmt.Errorf("Container %s is not running", c.ID)
+	}
+	var term nsinit.Terminal
+	if processConfig.Tty {
+		master, console, err := system.CreateMasterAndConsole()
+		if err != nil {
+			return -1, err
+		}
+		term = &dockerTtyTerm{
+			pipes: pipes,
+		}
+		term.SetMaster(master)
+		processConfig.Console = console
+	} else {
+		term = &dockerStdTerm{
+			pipes: pipes,
+		}
+	}
+	processConfig.Terminal = term
+	defer term.Close()
+
+	params := []string{
+		d.initPath,
+		"-driver", execInitName,
+		"-root", filepath.Join(d.root, c.ID),
+		"-console", processConfig.Console,
+	}
+	if processConfig.User != "" {
+		params = append(params, "-u", processConfig.User)
+	}
+	if processConfig.WorkingDir != "" {
+		params = append(params, "-w", processConfig.WorkingDir)
+	}
+	if processConfig.Privileged {
+		params = append(params, "-privileged")
+	}
+	params = append(params, "--", processConfig.Entrypoint)
+
+	processConfig.Path = d.initPath
+	processConfig.Args = append(params, processConfig.Arguments...)
+	processConfig.Cmd.Env = processConfig.Env
+	if !processConfig.Tty {
+		// the process with a tty is the leader of a new session instead
+		processConfig.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+	if c.UidMappings != nil {
+		// the init joins the user namespace before the Go runtime starts
+		state, err := nsinit.ReadState(filepath.Join(d.root, c.ID))
+		if err != nil {
+			return -1, err
+		}
+		processConfig.Cmd.Env = append(processConfig.Cmd.Env, fmt.Sprintf("%s=%d", nsinit.UsernsPidEnv, state.InitPid))
+	}
+
+	if err := term.Attach(&processConfig.Cmd); err != nil {
+		return -1, err
+	}
+	if err := processConfig.Start(); err != nil {
+		return -1, err
+	}
+	if startCallback != nil {
+		startCallback(processConfig)
+	}
+	if err := processConfig.Wait(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return -1, err
+		}
+	}
+	return processConfig.ProcessState.Sys().(syscall.WaitStatus).ExitStatus(), nil
+}
@@ -0,0 +1,142 @@
+package native
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"syscall"
+)
+
+const (
+	fallocPunch    = 0x03 // FALLOC_FL_PUNCH_HOLE | FALLOC_FL_KEEP_SIZE
+	inotifyEvents  = syscall.IN_MODIFY
+	inotifyBufSize = 4096
+)
+
+// logFollower copies what the process of a container writes to a file into
+// a pipe of the daemon, as it is written. The offset of the copied data is
+// kept in the file <path>.offset, a new daemon starts following the file
+// there. The copied data is punched out of the file to free its space, the
+// file is not followed on a filesystem which can't punch holes as it would
+// grow without bound.
+type logFollower struct {
+	file   *os.File
+	offset *os.File // offset of the data copied, 8 bytes in little endian
+	notify *os.File // inotify of the modifications of the file
+	w      io.Writer
+	done   chan struct{}
+	err    error // error which stopped the follower
+}
+
+// followLog follows the file at path into w, from the data not copied yet
+func followLog(path string, w io.Writer) (f *logFollower, err error) {
+	f = &logFollower{
+		w:    w,
+		done: make(chan struct{}),
+	}
+	defer func() {
+		if err != nil {
+			f.closeFiles()
+		}
+	}()
+	// the file is written to punch the copied data
+	if f.file, err = os.OpenFile(path, os.O_RDWR, 0); err != nil {
+		return nil, err
+	}
+	if f.offset, err = os.OpenFile(path+".offset", os.O_RDWR|os.O_CREATE, 0600); err != nil {
+		return nil, err
+	}
+	// punching past the end of the offset file changes nothing, it fails
+	// when the filesystem doesn't support it
+	if err := punchHole(f.offset, 8, 1); err != nil {
+		return nil, err
+	}
+	var offset int64
+	if err := binary.Read(f.offset, binary.LittleEndian, &offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if _, err := f.file.Seek(offset, os.SEEK_SET); err != nil {
+		return nil, err
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	// the inotify is non blocking, its reads are interrupted by Close
+	f.notify = os.NewFile(uintptr(fd), "inotify")
+	if _, err := syscall.InotifyAddWatch(fd, path, inotifyEvents); err != nil {
+		return nil, err
+	}
+	go f.follow()
+	return f, nil
+}
+
+func (f *logFollower) follow() {
+	defer close(f.done)
+
+	buf := make([]byte, inotifyBufSize)
+	for {
+		if f.err = f.copy(); f.err != nil {
+			return
+		}
+		// the read fails once the follower is closed, the data written
+		// before is copied one last time
+		if _, err := f.notify.Read(buf); err != nil {
+			f.err = f.copy()
+			return
+		}
+	}
+}
+
+// copy copies the data of the file up to its end, records its offset and
+// frees it
+func (f *logFollower) copy() error {
+	offset, err := f.file.Seek(0, os.SEEK_CUR)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f.w, f.file)
+	if n > 0 {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], uint64(offset+n))
+		if _, err := f.offset.WriteAt(buf[:], 0); err != nil {
+			return err
+		}
+		if err := punchHole(f.file, offset, n); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// punchHole frees n bytes of file at offset, the size of the file is kept as
+// the process appends to it
+func punchHole(file *os.File, offset, n int64) error {
+	if err := syscall.Fallocate(int(file.Fd()), fallocPunch, offset, n); err != nil {
+		return os.NewSyscallError("fallocate", err)
+	}
+	return nil
+}
+
+func (f *logFollower) closeFiles() {
+	for _, file := range []*os.File{f.file, f.offset, f.notify} {
+		if file != nil {
+			file.Close()
+		}
+	}
+}
+
+// Close copies the data left in the file and stops following it, the
+// process writing to the file is expected to have exited. The error which
+// stopped the follower is returned.
+func (f *logFollower) Close() error {
+	err := f.notify.Close()
+	<-f.done
+	f.file.Close()
+	f.offset.Close()
+	if f.err != nil {
+		return f.err
+	}
+	return err
+}
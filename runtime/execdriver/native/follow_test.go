@@ -0,0 +1,155 @@
+package native
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+func TestFollowLog(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-follow-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "stdout")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString("before\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &syncBuffer{}
+	follower, err := followLog(path, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString("while\n"); err != nil {
+		t.Fatal(err)
+	}
+	// the data is copied as it is written, without closing the follower
+	for i := 0; out.String() != "before\nwhile\n"; i++ {
+		if i == 100 {
+			t.Fatalf("Expected the written data to be followed, received %q", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := file.WriteString("last\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := follower.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "before\nwhile\nlast\n" {
+		t.Fatalf("Expected the data left to be copied on close, received %q", out.String())
+	}
+
+	// a new follower starts after the data already copied
+	if _, err := file.WriteString("after\n"); err != nil {
+		t.Fatal(err)
+	}
+	out = &syncBuffer{}
+	if follower, err = followLog(path, out); err != nil {
+		t.Fatal(err)
+	}
+	if err := follower.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "after\n" {
+		t.Fatalf("Expected the data after the copied one, received %q", out.String())
+	}
+}
+
+type countWriter struct {
+	sync.Mutex
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+func (c *countWriter) count() int64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.n
+}
+
+func TestFollowLogDiskUsage(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-follow-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "stdout")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	out := &countWriter{}
+	follower, err := followLog(path, out)
+	if err != nil {
+		if serr, ok := err.(*os.SyscallError); ok && serr.Err == syscall.EOPNOTSUPP {
+			t.Skip("the filesystem of the temporary directory can't punch holes")
+		}
+		t.Fatal(err)
+	}
+	chunk := bytes.Repeat([]byte("a"), 64*1024)
+	const chunks = 128
+	for i := 0; i < chunks; i++ {
+		if _, err := file.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := follower.Close(); err != nil {
+		t.Fatal(err)
+	}
+	total := int64(len(chunk) * chunks)
+	if out.count() != total {
+		t.Fatalf("Expected %d bytes to be followed, received %d", total, out.count())
+	}
+
+	// the size is kept but the blocks of the copied data are freed
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != total {
+		t.Fatalf("Expected the size of the file to be %d, received %d", total, fi.Size())
+	}
+	if used := fi.Sys().(*syscall.Stat_t).Blocks * 512; used > 64*1024 {
+		t.Fatalf("Expected the copied data to be freed, %d bytes are still used", used)
+	}
+}
@@ -1,7 +1,7 @@
 package native
 
 import (
-	"os"
+	"github.com/dotcloud/docker/pkg/libcontainer/nsinit"
 	"path/filepath"
 )
 
@@ -10,12 +10,13 @@ type info struct {
 	driver *driver
 }
 
-// IsRunning is determined by looking for the
-// pid file for a container.  If the file exists then the
-// container is currently running
+// IsRunning is determined by looking for the state file
+// of a container.  If the file exists and its init is still
+// alive then the container is currently running
 func (i *info) IsRunning() bool {
-	if _, err := os.Stat(filepath.Join(i.driver.root, i.ID, "pid")); err == nil {
-		return true
+	state, err := nsinit.ReadState(filepath.Join(i.driver.root, i.ID))
+	if err != nil {
+		return false
 	}
-	return false
+	return state.IsRunning()
 }
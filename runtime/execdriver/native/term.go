@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 )
 
 type dockerStdTerm struct {
@@ -24,6 +25,76 @@ func (d *dockerStdTerm) SetMaster(master *os.File) {
 	// do nothing
 }
 
+// dockerLogTerm gives the process files in the root of the container as
+// stdout and stderr, which are followed into the pipes. The process keeps
+// its output when the daemon exits, a new daemon follows the files again.
+type dockerLogTerm struct {
+	execdriver.StdConsole
+	root      string
+	pipes     *execdriver.Pipes
+	files     []*os.File
+	followers []*logFollower
+}
+
+func (d *dockerLogTerm) Attach(cmd *exec.Cmd) (err error) {
+	defer func() {
+		if err != nil {
+			d.Close()
+		}
+	}()
+	if err := d.AttachPipes(cmd, d.pipes); err != nil {
+		return err
+	}
+	if cmd.Stdout, err = d.openLog("stdout", d.pipes.Stdout); err != nil {
+		return err
+	}
+	if cmd.Stderr, err = d.openLog("stderr", d.pipes.Stderr); err != nil {
+		return err
+	}
+	return nil
+}
+
+// openLog creates the file name the process writes to and follows it into w
+func (d *dockerLogTerm) openLog(name string, w io.Writer) (*os.File, error) {
+	path := filepath.Join(d.root, name)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	d.files = append(d.files, file)
+	if err := d.follow(path, w); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// follow copies the file at path into w until the terminal is closed
+func (d *dockerLogTerm) follow(path string, w io.Writer) error {
+	follower, err := followLog(path, w)
+	if err != nil {
+		return err
+	}
+	d.followers = append(d.followers, follower)
+	return nil
+}
+
+func (d *dockerLogTerm) SetMaster(master *os.File) {
+	// do nothing
+}
+
+// Close copies the output left in the files, once the process exited
+func (d *dockerLogTerm) Close() (err error) {
+	for _, file := range d.files {
+		file.Close()
+	}
+	for _, follower := range d.followers {
+		if ferr := follower.Close(); ferr != nil && err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
+
 type dockerTtyTerm struct {
 	execdriver.TtyConsole
 	pipes *execdriver.Pipes
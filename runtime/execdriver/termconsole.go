@@ -9,28 +9,48 @@ import (
 )
 
 func SetTerminal(command *Command, pipes *Pipes) error {
-	var (
-		term Terminal
-		err  error
-	)
-	if command.Tty {
-		term, err = NewTtyConsole(command, pipes)
-	} else {
-		term, err = NewStdConsole(command, pipes)
-	}
+	term, console, err := newTerminal(&command.Cmd, command.Tty, pipes)
 	if err != nil {
 		return err
 	}
 	command.Terminal = term
+	command.Console = console
+	return nil
+}
+
+// SetProcessTerminal attaches the pipes to the process executed
+// inside a running container
+func SetProcessTerminal(processConfig *ProcessConfig, pipes *Pipes) error {
+	term, console, err := newTerminal(&processConfig.Cmd, processConfig.Tty, pipes)
+	if err != nil {
+		return err
+	}
+	processConfig.Terminal = term
+	processConfig.Console = console
 	return nil
 }
 
+func newTerminal(command *exec.Cmd, tty bool, pipes *Pipes) (Terminal, string, error) {
+	if tty {
+		term, err := NewTtyConsole(command, pipes)
+		if err != nil {
+			return nil, "", err
+		}
+		return term, term.SlavePty.Name(), nil
+	}
+	term, err := NewStdConsole(command, pipes)
+	if err != nil {
+		return nil, "", err
+	}
+	return term, "", nil
+}
+
 type TtyConsole struct {
 	MasterPty *os.File
 	SlavePty  *os.File
 }
 
-func NewTtyConsole(command *Command, pipes *Pipes) (*TtyConsole, error) {
+func NewTtyConsole(command *exec.Cmd, pipes *Pipes) (*TtyConsole, error) {
 	ptyMaster, ptySlave, err := pty.Open()
 	if err != nil {
 		return nil, err
@@ -39,11 +59,10 @@ func NewTtyConsole(command *Command, pipes *Pipes) (*TtyConsole, error) {
 		MasterPty: ptyMaster,
 		SlavePty:  ptySlave,
 	}
-	if err := tty.AttachPipes(&command.Cmd, pipes); err != nil {
+	if err := tty.AttachPipes(command, pipes); err != nil {
 		tty.Close()
 		return nil, err
 	}
-	command.Console = tty.SlavePty.Name()
 	return tty, nil
 }
 
@@ -88,10 +107,10 @@ func (t *TtyConsole) Close() error {
 type StdConsole struct {
 }
 
-func NewStdConsole(command *Command, pipes *Pipes) (*StdConsole, error) {
+func NewStdConsole(command *exec.Cmd, pipes *Pipes) (*StdConsole, error) {
 	std := &StdConsole{}
 
-	if err := std.AttachPipes(&command.Cmd, pipes); err != nil {
+	if err := std.AttachPipes(command, pipes); err != nil {
 		return nil, err
 	}
 	return std, nil
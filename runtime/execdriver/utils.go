@@ -0,0 +1,92 @@
+package execdriver
+
+import (
+	"fmt"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"strings"
+)
+
+// DefaultCapabilityMask holds the capabilities dropped from the
+// containers which are not privileged
+var DefaultCapabilityMask = []string{
+	"SETPCAP",
+	"SYS_MODULE",
+	"SYS_RAWIO",
+	"SYS_PACCT",
+	"SYS_ADMIN",
+	"SYS_NICE",
+	"SYS_RESOURCE",
+	"SYS_TIME",
+	"SYS_TTY_CONFIG",
+	"MKNOD",
+	"AUDIT_WRITE",
+	"AUDIT_CONTROL",
+	"MAC_OVERRIDE",
+	"MAC_ADMIN",
+	"NET_ADMIN",
+}
+
+// TweakCapabilities returns the capabilities to drop from a container,
+// starting from mask, keeping the capabilities in adds and dropping the
+// ones in drops. ALL in adds keeps every capability which is not in drops,
+// ALL in drops drops every capability which is not in adds.
+func TweakCapabilities(mask, adds, drops []string) ([]string, error) {
+	var err error
+	if adds, err = normalizeCapabilities(adds); err != nil {
+		return nil, err
+	}
+	if drops, err = normalizeCapabilities(drops); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case inSlice(adds, "ALL") && inSlice(drops, "ALL"):
+		return nil, fmt.Errorf("Cannot add and drop ALL capabilities")
+	case inSlice(adds, "ALL"):
+		mask = nil
+	case inSlice(drops, "ALL"):
+		mask = libcontainer.GetAllCapabilities()
+	}
+
+	newMask := []string{}
+	for _, capp := range mask {
+		if !inSlice(adds, capp) {
+			newMask = append(newMask, capp)
+		}
+	}
+	for _, capp := range drops {
+		if capp == "ALL" {
+			continue
+		}
+		if inSlice(adds, capp) {
+			return nil, fmt.Errorf("Cannot add and drop the capability %s", capp)
+		}
+		if !inSlice(newMask, capp) {
+			newMask = append(newMask, capp)
+		}
+	}
+	return newMask, nil
+}
+
+// normalizeCapabilities upper cases the capabilities and strips their CAP_
+// prefix, an unknown capability is an error
+func normalizeCapabilities(caps []string) ([]string, error) {
+	normalized := make([]string, len(caps))
+	for i, capp := range caps {
+		capp = strings.TrimPrefix(strings.ToUpper(capp), "CAP_")
+		if capp != "ALL" && libcontainer.GetCapability(capp) == nil {
+			return nil, fmt.Errorf("Unknown capability: %s", caps[i])
+		}
+		normalized[i] = capp
+	}
+	return normalized, nil
+}
+
+func inSlice(slice []string, s string) bool {
+	for _, ss := range slice {
+		if ss == s {
+			return true
+		}
+	}
+	return false
+}
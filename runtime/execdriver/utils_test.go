@@ -0,0 +1,48 @@
+package execdriver
+
+import (
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"reflect"
+	"testing"
+)
+
+func TestTweakCapabilities(t *testing.T) {
+	mask, err := TweakCapabilities([]string{"MKNOD", "NET_ADMIN"}, []string{"net_admin"}, []string{"CAP_CHOWN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"MKNOD", "CHOWN"}; !reflect.DeepEqual(mask, expected) {
+		t.Fatalf("Expected %v, received %v", expected, mask)
+	}
+
+	mask, err = TweakCapabilities(DefaultCapabilityMask, []string{"ALL"}, []string{"KILL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"KILL"}; !reflect.DeepEqual(mask, expected) {
+		t.Fatalf("Expected %v, received %v", expected, mask)
+	}
+
+	mask, err = TweakCapabilities(DefaultCapabilityMask, []string{"CHOWN"}, []string{"ALL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mask) != len(libcontainer.GetAllCapabilities())-1 || inSlice(mask, "CHOWN") {
+		t.Fatalf("Expected every capability but CHOWN to be dropped, received %v", mask)
+	}
+}
+
+func TestTweakCapabilitiesErrors(t *testing.T) {
+	for _, c := range []struct {
+		adds, drops []string
+	}{
+		{[]string{"NOT_A_CAP"}, nil},
+		{nil, []string{"NOT_A_CAP"}},
+		{[]string{"ALL"}, []string{"ALL"}},
+		{[]string{"CHOWN"}, []string{"CHOWN"}},
+	} {
+		if _, err := TweakCapabilities(DefaultCapabilityMask, c.adds, c.drops); err == nil {
+			t.Fatalf("Expected an error adding %v and dropping %v", c.adds, c.drops)
+		}
+	}
+}
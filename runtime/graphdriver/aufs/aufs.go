@@ -24,6 +24,8 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/dotcloud/docker/archive"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/pkg/libcontainer/selinux"
 	mountpk "github.com/dotcloud/docker/pkg/mount"
 	"github.com/dotcloud/docker/runtime/graphdriver"
 	"github.com/dotcloud/docker/utils"
@@ -219,6 +221,12 @@ func (a *Driver) Remove(id string) error {
 // Return the rootfs path for the id
 // This will mount the dir at it's given path
 func (a *Driver) Get(id string) (string, error) {
+	return a.GetLabeled(id, "")
+}
+
+// GetLabeled mounts the layers of the id with the mount label as the
+// context of their files
+func (a *Driver) GetLabeled(id, mountLabel string) (string, error) {
 	ids, err := getParentIds(a.rootPath(), id)
 	if err != nil {
 		if !os.IsNotExist(err) {
@@ -240,7 +248,7 @@ func (a *Driver) Get(id string) (string, error) {
 		out = path.Join(a.rootPath(), "mnt", id)
 
 		if count == 0 {
-			if err := a.mount(id); err != nil {
+			if err := a.mount(id, mountLabel); err != nil {
 				return "", err
 			}
 		}
@@ -251,6 +259,12 @@ func (a *Driver) Get(id string) (string, error) {
 	return out, nil
 }
 
+// Chown only chowns the diff dir of the layer, the files of
+// its parents are chowned with their own layers
+func (a *Driver) Chown(id string, uidMaps, gidMaps []libcontainer.IdMap) error {
+	return graphdriver.ChownTree(path.Join(a.rootPath(), "diff", id), uidMaps, gidMaps)
+}
+
 func (a *Driver) Put(id string) {
 	// Protect the a.active from concurrent access
 	a.Lock()
@@ -309,7 +323,7 @@ func (a *Driver) getParentLayerPaths(id string) ([]string, error) {
 	return layers, nil
 }
 
-func (a *Driver) mount(id string) error {
+func (a *Driver) mount(id, mountLabel string) error {
 	// If the id is mounted or we get an error return
 	if mounted, err := a.mounted(id); err != nil || mounted {
 		return err
@@ -325,7 +339,7 @@ func (a *Driver) mount(id string) error {
 		return err
 	}
 
-	if err := a.aufsMount(layers, rw, target); err != nil {
+	if err := a.aufsMount(layers, rw, target, mountLabel); err != nil {
 		return err
 	}
 	return nil
@@ -358,15 +372,15 @@ func (a *Driver) Cleanup() error {
 	return nil
 }
 
-func (a *Driver) aufsMount(ro []string, rw, target string) (err error) {
+func (a *Driver) aufsMount(ro []string, rw, target, mountLabel string) (err error) {
 	defer func() {
 		if err != nil {
 			Unmount(target)
 		}
 	}()
 
-	if err = a.tryMount(ro, rw, target); err != nil {
-		if err = a.mountRw(rw, target); err != nil {
+	if err = a.tryMount(ro, rw, target, mountLabel); err != nil {
+		if err = a.mountRw(rw, target, mountLabel); err != nil {
 			return
 		}
 
@@ -382,16 +396,18 @@ func (a *Driver) aufsMount(ro []string, rw, target string) (err error) {
 
 // Try to mount using the aufs fast path, if this fails then
 // append ro layers.
-func (a *Driver) tryMount(ro []string, rw, target string) (err error) {
+func (a *Driver) tryMount(ro []string, rw, target, mountLabel string) (err error) {
 	var (
 		rwBranch   = fmt.Sprintf("%s=rw", rw)
 		roBranches = fmt.Sprintf("%s=ro+wh:", strings.Join(ro, "=ro+wh:"))
+		data       = fmt.Sprintf("br:%v:%v,xino=/dev/shm/aufs.xino", rwBranch, roBranches)
 	)
-	return mount("none", target, "aufs", 0, fmt.Sprintf("br:%v:%v,xino=/dev/shm/aufs.xino", rwBranch, roBranches))
+	return mount("none", target, "aufs", 0, selinux.FormatMountLabel(data, mountLabel))
 }
 
-func (a *Driver) mountRw(rw, target string) error {
-	return mount("none", target, "aufs", 0, fmt.Sprintf("br:%s,xino=/dev/shm/aufs.xino", rw))
+func (a *Driver) mountRw(rw, target, mountLabel string) error {
+	data := fmt.Sprintf("br:%s,xino=/dev/shm/aufs.xino", rw)
+	return mount("none", target, "aufs", 0, selinux.FormatMountLabel(data, mountLabel))
 }
 
 func rollbackMount(target string, err error) {
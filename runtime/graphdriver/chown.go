@@ -0,0 +1,129 @@
+package graphdriver
+
+import (
+	"fmt"
+	"github.com/dotcloud/docker/archive"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/utils"
+	"github.com/dotcloud/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Chowner is implemented by the drivers whose layers only hold the files
+// they add to their parent, their files are chowned without mounting them
+type Chowner interface {
+	Chown(id string, uidMaps, gidMaps []libcontainer.IdMap) error
+}
+
+// ChownLayer gives the files of a layer to the ids of the host the ids of
+// the containers are mapped to. The drivers which are not Chowner chown all
+// the files of the mounted layer, the ones of its parents are already in the
+// ranges of the host and are kept.
+func ChownLayer(driver Driver, id string, uidMaps, gidMaps []libcontainer.IdMap) error {
+	if chowner, ok := driver.(Chowner); ok {
+		return chowner.Chown(id, uidMaps, gidMaps)
+	}
+	dir, err := driver.Get(id)
+	if err != nil {
+		return err
+	}
+	defer driver.Put(id)
+	return ChownTree(dir, uidMaps, gidMaps)
+}
+
+// ChownTree maps the owners of the files under root to the ids of the host
+func ChownTree(root string, uidMaps, gidMaps []libcontainer.IdMap) error {
+	return filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		st := fi.Sys().(*syscall.Stat_t)
+		uid, err := HostId(uidMaps, int(st.Uid))
+		if err != nil {
+			return fmt.Errorf("Cannot chown %s: uid %s", p, err)
+		}
+		gid, err := HostId(gidMaps, int(st.Gid))
+		if err != nil {
+			return fmt.Errorf("Cannot chown %s: gid %s", p, err)
+		}
+		if uid == int(st.Uid) && gid == int(st.Gid) {
+			return nil
+		}
+		if err := os.Lchown(p, uid, gid); err != nil {
+			return err
+		}
+		// chown clears the setuid and setgid bits
+		if fi.Mode()&os.ModeSymlink == 0 && fi.Mode()&(os.ModeSetuid|os.ModeSetgid) != 0 {
+			return os.Chmod(p, fi.Mode())
+		}
+		return nil
+	})
+}
+
+// HostId returns the id of the host an id of the container is mapped to,
+// an id already in the ranges of the host is returned as is
+func HostId(mappings []libcontainer.IdMap, id int) (int, error) {
+	for _, m := range mappings {
+		if id >= m.HostId && id < m.HostId+m.Size {
+			return id, nil
+		}
+	}
+	for _, m := range mappings {
+		if id >= m.ContainerId && id < m.ContainerId+m.Size {
+			return m.HostId + id - m.ContainerId, nil
+		}
+	}
+	return -1, fmt.Errorf("%d is not mapped in the user namespace", id)
+}
+
+// ContainerId returns the id of the container a host id is mapped from, the
+// ids out of the ranges of the host are returned as is
+func ContainerId(mappings []libcontainer.IdMap, id int) int {
+	for _, m := range mappings {
+		if id >= m.HostId && id < m.HostId+m.Size {
+			return m.ContainerId + id - m.HostId
+		}
+	}
+	return id
+}
+
+// UnmapArchive gives the files of a tar archive of a chowned layer back to
+// the ids of the containers, so the archive can be used out of the host
+func UnmapArchive(a archive.Archive, uidMaps, gidMaps []libcontainer.IdMap) archive.Archive {
+	if uidMaps == nil && gidMaps == nil {
+		return a
+	}
+	r, w := io.Pipe()
+	go func() {
+		tr := tar.NewReader(a)
+		tw := tar.NewWriter(w)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+			hdr.Uid = ContainerId(uidMaps, hdr.Uid)
+			hdr.Gid = ContainerId(gidMaps, hdr.Gid)
+			if err := tw.WriteHeader(hdr); err != nil {
+				w.CloseWithError(err)
+				return
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+		w.CloseWithError(tw.Close())
+	}()
+	return utils.NewReadCloserWrapper(r, func() error {
+		r.Close()
+		return a.Close()
+	})
+}
@@ -0,0 +1,140 @@
+package graphdriver
+
+import (
+	"bytes"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/vendor/src/code.google.com/p/go/src/pkg/archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+var testMappings = []libcontainer.IdMap{
+	{ContainerId: 0, HostId: 100000, Size: 1000},
+	{ContainerId: 1000, HostId: 200000, Size: 1000},
+}
+
+func TestHostId(t *testing.T) {
+	for id, expected := range map[int]int{
+		0:      100000,
+		999:    100999,
+		1000:   200000,
+		100005: 100005,
+		200999: 200999,
+	} {
+		hostId, err := HostId(testMappings, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hostId != expected {
+			t.Fatalf("Expected %d to be mapped to %d, received %d", id, expected, hostId)
+		}
+	}
+	if _, err := HostId(testMappings, 2000); err == nil {
+		t.Fatal("Expected an error for an id out of the ranges")
+	}
+}
+
+func TestUnmapArchive(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, f := range []struct {
+		name     string
+		uid, gid int
+	}{
+		{"root", 100000, 100000},
+		{"user", 200001, 100005},
+		{"unmapped", 0, 50},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Uid: f.uid, Gid: f.gid, Mode: 0644, Size: 4}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.name[:4])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a := UnmapArchive(ioutil.NopCloser(buf), testMappings, testMappings)
+	defer a.Close()
+	tr := tar.NewReader(a)
+	for _, expected := range []struct {
+		name     string
+		uid, gid int
+	}{
+		{"root", 0, 0},
+		{"user", 1001, 5},
+		{"unmapped", 0, 50},
+	} {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name != expected.name || hdr.Uid != expected.uid || hdr.Gid != expected.gid {
+			t.Fatalf("Expected %s owned by %d:%d, received %s owned by %d:%d", expected.name, expected.uid, expected.gid, hdr.Name, hdr.Uid, hdr.Gid)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected.name[:4] {
+			t.Fatalf("Expected the content of %s to be kept, received %q", expected.name, data)
+		}
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Fatalf("Expected the end of the archive, received %v", err)
+	}
+}
+
+func TestChownTree(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("chown requires root")
+	}
+	root, err := ioutil.TempDir("", "docker-chown-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	setuid := filepath.Join(root, "setuid")
+	if err := ioutil.WriteFile(setuid, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(setuid, 0755|os.ModeSetuid); err != nil {
+		t.Fatal(err)
+	}
+	user := filepath.Join(root, "user")
+	if err := ioutil.WriteFile(user, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(user, 1001, 1001); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ChownTree(root, testMappings, testMappings); err != nil {
+		t.Fatal(err)
+	}
+	// a second chown keeps the ids of the host
+	if err := ChownTree(root, testMappings, testMappings); err != nil {
+		t.Fatal(err)
+	}
+	for p, expected := range map[string]uint32{root: 100000, setuid: 100000, user: 200001} {
+		var st syscall.Stat_t
+		if err := syscall.Lstat(p, &st); err != nil {
+			t.Fatal(err)
+		}
+		if st.Uid != expected || st.Gid != expected {
+			t.Fatalf("Expected %s to be owned by %d:%d, received %d:%d", p, expected, expected, st.Uid, st.Gid)
+		}
+	}
+	if fi, err := os.Stat(setuid); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode()&os.ModeSetuid == 0 {
+		t.Fatalf("Expected %s to keep its setuid bit", setuid)
+	}
+}
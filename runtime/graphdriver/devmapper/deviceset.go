@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/dotcloud/docker/pkg/libcontainer/selinux"
 	"github.com/dotcloud/docker/utils"
 	"io"
 	"io/ioutil"
@@ -33,6 +34,7 @@ type DevInfo struct {
 
 	mountCount int    `json:"-"`
 	mountPath  string `json:"-"`
+	mountLabel string `json:"-"`
 	// A floating mount means one reference is not owned and
 	// will be stolen by the next mount. This allows us to
 	// avoid unmounting directly after creation before the
@@ -824,7 +826,7 @@ func (devices *DeviceSet) Shutdown() error {
 	return nil
 }
 
-func (devices *DeviceSet) MountDevice(hash, path string) error {
+func (devices *DeviceSet) MountDevice(hash, path, mountLabel string) error {
 	devices.Lock()
 	defer devices.Unlock()
 
@@ -841,6 +843,18 @@ func (devices *DeviceSet) MountDevice(hash, path string) error {
 			return fmt.Errorf("Trying to mount devmapper device in multple places (%s, %s)", info.mountPath, path)
 		}
 
+		// The floating mount of the creation has no label, the device
+		// is mounted again with the context of the files
+		if info.floating && mountLabel != info.mountLabel {
+			if err := sysUnmount(info.mountPath, 0); err != nil {
+				return err
+			}
+			info.mountCount = 0
+			info.floating = false
+		}
+	}
+
+	if info.mountCount > 0 {
 		if info.floating {
 			// Steal floating ref
 			info.floating = false
@@ -856,9 +870,9 @@ func (devices *DeviceSet) MountDevice(hash, path string) error {
 
 	var flags uintptr = sysMsMgcVal
 
-	err := sysMount(info.DevName(), path, "ext4", flags, "discard")
+	err := sysMount(info.DevName(), path, "ext4", flags, selinux.FormatMountLabel("discard", mountLabel))
 	if err != nil && err == sysEInval {
-		err = sysMount(info.DevName(), path, "ext4", flags, "")
+		err = sysMount(info.DevName(), path, "ext4", flags, selinux.FormatMountLabel("", mountLabel))
 	}
 	if err != nil {
 		return fmt.Errorf("Error mounting '%s' on '%s': %s", info.DevName(), path, err)
@@ -866,6 +880,7 @@ func (devices *DeviceSet) MountDevice(hash, path string) error {
 
 	info.mountCount = 1
 	info.mountPath = path
+	info.mountLabel = mountLabel
 	info.floating = false
 
 	return devices.setInitialized(hash)
@@ -66,7 +66,7 @@ func (d *Driver) Create(id, parent string) error {
 	}
 
 	mp := path.Join(d.home, "mnt", id)
-	if err := d.mount(id, mp); err != nil {
+	if err := d.mount(id, mp, ""); err != nil {
 		return err
 	}
 
@@ -115,8 +115,14 @@ func (d *Driver) Remove(id string) error {
 }
 
 func (d *Driver) Get(id string) (string, error) {
+	return d.GetLabeled(id, "")
+}
+
+// GetLabeled mounts the device of the id with the mount label
+// as the context of its files
+func (d *Driver) GetLabeled(id, mountLabel string) (string, error) {
 	mp := path.Join(d.home, "mnt", id)
-	if err := d.mount(id, mp); err != nil {
+	if err := d.mount(id, mp, mountLabel); err != nil {
 		return "", err
 	}
 
@@ -129,13 +135,13 @@ func (d *Driver) Put(id string) {
 	}
 }
 
-func (d *Driver) mount(id, mountPoint string) error {
+func (d *Driver) mount(id, mountPoint, mountLabel string) error {
 	// Create the target directories if they don't exist
 	if err := osMkdirAll(mountPoint, 0755); err != nil && !osIsExist(err) {
 		return err
 	}
 	// Mount the device
-	return d.DeviceSet.MountDevice(id, mountPoint)
+	return d.DeviceSet.MountDevice(id, mountPoint, mountLabel)
 }
 
 func (d *Driver) Exists(id string) bool {
@@ -0,0 +1,29 @@
+package graphdriver
+
+import (
+	"github.com/dotcloud/docker/pkg/libcontainer/selinux"
+)
+
+// MountLabeler is implemented by the drivers which mount the layers
+// with the SELinux context of their files
+type MountLabeler interface {
+	GetLabeled(id, mountLabel string) (dir string, err error)
+}
+
+// GetLabeled returns the rootfs path of the id with the mount label on its
+// files. The drivers which are not MountLabeler relabel the files of the
+// layer instead.
+func GetLabeled(driver Driver, id, mountLabel string) (string, error) {
+	if labeler, ok := driver.(MountLabeler); ok {
+		return labeler.GetLabeled(id, mountLabel)
+	}
+	dir, err := driver.Get(id)
+	if err != nil {
+		return "", err
+	}
+	if err := selinux.Relabel(dir, mountLabel); err != nil {
+		driver.Put(id)
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,198 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dotcloud/docker/runconfig"
+	"github.com/dotcloud/docker/runtime/execdriver"
+	"github.com/dotcloud/docker/utils"
+	"sync"
+	"time"
+)
+
+// Health status of a container with a health check
+const (
+	Starting  = "starting"  // no health check succeeded nor failed enough yet
+	Healthy   = "healthy"   // the last health check succeeded
+	Unhealthy = "unhealthy" // the health check failed Retries times in a row
+)
+
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthTimeout  = 30 * time.Second
+	defaultHealthRetries  = 3
+
+	// Number of results kept in the health log of the container
+	maxHealthLogEntries = 5
+	// Longest output of a health check kept in the health log
+	maxHealthOutputLen = 4096
+)
+
+// Health is the health state of a container, stored in its State
+type Health struct {
+	Status        string
+	FailingStreak int
+	Log           []*HealthcheckResult
+}
+
+// HealthcheckResult is the outcome of a single run of the health check
+type HealthcheckResult struct {
+	Start    time.Time
+	End      time.Time
+	ExitCode int
+	Output   string
+}
+
+// healthcheckCmd returns the command of the health check of the container,
+// or nil if the container has no health check
+func healthcheckCmd(config *runconfig.HealthConfig) []string {
+	if config == nil || len(config.Test) == 0 {
+		return nil
+	}
+	switch config.Test[0] {
+	case "CMD":
+		if len(config.Test) > 1 {
+			return config.Test[1:]
+		}
+	case "CMD-SHELL":
+		if len(config.Test) > 1 {
+			return []string{"/bin/sh", "-c", config.Test[1]}
+		}
+	}
+	// "NONE" or invalid definitions disable the health check
+	return nil
+}
+
+// startHealthMonitor runs the health check of the container periodically
+// until stopHealthMonitor is called. The container lock must be held.
+func (container *Container) startHealthMonitor() {
+	config := container.Config.Healthcheck
+	cmd := healthcheckCmd(config)
+	if cmd == nil {
+		return
+	}
+	var (
+		interval = defaultHealthInterval
+		timeout  = defaultHealthTimeout
+		retries  = defaultHealthRetries
+	)
+	if config.Interval != 0 {
+		interval = config.Interval
+	}
+	if config.Timeout != 0 {
+		timeout = config.Timeout
+	}
+	if config.Retries != 0 {
+		retries = config.Retries
+	}
+
+	container.State.SetHealth(&Health{Status: Starting})
+	container.healthStop = make(chan struct{})
+	go container.monitorHealth(container.healthStop, cmd, interval, timeout, retries)
+}
+
+// stopHealthMonitor stops the health checks started by startHealthMonitor
+func (container *Container) stopHealthMonitor() {
+	if container.healthStop != nil {
+		close(container.healthStop)
+		container.healthStop = nil
+	}
+}
+
+func (container *Container) monitorHealth(stop chan struct{}, cmd []string, interval, timeout time.Duration, retries int) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+		// the health check cannot run while the processes are frozen
+		if container.State.IsPaused() {
+			continue
+		}
+
+		result := container.runHealthcheck(cmd, timeout)
+
+		// the container stopped while running the health check
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if status, changed := container.State.addHealthResult(result, retries); changed {
+			if container.runtime != nil && container.runtime.srv != nil {
+				container.runtime.srv.LogEvent("health_status: "+status, container.ID, container.runtime.repositories.ImageName(container.Image))
+			}
+		}
+	}
+}
+
+func (container *Container) runHealthcheck(cmd []string, timeout time.Duration) *HealthcheckResult {
+	var (
+		result = &HealthcheckResult{Start: time.Now().UTC(), ExitCode: -1}
+		output = &limitedBuffer{}
+	)
+	execConfig, err := newExecConfig(container, &runconfig.ExecConfig{Cmd: cmd})
+	if err != nil {
+		result.End = time.Now().UTC()
+		result.Output = err.Error()
+		return result
+	}
+
+	type exit struct {
+		code int
+		err  error
+	}
+	done := make(chan exit, 1)
+	go func() {
+		code, err := container.runtime.Exec(execConfig, execdriver.NewPipes(nil, output, output, false))
+		done <- exit{code, err}
+	}()
+
+	select {
+	case e := <-done:
+		result.ExitCode = e.code
+		result.Output = output.String()
+		if e.err != nil {
+			result.ExitCode = -1
+			result.Output = e.err.Error()
+		}
+	case <-time.After(timeout):
+		if err := execConfig.kill(); err != nil {
+			utils.Debugf("Error killing health check of %s: %s", container.ID, err)
+		}
+		result.Output = fmt.Sprintf("Health check exceeded timeout (%s)", timeout)
+	}
+	result.End = time.Now().UTC()
+	return result
+}
+
+// limitedBuffer keeps the first maxHealthOutputLen bytes written to it
+// from the stdout and stderr of the health check
+type limitedBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	n := len(p)
+	if remaining := maxHealthOutputLen - b.buf.Len(); remaining < len(p) {
+		if remaining < 0 {
+			remaining = 0
+		}
+		p = p[:remaining]
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.buf.String()
+}
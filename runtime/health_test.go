@@ -0,0 +1,44 @@
+package runtime
+
+import (
+	"github.com/dotcloud/docker/runconfig"
+	"testing"
+)
+
+func TestHealthcheckCmd(t *testing.T) {
+	if cmd := healthcheckCmd(&runconfig.HealthConfig{Test: []string{"NONE"}}); cmd != nil {
+		t.Fatalf("Expected NONE to disable the health check, received %v", cmd)
+	}
+	cmd := healthcheckCmd(&runconfig.HealthConfig{Test: []string{"CMD-SHELL", "exit 1"}})
+	if len(cmd) != 3 || cmd[0] != "/bin/sh" || cmd[2] != "exit 1" {
+		t.Fatalf("Unexpected command %v", cmd)
+	}
+	cmd = healthcheckCmd(&runconfig.HealthConfig{Test: []string{"CMD", "true"}})
+	if len(cmd) != 1 || cmd[0] != "true" {
+		t.Fatalf("Unexpected command %v", cmd)
+	}
+}
+
+func TestAddHealthResult(t *testing.T) {
+	s := &State{}
+	s.SetHealth(&Health{Status: Starting})
+
+	if status, changed := s.addHealthResult(&HealthcheckResult{ExitCode: 1}, 2); changed || status != Starting {
+		t.Fatalf("Expected the container to still be starting, received %s", status)
+	}
+	if status, changed := s.addHealthResult(&HealthcheckResult{ExitCode: 1}, 2); !changed || status != Unhealthy {
+		t.Fatalf("Expected the container to be unhealthy, received %s", status)
+	}
+	if status, changed := s.addHealthResult(&HealthcheckResult{ExitCode: 0}, 2); !changed || status != Healthy {
+		t.Fatalf("Expected the container to be healthy, received %s", status)
+	}
+	for i := 0; i < maxHealthLogEntries; i++ {
+		s.addHealthResult(&HealthcheckResult{ExitCode: 0}, 2)
+	}
+	if len(s.Health.Log) != maxHealthLogEntries {
+		t.Fatalf("Expected %d log entries, received %d", maxHealthLogEntries, len(s.Health.Log))
+	}
+	if s.Health.FailingStreak != 0 {
+		t.Fatalf("Expected the failing streak to be reset, received %d", s.Health.FailingStreak)
+	}
+}
@@ -0,0 +1,94 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/dotcloud/docker/runtime/logger"
+	"github.com/dotcloud/docker/utils"
+	"net"
+	"strings"
+)
+
+const (
+	Name = "journald"
+
+	journalSocket = "/run/systemd/journal/socket"
+
+	// syslog priorities of the messages
+	priorityInfo = "6"
+	priorityErr  = "3"
+)
+
+func init() {
+	if err := logger.Register(Name, New); err != nil {
+		panic(err)
+	}
+}
+
+type field struct {
+	name  string
+	value string
+}
+
+// Journald sends the output of the container to the journal with the
+// native protocol of systemd-journald, one datagram per line
+type Journald struct {
+	conn   *net.UnixConn
+	fields []field // fields added to all the messages of the container
+}
+
+func New(ctx *logger.Context) (logger.Logger, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &Journald{
+		conn: conn,
+		fields: []field{
+			{"CONTAINER_ID", utils.TruncateID(ctx.ContainerID)},
+			{"CONTAINER_ID_FULL", ctx.ContainerID},
+			{"CONTAINER_NAME", strings.TrimPrefix(ctx.ContainerName, "/")},
+			{"SYSLOG_IDENTIFIER", "docker"},
+		},
+	}, nil
+}
+
+func (j *Journald) Name() string {
+	return Name
+}
+
+func (j *Journald) Log(msg *logger.Message) error {
+	priority := priorityInfo
+	if msg.Source == "stderr" {
+		priority = priorityErr
+	}
+	fields := append([]field{
+		{"MESSAGE", string(msg.Line)},
+		{"PRIORITY", priority},
+	}, j.fields...)
+	_, err := j.conn.Write(encodeFields(fields))
+	return err
+}
+
+func (j *Journald) Close() error {
+	return j.conn.Close()
+}
+
+// encodeFields serializes the fields of a journal entry. A value is written
+// after an equal sign, or after its length as a 64 bit little endian integer
+// when it contains line breaks.
+func encodeFields(fields []field) []byte {
+	buf := bytes.NewBuffer(nil)
+	for _, f := range fields {
+		buf.WriteString(f.name)
+		if strings.Contains(f.value, "\n") {
+			buf.WriteByte('\n')
+			binary.Write(buf, binary.LittleEndian, uint64(len(f.value)))
+		} else {
+			buf.WriteByte('=')
+		}
+		buf.WriteString(f.value)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,24 @@
+package journald
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeFields(t *testing.T) {
+	data := encodeFields([]field{
+		{"MESSAGE", "hello"},
+		{"PRIORITY", "6"},
+	})
+	if expected := "MESSAGE=hello\nPRIORITY=6\n"; string(data) != expected {
+		t.Fatalf("Expected %q, received %q", expected, data)
+	}
+}
+
+func TestEncodeFieldsWithLineBreak(t *testing.T) {
+	data := encodeFields([]field{{"MESSAGE", "a\nb"}})
+	expected := []byte("MESSAGE\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n")
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("Expected %q, received %q", expected, data)
+	}
+}
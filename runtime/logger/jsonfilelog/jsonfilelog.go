@@ -0,0 +1,183 @@
+package jsonfilelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dotcloud/docker/runtime/logger"
+	"github.com/dotcloud/docker/utils"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const Name = "json-file"
+
+func init() {
+	if err := logger.Register(Name, New); err != nil {
+		panic(err)
+	}
+	if err := logger.RegisterReader(Name, Read); err != nil {
+		panic(err)
+	}
+	if err := logger.RegisterLogOptValidator(Name, ValidateLogOpt); err != nil {
+		panic(err)
+	}
+}
+
+// JSONFileLogger writes the output of the container to its json log file,
+// one json encoded utils.JSONLog per line. When max-size is set, the file
+// is rotated to file.1, file.1 to file.2, and so on, before it grows larger,
+// and only max-file files are kept.
+type JSONFileLogger struct {
+	sync.Mutex
+	f        *os.File
+	path     string
+	size     int64 // size of the current file
+	maxSize  int64 // 0 when the file is never rotated
+	maxFiles int
+}
+
+func New(ctx *logger.Context) (logger.Logger, error) {
+	maxSize, maxFiles, err := parseLogOpt(ctx.Config)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(ctx.LogPath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &JSONFileLogger{
+		f:        f,
+		path:     ctx.LogPath,
+		size:     fi.Size(),
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}, nil
+}
+
+func (l *JSONFileLogger) Name() string {
+	return Name
+}
+
+func (l *JSONFileLogger) Log(msg *logger.Message) error {
+	data, err := json.Marshal(&utils.JSONLog{
+		Log:     string(msg.Line) + "\n",
+		Stream:  msg.Source,
+		Created: msg.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.Lock()
+	defer l.Unlock()
+
+	// a line larger than max-size still gets a file of its own
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(data)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.f.Write(data)
+	l.size += int64(n)
+	return err
+}
+
+// rotate shifts the rotated files by one, removing the one past max-file,
+// and starts a new file. The files are renamed and unlinked rather than
+// truncated, even when a single file is kept, so the readers which already
+// opened them keep reading the same content. Must be called with the lock held.
+func (l *JSONFileLogger) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	for i := l.maxFiles; i > 0; i-- {
+		if err := os.Rename(rotatedPath(l.path, i-1), rotatedPath(l.path, i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Remove(rotatedPath(l.path, l.maxFiles)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	l.f = f
+	l.size = 0
+	return nil
+}
+
+func (l *JSONFileLogger) Close() error {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.f.Close()
+}
+
+// Read opens the json log file of the container and the files rotated
+// from it, oldest first
+func Read(ctx *logger.Context) ([]*os.File, error) {
+	var files []*os.File
+	for i := 0; ; i++ {
+		f, err := os.Open(rotatedPath(ctx.LogPath, i))
+		if err != nil {
+			if os.IsNotExist(err) {
+				break
+			}
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, err
+		}
+		files = append([]*os.File{f}, files...)
+	}
+	return files, nil
+}
+
+// ValidateLogOpt checks the max-size and max-file options
+func ValidateLogOpt(config map[string]string) error {
+	_, _, err := parseLogOpt(config)
+	return err
+}
+
+// parseLogOpt returns the maximum size of a log file, 0 when unlimited,
+// and the number of log files to keep
+func parseLogOpt(config map[string]string) (int64, int, error) {
+	var (
+		maxSize  int64
+		maxFiles = 1
+		err      error
+	)
+	if s, exists := config["max-size"]; exists {
+		if maxSize, err = utils.RAMInBytes(s); err != nil {
+			return 0, 0, fmt.Errorf("Invalid max-size: %s", err)
+		}
+		if maxSize <= 0 {
+			return 0, 0, fmt.Errorf("Invalid max-size %s: it must be a positive size", s)
+		}
+	}
+	if s, exists := config["max-file"]; exists {
+		if maxSize == 0 {
+			return 0, 0, fmt.Errorf("max-file cannot be set without max-size")
+		}
+		if maxFiles, err = strconv.Atoi(s); err != nil || maxFiles < 1 {
+			return 0, 0, fmt.Errorf("Invalid max-file %s: it must be a positive number", s)
+		}
+	}
+	return maxSize, maxFiles, nil
+}
+
+// rotatedPath returns the path of the log file rotated n times
+func rotatedPath(path string, n int) string {
+	if n == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s.%d", path, n)
+}
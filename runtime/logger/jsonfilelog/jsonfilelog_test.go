@@ -0,0 +1,207 @@
+package jsonfilelog
+
+import (
+	"encoding/json"
+	"github.com/dotcloud/docker/runtime/logger"
+	"github.com/dotcloud/docker/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJSONFileLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonfilelog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := &logger.Context{LogPath: filepath.Join(dir, "container-json.log")}
+	l, err := New(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	if err := l.Log(&logger.Message{Line: []byte("hello"), Source: "stderr", Timestamp: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 log file, received %d", len(files))
+	}
+	defer files[0].Close()
+
+	entry := &utils.JSONLog{}
+	if err := json.NewDecoder(files[0]).Decode(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Log != "hello\n" || entry.Stream != "stderr" || !entry.Created.Equal(now) {
+		t.Fatalf("Unexpected log entry %#v", entry)
+	}
+}
+
+func TestJSONFileLoggerRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonfilelog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := &logger.Context{
+		Config:  map[string]string{"max-size": "1k", "max-file": "3"},
+		LogPath: filepath.Join(dir, "container-json.log"),
+	}
+	l, err := New(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each entry takes more than a third of max-size, so every
+	// other entry starts a new file
+	line := make([]byte, 400)
+	for i := range line {
+		line[i] = 'a'
+	}
+	for i := 0; i < 10; i++ {
+		line[0] = byte('0' + i)
+		if err := l.Log(&logger.Message{Line: line, Source: "stdout", Timestamp: time.Now().UTC()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(ctx.LogPath + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("Expected only 3 log files to be kept")
+	}
+
+	files, err := Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 log files, received %d", len(files))
+	}
+	// the files are read oldest first, the 4 oldest entries were dropped
+	expected := byte('4')
+	for _, f := range files {
+		defer f.Close()
+		dec := json.NewDecoder(f)
+		for {
+			entry := &utils.JSONLog{}
+			if err := dec.Decode(entry); err != nil {
+				break
+			}
+			if entry.Log[0] != expected {
+				t.Fatalf("Expected entry %c, received %c", expected, entry.Log[0])
+			}
+			expected++
+		}
+	}
+	if expected != '9'+1 {
+		t.Fatalf("Expected to read the entries up to 9, stopped before %c", expected)
+	}
+}
+
+func TestJSONFileLoggerRotationSingleFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonfilelog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// max-file defaults to 1 when only max-size is set
+	ctx := &logger.Context{
+		Config:  map[string]string{"max-size": "1k"},
+		LogPath: filepath.Join(dir, "container-json.log"),
+	}
+	l, err := New(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	line := make([]byte, 600)
+	for i := range line {
+		line[i] = 'a'
+	}
+	line[0] = '0'
+	if err := l.Log(&logger.Message{Line: line, Source: "stdout", Timestamp: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a reader opened before the rotation, like a follower of the logs
+	files, err := Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 log file, received %d", len(files))
+	}
+	defer files[0].Close()
+
+	line[0] = '1'
+	if err := l.Log(&logger.Message{Line: line, Source: "stdout", Timestamp: time.Now().UTC()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(ctx.LogPath + ".1"); !os.IsNotExist(err) {
+		t.Fatalf("Expected only 1 log file to be kept")
+	}
+
+	entry := &utils.JSONLog{}
+	if err := json.NewDecoder(files[0]).Decode(entry); err != nil {
+		t.Fatalf("Expected the open reader to keep the rotated content: %s", err)
+	}
+	if entry.Log[0] != '0' {
+		t.Fatalf("Expected entry 0 from the open reader, received %c", entry.Log[0])
+	}
+
+	rotated, err := Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("Expected 1 log file, received %d", len(rotated))
+	}
+	defer rotated[0].Close()
+	dec := json.NewDecoder(rotated[0])
+	entry = &utils.JSONLog{}
+	if err := dec.Decode(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Log[0] != '1' {
+		t.Fatalf("Expected entry 1 in the new file, received %c", entry.Log[0])
+	}
+	if err := dec.Decode(&utils.JSONLog{}); err == nil {
+		t.Fatalf("Expected the new file to only hold the new entry")
+	}
+}
+
+func TestValidateLogOpt(t *testing.T) {
+	for _, config := range []map[string]string{
+		{},
+		{"max-size": "10m"},
+		{"max-size": "10m", "max-file": "5"},
+	} {
+		if err := ValidateLogOpt(config); err != nil {
+			t.Fatalf("Unexpected error for %v: %s", config, err)
+		}
+	}
+	for _, config := range []map[string]string{
+		{"max-size": "ten"},
+		{"max-size": "0"},
+		{"max-file": "5"},
+		{"max-size": "10m", "max-file": "0"},
+	} {
+		if err := ValidateLogOpt(config); err == nil {
+			t.Fatalf("Expected an error for %v", config)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dotcloud/docker/utils"
+	"os"
+	"sync"
+	"time"
+)
+
+// Name of the driver which does not log anything
+const NoneDriver = "none"
+
+type InitFunc func(ctx *Context) (Logger, error)
+
+// ReadFunc opens the json log files written by a driver for the container,
+// oldest first. It is only registered by the drivers whose logs can be read back.
+type ReadFunc func(ctx *Context) ([]*os.File, error)
+
+// LogOptValidator checks the options given to a driver
+type LogOptValidator func(config map[string]string) error
+
+// Logger sends the output of a container to a logging backend
+type Logger interface {
+	Name() string
+	Log(msg *Message) error
+	Close() error
+}
+
+// Message is a line of the output of a container, without its line break
+type Message struct {
+	Line      []byte
+	Source    string // "stdout" or "stderr"
+	Timestamp time.Time
+}
+
+// Context holds what the drivers know about the container they log for
+type Context struct {
+	Config        map[string]string // options of the driver
+	ContainerID   string
+	ContainerName string
+	LogPath       string // path of the json log file of the container
+}
+
+var (
+	drivers    = make(map[string]InitFunc)
+	readers    = make(map[string]ReadFunc)
+	validators = make(map[string]LogOptValidator)
+)
+
+func Register(name string, initFunc InitFunc) error {
+	if _, exists := drivers[name]; exists {
+		return fmt.Errorf("Name already registered %s", name)
+	}
+	drivers[name] = initFunc
+	return nil
+}
+
+// RegisterReader registers the function reading back the logs of the driver name
+func RegisterReader(name string, readFunc ReadFunc) error {
+	if _, exists := readers[name]; exists {
+		return fmt.Errorf("Name already registered %s", name)
+	}
+	readers[name] = readFunc
+	return nil
+}
+
+// RegisterLogOptValidator registers the function checking the options of the driver name
+func RegisterLogOptValidator(name string, validator LogOptValidator) error {
+	if _, exists := validators[name]; exists {
+		return fmt.Errorf("Name already registered %s", name)
+	}
+	validators[name] = validator
+	return nil
+}
+
+// ValidateLogOpts checks the options of the driver name, when it validates them
+func ValidateLogOpts(name string, config map[string]string) error {
+	if validator, exists := validators[name]; exists {
+		return validator(config)
+	}
+	return nil
+}
+
+// ValidateDriver returns an error if no driver is registered for name
+func ValidateDriver(name string) error {
+	if _, exists := drivers[name]; !exists && name != NoneDriver {
+		return fmt.Errorf("No such log driver: %s", name)
+	}
+	return nil
+}
+
+// New returns the driver name for the container, or nil for the none driver
+func New(name string, ctx *Context) (Logger, error) {
+	if name == NoneDriver {
+		return nil, nil
+	}
+	initFunc, exists := drivers[name]
+	if !exists {
+		return nil, fmt.Errorf("No such log driver: %s", name)
+	}
+	return initFunc(ctx)
+}
+
+// ReadLogs opens the json log files written by the driver name for the container
+func ReadLogs(name string, ctx *Context) ([]*os.File, error) {
+	readFunc, exists := readers[name]
+	if !exists {
+		return nil, fmt.Errorf("The logs of the %s log driver cannot be read back", name)
+	}
+	return readFunc(ctx)
+}
+
+// Writer splits the output of a container written to it into
+// lines and sends them to its logger
+type Writer struct {
+	sync.Mutex
+	logger Logger
+	source string
+	buf    bytes.Buffer
+}
+
+func NewWriter(logger Logger, source string) *Writer {
+	return &Writer{
+		logger: logger,
+		source: source,
+	}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := make([]byte, i)
+		copy(line, w.buf.Next(i+1))
+		// a failing backend must not stop the output of the container
+		if err := w.log(line); err != nil {
+			utils.Errorf("Error logging to %s: %s", w.logger.Name(), err)
+		}
+	}
+	return len(p), nil
+}
+
+// Close logs the last line when it is not terminated by a line break,
+// the logger is closed by its owner
+func (w *Writer) Close() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	line := make([]byte, w.buf.Len())
+	copy(line, w.buf.Bytes())
+	w.buf.Reset()
+	return w.log(line)
+}
+
+func (w *Writer) log(line []byte) error {
+	return w.logger.Log(&Message{
+		Line:      line,
+		Source:    w.source,
+		Timestamp: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"testing"
+)
+
+type testLogger struct {
+	messages []*Message
+}
+
+func (l *testLogger) Name() string {
+	return "test"
+}
+
+func (l *testLogger) Log(msg *Message) error {
+	l.messages = append(l.messages, msg)
+	return nil
+}
+
+func (l *testLogger) Close() error {
+	return nil
+}
+
+func TestWriterSplitsLines(t *testing.T) {
+	l := &testLogger{}
+	w := NewWriter(l, "stdout")
+	for _, data := range []string{"first line\nsec", "ond line\n", "\nlast"} {
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"first line", "second line", "", "last"}
+	if len(l.messages) != len(expected) {
+		t.Fatalf("Expected %d messages, received %d", len(expected), len(l.messages))
+	}
+	for i, msg := range l.messages {
+		if string(msg.Line) != expected[i] {
+			t.Fatalf("Expected line %q, received %q", expected[i], msg.Line)
+		}
+		if msg.Source != "stdout" {
+			t.Fatalf("Expected the stdout source, received %s", msg.Source)
+		}
+	}
+}
+
+func TestNoneDriver(t *testing.T) {
+	l, err := New(NoneDriver, &Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l != nil {
+		t.Fatalf("Expected no logger for the none driver, received %s", l.Name())
+	}
+	if err := ValidateDriver("unknown"); err == nil {
+		t.Fatal("Expected an error for an unknown driver")
+	}
+}
@@ -0,0 +1,80 @@
+package syslog
+
+import (
+	"fmt"
+	"github.com/dotcloud/docker/runtime/logger"
+	"github.com/dotcloud/docker/utils"
+	"log/syslog"
+	"net/url"
+)
+
+const Name = "syslog"
+
+func init() {
+	if err := logger.Register(Name, New); err != nil {
+		panic(err)
+	}
+}
+
+// Syslog sends the output of the container to syslog, stdout with the
+// info priority and stderr with the err priority
+type Syslog struct {
+	writer *syslog.Writer
+}
+
+// New connects to the syslog-address option of the driver:
+// unix:///path/to/socket or udp://host:port, the local syslog by default
+func New(ctx *logger.Context) (logger.Logger, error) {
+	network, address, err := parseAddress(ctx.Config["syslog-address"])
+	if err != nil {
+		return nil, err
+	}
+	tag := "docker/" + utils.TruncateID(ctx.ContainerID)
+	priority := syslog.LOG_DAEMON | syslog.LOG_INFO
+	writer, err := syslog.Dial(network, address, priority, tag)
+	if err != nil && network == "unixgram" {
+		// like for the local syslog, the socket can be a stream socket too
+		writer, err = syslog.Dial("unix", address, priority, tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Syslog{writer: writer}, nil
+}
+
+func (s *Syslog) Name() string {
+	return Name
+}
+
+func (s *Syslog) Log(msg *logger.Message) error {
+	if msg.Source == "stderr" {
+		return s.writer.Err(string(msg.Line))
+	}
+	return s.writer.Info(string(msg.Line))
+}
+
+func (s *Syslog) Close() error {
+	return s.writer.Close()
+}
+
+// parseAddress returns the network and address given to syslog.Dial,
+// both are empty for the local syslog
+func parseAddress(address string) (string, string, error) {
+	if address == "" {
+		return "", "", nil
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case "unix":
+		return "unixgram", u.Path, nil
+	case "udp":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("Missing host in syslog address: %s", address)
+		}
+		return "udp", u.Host, nil
+	}
+	return "", "", fmt.Errorf("Unsupported syslog address %s: the protocol must be unix or udp", address)
+}
@@ -0,0 +1,28 @@
+package syslog
+
+import (
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	for address, expected := range map[string][2]string{
+		"":                       {"", ""},
+		"unix:///dev/log":        {"unixgram", "/dev/log"},
+		"udp://10.0.0.1:514":     {"udp", "10.0.0.1:514"},
+		"udp://logs.example.com": {"udp", "logs.example.com"},
+	} {
+		network, addr, err := parseAddress(address)
+		if err != nil {
+			t.Fatalf("%s: %s", address, err)
+		}
+		if network != expected[0] || addr != expected[1] {
+			t.Fatalf("%s: expected %s %s, received %s %s", address, expected[0], expected[1], network, addr)
+		}
+	}
+
+	for _, address := range []string{"tcp://10.0.0.1:514", "udp://", "/dev/log"} {
+		if _, _, err := parseAddress(address); err == nil {
+			t.Fatalf("Expected an error for %s", address)
+		}
+	}
+}
@@ -5,27 +5,35 @@ import (
 	"errors"
 	"github.com/dotcloud/docker/pkg/collections"
 	"github.com/dotcloud/docker/runtime/networkdriver"
+	"math/big"
 	"net"
 	"sync"
 )
 
+// maxHostBits limits the addresses allocated on the large IPv6
+// networks to the first 2^32 of the network
+const maxHostBits = 32
+
 type networkSet map[string]*collections.OrderedIntSet
 
 var (
 	ErrNoAvailableIPs     = errors.New("no available ip addresses on network")
 	ErrIPAlreadyAllocated = errors.New("ip already allocated")
+	ErrIPOutOfRange       = errors.New("requested ip is out of range")
+	ErrIPAlreadyReserved  = errors.New("ip already reserved")
 )
 
 var (
 	lock         = sync.Mutex{}
 	allocatedIPs = networkSet{}
 	availableIPS = networkSet{}
+	reservedIPs  = networkSet{} // only returned when they are requested
 )
 
 // RequestIP requests an available ip from the given network.  It
 // will return the next available ip if the ip provided is nil.  If the
-// ip provided is not nil it will validate that the provided ip is available
-// for use or return an error
+// ip provided is not nil it will validate that the provided ip is in the
+// network and available for use or return an error
 func RequestIP(address *net.IPNet, ip *net.IP) (*net.IP, error) {
 	lock.Lock()
 	defer lock.Unlock()
@@ -60,79 +68,153 @@ func ReleaseIP(address *net.IPNet, ip *net.IP) error {
 		pos       = getPosition(address, ip)
 	)
 
-	existing.Remove(int(pos))
-	available.Push(int(pos))
+	existing.Remove(pos)
+	if !reservedIPs[address.String()].Exists(pos) {
+		available.Push(pos)
+	}
 
 	return nil
 }
 
-// convert the ip into the position in the subnet.  Only
-// position are saved in the set
-func getPosition(address *net.IPNet, ip *net.IP) int32 {
+// ReserveIP keeps the provided ip out of the ips returned for the
+// requests without an ip until it is unreserved, the ip is still
+// returned when it is requested
+func ReserveIP(address *net.IPNet, ip *net.IP) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	checkAddress(address)
+
+	first, last := networkdriver.NetworkRange(address)
+	if !address.Contains(*ip) || ip.Equal(first) || ip.Equal(last) || ip.Equal(address.IP) {
+		// only the addresses which can be allocated are reserved
+		return ErrIPOutOfRange
+	}
+	var (
+		reserved = reservedIPs[address.String()]
+		pos      = getPosition(address, ip)
+	)
+	if reserved.Exists(pos) {
+		return ErrIPAlreadyReserved
+	}
+	reserved.Push(pos)
+	availableIPS[address.String()].Remove(pos)
+
+	return nil
+}
+
+// UnreserveIP returns the provided ip to the ips returned for the
+// requests without an ip once it is released
+func UnreserveIP(address *net.IPNet, ip *net.IP) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	checkAddress(address)
+
 	var (
-		first, _ = networkdriver.NetworkRange(address)
-		base     = ipToInt(&first)
-		i        = ipToInt(ip)
+		reserved = reservedIPs[address.String()]
+		pos      = getPosition(address, ip)
 	)
-	return i - base
+	if !reserved.Exists(pos) {
+		return nil
+	}
+	reserved.Remove(pos)
+	if !allocatedIPs[address.String()].Exists(pos) {
+		availableIPS[address.String()].Push(pos)
+	}
+
+	return nil
+}
+
+// convert the ip into the position in the subnet.  Only
+// position are saved in the set
+func getPosition(address *net.IPNet, ip *net.IP) int {
+	first, _ := networkdriver.NetworkRange(address)
+	if ip.To4() != nil {
+		return int(ipToInt(ip) - ipToInt(&first))
+	}
+	return int(new(big.Int).Sub(ipv6ToInt(*ip), ipv6ToInt(first)).Int64())
+}
+
+// getIP converts the position in the subnet back into the ip
+func getIP(address *net.IPNet, pos int) *net.IP {
+	first, _ := networkdriver.NetworkRange(address)
+	if first.To4() != nil {
+		return intToIP(ipToInt(&first) + int32(pos))
+	}
+	return intToIPv6(new(big.Int).Add(ipv6ToInt(first), big.NewInt(int64(pos))))
+}
+
+// networkSize returns the number of addresses of the network, at
+// most 2^maxHostBits
+func networkSize(mask net.IPMask) int {
+	ones, bits := mask.Size()
+	if bits-ones > maxHostBits {
+		return 1 << maxHostBits
+	}
+	return 1 << uint(bits-ones)
 }
 
 // return an available ip if one is currently available.  If not,
 // return the next available ip for the nextwork
 func getNextIp(address *net.IPNet) (*net.IP, error) {
 	var (
-		ownIP     = ipToInt(&address.IP)
+		ownPos    = getPosition(address, &address.IP)
 		available = availableIPS[address.String()]
 		allocated = allocatedIPs[address.String()]
-		first, _  = networkdriver.NetworkRange(address)
-		base      = ipToInt(&first)
-		size      = int(networkdriver.NetworkSize(address.Mask))
-		max       = int32(size - 2) // size -1 for the broadcast address, -1 for the gateway address
-		pos       = int32(available.Pop())
+		reserved  = reservedIPs[address.String()]
+		max       = networkSize(address.Mask) - 2 // size -1 for the broadcast address, -1 for the gateway address
+		pos       = available.Pop()
 	)
 
 	// We pop and push the position not the ip
 	if pos != 0 {
-		ip := intToIP(int32(base + pos))
-		allocated.Push(int(pos))
-
-		return ip, nil
+		allocated.Push(pos)
+		return getIP(address, pos), nil
 	}
 
-	var (
-		firstNetIP = address.IP.To4().Mask(address.Mask)
-		firstAsInt = ipToInt(&firstNetIP) + 1
-	)
-
-	pos = int32(allocated.PullBack())
-	for i := int32(0); i < max; i++ {
+	pos = allocated.PullBack()
+	for i := 0; i < max; i++ {
 		pos = pos%max + 1
-		next := int32(base + pos)
 
-		if next == ownIP || next == firstAsInt {
+		// the first address of the network is skipped as well
+		if pos == ownPos || pos == 1 {
 			continue
 		}
 
-		if !allocated.Exists(int(pos)) {
-			ip := intToIP(next)
-			allocated.Push(int(pos))
-			return ip, nil
+		if !allocated.Exists(pos) && !reserved.Exists(pos) {
+			allocated.Push(pos)
+			return getIP(address, pos), nil
 		}
 	}
 	return nil, ErrNoAvailableIPs
 }
 
 func registerIP(address *net.IPNet, ip *net.IP) error {
+	if !address.Contains(*ip) {
+		return ErrIPOutOfRange
+	}
+	first, last := networkdriver.NetworkRange(address)
+	if ip.Equal(first) || ip.Equal(last) {
+		// the network and broadcast addresses
+		return ErrIPOutOfRange
+	}
+	if ip.Equal(address.IP) {
+		// the address of the gateway
+		return ErrIPAlreadyAllocated
+	}
+
 	var (
 		existing  = allocatedIPs[address.String()]
 		available = availableIPS[address.String()]
 		pos       = getPosition(address, ip)
 	)
 
-	if existing.Exists(int(pos)) {
+	if existing.Exists(pos) {
 		return ErrIPAlreadyAllocated
 	}
-	available.Remove(int(pos))
+	available.Remove(pos)
+	existing.Push(pos)
 
 	return nil
 }
@@ -150,10 +232,24 @@ func intToIP(n int32) *net.IP {
 	return &ip
 }
 
+// Converts a 16 bytes IPv6 address into an integer
+func ipv6ToInt(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+// Converts an integer into a 16 bytes IPv6 address
+func intToIPv6(n *big.Int) *net.IP {
+	b := n.Bytes()
+	ip := make(net.IP, net.IPv6len)
+	copy(ip[net.IPv6len-len(b):], b)
+	return &ip
+}
+
 func checkAddress(address *net.IPNet) {
 	key := address.String()
 	if _, exists := allocatedIPs[key]; !exists {
 		allocatedIPs[key] = collections.NewOrderedIntSet()
 		availableIPS[key] = collections.NewOrderedIntSet()
+		reservedIPs[key] = collections.NewOrderedIntSet()
 	}
 }
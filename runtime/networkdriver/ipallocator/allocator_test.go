@@ -9,6 +9,7 @@ import (
 func reset() {
 	allocatedIPs = networkSet{}
 	availableIPS = networkSet{}
+	reservedIPs = networkSet{}
 }
 
 func TestRequestNewIps(t *testing.T) {
@@ -81,11 +82,102 @@ func TestRequesetSpecificIp(t *testing.T) {
 		Mask: []byte{255, 255, 255, 0},
 	}
 
-	ip := net.ParseIP("192.168.1.5")
+	ip := net.ParseIP("192.168.0.5")
 
 	if _, err := RequestIP(network, &ip); err != nil {
 		t.Fatal(err)
 	}
+	if _, err := RequestIP(network, &ip); err != ErrIPAlreadyAllocated {
+		t.Fatalf("Expected %s requesting %s twice, got %v", ErrIPAlreadyAllocated, ip, err)
+	}
+
+	// the next ips skip the requested one
+	for i := 2; i < 6; i++ {
+		next, err := RequestIP(network, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.Equal(ip) {
+			t.Fatalf("Expected %s to be allocated once", ip)
+		}
+	}
+}
+
+func TestReserveIp(t *testing.T) {
+	defer reset()
+	network := &net.IPNet{
+		IP:   []byte{192, 168, 0, 1},
+		Mask: []byte{255, 255, 255, 0},
+	}
+	reserved := net.ParseIP("192.168.0.2")
+
+	if err := ReserveIP(network, &reserved); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReserveIP(network, &reserved); err != ErrIPAlreadyReserved {
+		t.Fatalf("Expected error %s got %v", ErrIPAlreadyReserved, err)
+	}
+
+	// the reserved ip is skipped for the requests without an ip
+	ip, err := RequestIP(network, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "192.168.0.3"; ip.String() != expected {
+		t.Fatalf("Expected ip %s got %s", expected, ip.String())
+	}
+
+	// it is still returned when it is requested, and kept once released
+	ip, err = RequestIP(network, &reserved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ReleaseIP(network, ip); err != nil {
+		t.Fatal(err)
+	}
+	ip, err = RequestIP(network, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "192.168.0.4"; ip.String() != expected {
+		t.Fatalf("Expected ip %s got %s", expected, ip.String())
+	}
+
+	if err := UnreserveIP(network, &reserved); err != nil {
+		t.Fatal(err)
+	}
+	ip, err = RequestIP(network, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(reserved) {
+		t.Fatalf("Expected ip %s got %s", reserved, ip)
+	}
+
+	outOfRange := net.ParseIP("192.168.1.2")
+	if err := ReserveIP(network, &outOfRange); err != ErrIPOutOfRange {
+		t.Fatalf("Expected error %s got %v", ErrIPOutOfRange, err)
+	}
+}
+
+func TestRequestInvalidSpecificIp(t *testing.T) {
+	defer reset()
+	network := &net.IPNet{
+		IP:   []byte{192, 168, 0, 1},
+		Mask: []byte{255, 255, 255, 0},
+	}
+
+	for rawIP, expected := range map[string]error{
+		"192.168.1.5":   ErrIPOutOfRange,
+		"192.168.0.0":   ErrIPOutOfRange,
+		"192.168.0.255": ErrIPOutOfRange,
+		"192.168.0.1":   ErrIPAlreadyAllocated,
+	} {
+		ip := net.ParseIP(rawIP)
+		if _, err := RequestIP(network, &ip); err != expected {
+			t.Fatalf("Expected %s requesting %s, got %v", expected, ip, err)
+		}
+	}
 }
 
 func TestConversion(t *testing.T) {
@@ -234,6 +326,32 @@ func TestAllocateFirstIP(t *testing.T) {
 	}
 }
 
+func TestRequestNewIPv6s(t *testing.T) {
+	defer reset()
+	gwIP, n, _ := net.ParseCIDR("2001:db8::1/64")
+	network := &net.IPNet{IP: gwIP, Mask: n.Mask}
+
+	for i := 2; i < 20; i++ {
+		ip, err := RequestIP(network, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := fmt.Sprintf("2001:db8::%x", i); ip.String() != expected {
+			t.Fatalf("Expected ip %s got %s", expected, ip.String())
+		}
+	}
+
+	released := net.ParseIP("2001:db8::5")
+	if err := ReleaseIP(network, &released); err != nil {
+		t.Fatal(err)
+	}
+	ip, err := RequestIP(network, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIPEquals(t, &released, ip)
+}
+
 func assertIPEquals(t *testing.T, ip1, ip2 *net.IP) {
 	if !ip1.Equal(*ip2) {
 		t.Fatalf("Expected IP %s, got %s", ip1, ip2)
@@ -13,6 +13,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"unsafe"
@@ -26,7 +27,10 @@ const (
 // Network interface represents the networking stack of a container
 type networkInterface struct {
 	IP           net.IP
+	IPv6         net.IP     // nil when IPv6 is disabled
 	PortMappings []net.Addr // there are mappings to the host interfaces
+	PortEnds     []int      // last host port of each mapping, a range when after its port
+	network      *network
 }
 
 var (
@@ -50,8 +54,9 @@ var (
 		"192.168.44.1/24",
 	}
 
-	bridgeIface   string
-	bridgeNetwork *net.IPNet
+	bridgeIface     string
+	bridgeNetwork   *net.IPNet
+	bridgeNetworkV6 *net.IPNet // IPv6 address of the bridge, nil when IPv6 is disabled
 
 	defaultBindingIP  = net.ParseIP("0.0.0.0")
 	currentInterfaces = make(map[string]*networkInterface)
@@ -59,12 +64,16 @@ var (
 
 func InitDriver(job *engine.Job) engine.Status {
 	var (
-		network        *net.IPNet
-		enableIPTables = job.GetenvBool("EnableIptables")
-		icc            = job.GetenvBool("InterContainerCommunication")
-		ipForward      = job.GetenvBool("EnableIpForward")
-		bridgeIP       = job.Getenv("BridgeIP")
+		network    *net.IPNet
+		ipForward  = job.GetenvBool("EnableIpForward")
+		bridgeIP   = job.Getenv("BridgeIP")
+		bridgeIPv6 = job.Getenv("BridgeIPv6")
 	)
+	enableIPTables = job.GetenvBool("EnableIptables")
+	icc = job.GetenvBool("InterContainerCommunication")
+	if root := job.Getenv("Root"); root != "" {
+		networksPath = filepath.Join(root, "networks.json")
+	}
 
 	if defaultIP := job.Getenv("DefaultBindingIP"); defaultIP != "" {
 		defaultBindingIP = net.ParseIP(defaultIP)
@@ -79,7 +88,7 @@ func InitDriver(job *engine.Job) engine.Status {
 	if err != nil {
 		// If the iface is not found, try to create it
 		job.Logf("creating new bridge for %s", bridgeIface)
-		if err := createBridge(bridgeIP); err != nil {
+		if err := createBridge(bridgeIface, bridgeIP); err != nil {
 			job.Error(err)
 			return engine.StatusErr
 		}
@@ -95,12 +104,23 @@ func InitDriver(job *engine.Job) engine.Status {
 		network = addr.(*net.IPNet)
 	}
 
+	if bridgeIPv6 != "" {
+		if bridgeNetworkV6, err = setupBridgeIPv6(bridgeIface, bridgeIPv6); err != nil {
+			return job.Error(err)
+		}
+	}
+
 	// Configure iptables for link support
 	if enableIPTables {
-		if err := setupIPTables(addr, icc); err != nil {
+		if err := setupIPTables(bridgeIface, addr, icc); err != nil {
 			job.Error(err)
 			return engine.StatusErr
 		}
+		if bridgeNetworkV6 != nil {
+			if err := setupIP6Tables(bridgeIface, icc); err != nil {
+				return job.Error(err)
+			}
+		}
 	}
 
 	if ipForward {
@@ -108,6 +128,11 @@ func InitDriver(job *engine.Job) engine.Status {
 		if err := ioutil.WriteFile("/proc/sys/net/ipv4/ip_forward", []byte{'1', '\n'}, 0644); err != nil {
 			job.Logf("WARNING: unable to enable IPv4 forwarding: %s\n", err)
 		}
+		if bridgeNetworkV6 != nil {
+			if err := ioutil.WriteFile("/proc/sys/net/ipv6/conf/all/forwarding", []byte{'1', '\n'}, 0644); err != nil {
+				job.Logf("WARNING: unable to enable IPv6 forwarding: %s\n", err)
+			}
+		}
 	}
 
 	// We can always try removing the iptables
@@ -115,6 +140,11 @@ func InitDriver(job *engine.Job) engine.Status {
 		job.Error(err)
 		return engine.StatusErr
 	}
+	if bridgeNetworkV6 != nil {
+		if err := iptables.RemoveExistingChainV6("DOCKER"); err != nil {
+			return job.Error(err)
+		}
+	}
 
 	if enableIPTables {
 		chain, err := iptables.NewChain("DOCKER", bridgeIface)
@@ -123,6 +153,14 @@ func InitDriver(job *engine.Job) engine.Status {
 			return engine.StatusErr
 		}
 		portmapper.SetIptablesChain(chain)
+
+		if bridgeNetworkV6 != nil {
+			chain6, err := iptables.NewChainV6("DOCKER", bridgeIface)
+			if err != nil {
+				return job.Error(err)
+			}
+			portmapper.SetIp6tablesChain(chain6)
+		}
 	}
 
 	bridgeNetwork = network
@@ -130,11 +168,30 @@ func InitDriver(job *engine.Job) engine.Status {
 	// https://github.com/dotcloud/docker/issues/2768
 	job.Eng.Hack_SetGlobalVar("httpapi.bridgeIP", bridgeNetwork.IP)
 
+	if err := loadNetworks(); err != nil {
+		job.Error(err)
+		return engine.StatusErr
+	}
+	if err := setupIsolation(); err != nil {
+		job.Error(err)
+		return engine.StatusErr
+	}
+	if err := saveNetworks(); err != nil {
+		job.Error(err)
+		return engine.StatusErr
+	}
+
 	for name, f := range map[string]engine.Handler{
 		"allocate_interface": Allocate,
 		"release_interface":  Release,
+		"reserve_ip":         ReserveIP,
+		"unreserve_ip":       UnreserveIP,
 		"allocate_port":      AllocatePort,
 		"link":               LinkContainers,
+		"network_create":     CreateNetwork,
+		"network_ls":         ListNetworks,
+		"network_inspect":    InspectNetwork,
+		"network_rm":         RemoveNetwork,
 	} {
 		if err := job.Eng.Register(name, f); err != nil {
 			job.Error(err)
@@ -144,7 +201,7 @@ func InitDriver(job *engine.Job) engine.Status {
 	return engine.StatusOK
 }
 
-func setupIPTables(addr net.Addr, icc bool) error {
+func setupIPTables(bridgeIface string, addr net.Addr, icc bool) error {
 	// Enable NAT
 	natArgs := []string{"POSTROUTING", "-t", "nat", "-s", addr.String(), "!", "-d", addr.String(), "-j", "MASQUERADE"}
 
@@ -209,10 +266,75 @@ func setupIPTables(addr net.Addr, icc bool) error {
 	return nil
 }
 
-// CreateBridgeIface creates a network bridge interface on the host system with the name `ifaceName`,
+// setupIP6Tables sets up the FORWARD rules of setupIPTables for IPv6, the
+// containers have global addresses and their packets are not masqueraded
+func setupIP6Tables(bridgeIface string, icc bool) error {
+	var (
+		args       = []string{"FORWARD", "-i", bridgeIface, "-o", bridgeIface, "-j"}
+		acceptArgs = append(args, "ACCEPT")
+		dropArgs   = append(args, "DROP")
+		rules      = [][]string{
+			// Accept all non-intercontainer outgoing packets
+			{"FORWARD", "-i", bridgeIface, "!", "-o", bridgeIface, "-j", "ACCEPT"},
+			// Accept incoming packets for existing connections
+			{"FORWARD", "-o", bridgeIface, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"},
+		}
+	)
+	if icc {
+		iptables.Raw6(append([]string{"-D"}, dropArgs...)...)
+		rules = append(rules, acceptArgs)
+	} else {
+		iptables.Raw6(append([]string{"-D"}, acceptArgs...)...)
+		rules = append(rules, dropArgs)
+	}
+
+	for _, rule := range rules {
+		if iptables.Exists6(rule...) {
+			continue
+		}
+		if output, err := iptables.Raw6(append([]string{"-I"}, rule...)...); err != nil {
+			return fmt.Errorf("Unable to set up the IPv6 forwarding of %s: %s", bridgeIface, err)
+		} else if len(output) != 0 {
+			return fmt.Errorf("Error ip6tables forward: %s", output)
+		}
+	}
+	return nil
+}
+
+// setupBridgeIPv6 adds the IPv6 address in CIDR notation to the bridge
+// unless the bridge already has it, and returns the address and its subnet
+func setupBridgeIPv6(bridgeIface, bridgeIPv6 string) (*net.IPNet, error) {
+	ip, ipNet, err := net.ParseCIDR(bridgeIPv6)
+	if err != nil {
+		return nil, err
+	}
+	if ip.To4() != nil {
+		return nil, fmt.Errorf("%s is not an IPv6 address", bridgeIPv6)
+	}
+	iface, err := net.InterfaceByName(bridgeIface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	bridgeNetwork := &net.IPNet{IP: ip, Mask: ipNet.Mask}
+	for _, addr := range addrs {
+		if a, ok := addr.(*net.IPNet); ok && a.IP.Equal(ip) {
+			return bridgeNetwork, nil
+		}
+	}
+	if err := netlink.NetworkLinkAddIp(iface, ip, ipNet); err != nil {
+		return nil, fmt.Errorf("Unable to add the IPv6 address to the bridge: %s", err)
+	}
+	return bridgeNetwork, nil
+}
+
+// CreateBridgeIface creates a network bridge interface on the host system with the name `bridgeIface`,
 // and attempts to configure it with an address which doesn't conflict with any other interface on the host.
 // If it can't find an address which doesn't conflict, it will return an error.
-func createBridge(bridgeIP string) error {
+func createBridge(bridgeIface, bridgeIP string) error {
 	nameservers := []string{}
 	resolvConf, _ := utils.GetResolvConf()
 	// we don't check for an error here, because we don't really care
@@ -302,16 +424,28 @@ func createBridgeIface(name string) error {
 // Allocate a network interface
 func Allocate(job *engine.Job) engine.Status {
 	var (
-		ip          *net.IP
-		err         error
-		id          = job.Args[0]
-		requestedIP = net.ParseIP(job.Getenv("RequestedIP"))
+		ip            *net.IP
+		ipv6          *net.IP
+		err           error
+		id            = job.Args[0]
+		requestedIP   = net.ParseIP(job.Getenv("RequestedIP"))
+		requestedIPv6 = net.ParseIP(job.Getenv("RequestedIPv6"))
 	)
 
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	n, err := getNetwork(job.Getenv("Network"))
+	if err != nil {
+		return job.Error(err)
+	}
+
 	if requestedIP != nil {
-		ip, err = ipallocator.RequestIP(bridgeNetwork, &requestedIP)
+		if ip, err = ipallocator.RequestIP(n.ipNet, &requestedIP); err != nil {
+			return job.Errorf("Cannot assign the address %s in the network %s (%s): %s", requestedIP, n.Name, n.Subnet, err)
+		}
 	} else {
-		ip, err = ipallocator.RequestIP(bridgeNetwork, nil)
+		ip, err = ipallocator.RequestIP(n.ipNet, nil)
 	}
 	if err != nil {
 		job.Error(err)
@@ -320,16 +454,36 @@ func Allocate(job *engine.Job) engine.Status {
 
 	out := engine.Env{}
 	out.Set("IP", ip.String())
-	out.Set("Mask", bridgeNetwork.Mask.String())
-	out.Set("Gateway", bridgeNetwork.IP.String())
-	out.Set("Bridge", bridgeIface)
+	out.Set("Mask", n.ipNet.Mask.String())
+	out.Set("Gateway", n.ipNet.IP.String())
+	out.Set("Bridge", n.Bridge)
+	out.Set("Network", n.Name)
 
-	size, _ := bridgeNetwork.Mask.Size()
+	size, _ := n.ipNet.Mask.Size()
 	out.SetInt("IPPrefixLen", size)
 
-	currentInterfaces[id] = &networkInterface{
-		IP: *ip,
+	iface := &networkInterface{
+		IP:      *ip,
+		network: n,
 	}
+	if n.ipNetV6 != nil {
+		if requestedIPv6 != nil {
+			ipv6, err = ipallocator.RequestIP(n.ipNetV6, &requestedIPv6)
+		} else {
+			ipv6, err = ipallocator.RequestIP(n.ipNetV6, nil)
+		}
+		if err != nil {
+			ipallocator.ReleaseIP(n.ipNet, ip)
+			return job.Error(err)
+		}
+		iface.IPv6 = *ipv6
+
+		out.Set("GlobalIPv6", ipv6.String())
+		out.Set("IPv6Gateway", n.ipNetV6.IP.String())
+		sizeV6, _ := n.ipNetV6.Mask.Size()
+		out.SetInt("GlobalIPv6PrefixLen", sizeV6)
+	}
+	currentInterfaces[id] = iface
 
 	out.WriteTo(job.Stdout)
 
@@ -350,7 +504,11 @@ func Release(job *engine.Job) engine.Status {
 		return job.Errorf("No network information to release for %s", id)
 	}
 
-	for _, nat := range containerInterface.PortMappings {
+	networksLock.Lock()
+	delete(currentInterfaces, id)
+	networksLock.Unlock()
+
+	for i, nat := range containerInterface.PortMappings {
 		if err := portmapper.Unmap(nat); err != nil {
 			log.Printf("Unable to unmap port %s: %s", nat, err)
 		}
@@ -367,18 +525,68 @@ func Release(job *engine.Job) engine.Status {
 			port = a.Port
 		}
 
-		if err := portallocator.ReleasePort(ip, proto, port); err != nil {
-			log.Printf("Unable to release port %s", nat)
+		for end := containerInterface.PortEnds[i]; port <= end; port++ {
+			if err := portallocator.ReleasePort(ip, proto, port); err != nil {
+				log.Printf("Unable to release port %s", nat)
+			}
 		}
 	}
 
-	if err := ipallocator.ReleaseIP(bridgeNetwork, &containerInterface.IP); err != nil {
+	if err := ipallocator.ReleaseIP(containerInterface.network.ipNet, &containerInterface.IP); err != nil {
 		log.Printf("Unable to release ip %s\n", err)
 	}
+	if containerInterface.IPv6 != nil {
+		if err := ipallocator.ReleaseIP(containerInterface.network.ipNetV6, &containerInterface.IPv6); err != nil {
+			log.Printf("Unable to release ipv6 %s\n", err)
+		}
+	}
+	return engine.StatusOK
+}
+
+// ReserveIP keeps the address IP of the network Network for the container
+// which requested it, the address is not allocated to the containers which
+// did not request one
+func ReserveIP(job *engine.Job) engine.Status {
+	ip := net.ParseIP(job.Getenv("IP"))
+	if ip == nil {
+		return job.Errorf("Invalid address %s", job.Getenv("IP"))
+	}
+
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	n, err := getNetwork(job.Getenv("Network"))
+	if err != nil {
+		return job.Error(err)
+	}
+	if err := ipallocator.ReserveIP(n.ipNet, &ip); err != nil {
+		return job.Errorf("Cannot reserve the address %s in the network %s (%s): %s", ip, n.Name, n.Subnet, err)
+	}
+	return engine.StatusOK
+}
+
+// UnreserveIP gives back the address IP of the network Network reserved by
+// ReserveIP
+func UnreserveIP(job *engine.Job) engine.Status {
+	ip := net.ParseIP(job.Getenv("IP"))
+	if ip == nil {
+		return job.Errorf("Invalid address %s", job.Getenv("IP"))
+	}
+
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	n, err := getNetwork(job.Getenv("Network"))
+	if err != nil {
+		return job.Error(err)
+	}
+	if err := ipallocator.UnreserveIP(n.ipNet, &ip); err != nil {
+		return job.Error(err)
+	}
 	return engine.StatusOK
 }
 
-// Allocate an external port and map it to the interface
+// Allocate an external port, or a range of ports, and map it to the interface
 func AllocatePort(job *engine.Job) engine.Status {
 	var (
 		err error
@@ -387,6 +595,7 @@ func AllocatePort(job *engine.Job) engine.Status {
 		id            = job.Args[0]
 		hostIP        = job.Getenv("HostIP")
 		hostPort      = job.GetenvInt("HostPort")
+		hostPortEnd   = job.GetenvInt("HostPortEnd") // the last port of a range from HostPort
 		containerPort = job.GetenvInt("ContainerPort")
 		proto         = job.Getenv("Proto")
 		network       = currentInterfaces[id]
@@ -396,33 +605,60 @@ func AllocatePort(job *engine.Job) engine.Status {
 		ip = net.ParseIP(hostIP)
 	}
 
+	// the ports published on an IPv6 address of the host
+	// are forwarded to the IPv6 address of the container
+	containerIP := network.IP
+	if ip.To4() == nil {
+		if network.IPv6 == nil {
+			return job.Errorf("Cannot publish the port %d on %s: the container has no IPv6 address", containerPort, ip)
+		}
+		containerIP = network.IPv6
+	}
+
 	// host ip, proto, and host port
-	hostPort, err = portallocator.RequestPort(ip, proto, hostPort)
-	if err != nil {
-		job.Error(err)
-		return engine.StatusErr
+	if hostPortEnd > hostPort {
+		// the whole range is allocated or none of its ports
+		if err := portallocator.RequestPortRange(ip, proto, hostPort, hostPortEnd); err != nil {
+			return job.Error(err)
+		}
+	} else {
+		hostPort, err = portallocator.RequestPort(ip, proto, hostPort)
+		if err != nil {
+			job.Error(err)
+			return engine.StatusErr
+		}
+		hostPortEnd = hostPort
 	}
 
 	var (
 		container net.Addr
 		host      net.Addr
 	)
-
 	if proto == "tcp" {
 		host = &net.TCPAddr{IP: ip, Port: hostPort}
-		container = &net.TCPAddr{IP: network.IP, Port: containerPort}
+		container = &net.TCPAddr{IP: containerIP, Port: containerPort}
 	} else {
 		host = &net.UDPAddr{IP: ip, Port: hostPort}
-		container = &net.UDPAddr{IP: network.IP, Port: containerPort}
+		container = &net.UDPAddr{IP: containerIP, Port: containerPort}
 	}
 
-	if err := portmapper.Map(container, ip, hostPort); err != nil {
-		portallocator.ReleasePort(ip, proto, hostPort)
+	// the container ports of a range are mapped from the host ports at the
+	// same offset, by a single rule without userland proxies
+	if hostPortEnd > hostPort {
+		err = portmapper.MapRange(container, ip, hostPort, hostPortEnd, network.network.Bridge)
+	} else {
+		err = portmapper.MapBridge(container, ip, hostPort, network.network.Bridge)
+	}
+	if err != nil {
+		for port := hostPort; port <= hostPortEnd; port++ {
+			portallocator.ReleasePort(ip, proto, port)
+		}
 
 		job.Error(err)
 		return engine.StatusErr
 	}
 	network.PortMappings = append(network.PortMappings, host)
+	network.PortEnds = append(network.PortEnds, hostPortEnd)
 
 	out := engine.Env{}
 	out.Set("HostIP", ip.String())
@@ -448,10 +684,18 @@ func LinkContainers(job *engine.Job) engine.Status {
 		return parts[0], parts[1]
 	}
 
+	// the containers are linked on the bridge of the network of the parent
+	bridge := bridgeIface
+	networksLock.Lock()
+	if n := getNetworkByIP(net.ParseIP(parentIP)); n != nil {
+		bridge = n.Bridge
+	}
+	networksLock.Unlock()
+
 	for _, p := range ports {
 		port, proto := split(p)
 		if output, err := iptables.Raw(action, "FORWARD",
-			"-i", bridgeIface, "-o", bridgeIface,
+			"-i", bridge, "-o", bridge,
 			"-p", proto,
 			"-s", parentIP,
 			"--dport", port,
@@ -465,7 +709,7 @@ func LinkContainers(job *engine.Job) engine.Status {
 		}
 
 		if output, err := iptables.Raw(action, "FORWARD",
-			"-i", bridgeIface, "-o", bridgeIface,
+			"-i", bridge, "-o", bridge,
 			"-p", proto,
 			"-s", childIP,
 			"--sport", port,
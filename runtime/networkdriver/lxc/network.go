@@ -0,0 +1,431 @@
+package lxc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dotcloud/docker/engine"
+	"github.com/dotcloud/docker/pkg/iptables"
+	"github.com/dotcloud/docker/pkg/netlink"
+	"github.com/dotcloud/docker/runtime/networkdriver"
+	"github.com/dotcloud/docker/utils"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// DefaultNetworkName is the name of the network of the containers
+	// on the bridge of the daemon, docker0 by default
+	DefaultNetworkName = "bridge"
+
+	isolationChain = "DOCKER-ISOLATION"
+	siocBRDELBR    = 0x89a1
+)
+
+// network is a network of the containers with its own bridge and subnet,
+// the containers of a network can not reach the ones of the other networks
+type network struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Bridge string `json:"bridge"`
+	Subnet string `json:"subnet"` // address of the bridge in CIDR notation, the gateway of the containers
+
+	ipNet   *net.IPNet // address and mask of the bridge, set once the bridge is up
+	ipNetV6 *net.IPNet // IPv6 address and mask of the bridge, only on the default network
+}
+
+var (
+	validNetworkName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+	// names of the modes of the networking of the containers
+	reservedNetworkNames = []string{DefaultNetworkName, "host", "none"}
+
+	networksLock   sync.Mutex
+	networks       = make(map[string]*network) // by name
+	networksPath   string                      // file the networks are saved to, they are not saved when empty
+	failedNetworks []*network                  // saved networks which could not be set up, they are saved again
+
+	enableIPTables bool
+	icc            bool
+)
+
+// getNetwork returns the network named name, or whose ID starts with
+// name. The default network is returned when name is empty.
+func getNetwork(name string) (*network, error) {
+	if name == "" {
+		name = DefaultNetworkName
+	}
+	if n, exists := networks[name]; exists {
+		return n, nil
+	}
+	var found *network
+	for _, n := range networks {
+		if strings.HasPrefix(n.ID, name) {
+			if found != nil {
+				return nil, fmt.Errorf("Network ID %s is ambiguous", name)
+			}
+			found = n
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("No such network: %s", name)
+	}
+	return found, nil
+}
+
+// getNetworkByIP returns the network whose subnet contains ip
+func getNetworkByIP(ip net.IP) *network {
+	for _, n := range networks {
+		if n.ipNet != nil && n.ipNet.Contains(ip) {
+			return n
+		}
+	}
+	return nil
+}
+
+// containersOf returns the IDs of the containers with an interface on n
+func containersOf(n *network) []string {
+	ids := []string{}
+	for id, iface := range currentInterfaces {
+		if iface.network == n {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// loadNetworks sets up the networks saved by a previous daemon, the ID of
+// the default network is kept while its bridge comes from the configuration
+func loadNetworks() error {
+	networks[DefaultNetworkName] = &network{
+		ID:      utils.GenerateRandomID(),
+		Name:    DefaultNetworkName,
+		Bridge:  bridgeIface,
+		Subnet:  bridgeNetwork.String(),
+		ipNet:   bridgeNetwork,
+		ipNetV6: bridgeNetworkV6,
+	}
+	if networksPath == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(networksPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var saved []*network
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	for _, n := range saved {
+		if n.Name == DefaultNetworkName {
+			networks[DefaultNetworkName].ID = n.ID
+			continue
+		}
+		if err := setupNetwork(n); err != nil {
+			log.Printf("WARNING: unable to set up the network %s: %s\n", n.Name, err)
+			failedNetworks = append(failedNetworks, n)
+			continue
+		}
+		networks[n.Name] = n
+	}
+	return nil
+}
+
+func saveNetworks() error {
+	if networksPath == "" {
+		return nil
+	}
+	saved := []*network{}
+	for _, n := range networks {
+		saved = append(saved, n)
+	}
+	// the networks which failed are kept for the next daemon, unless
+	// a network was created with their name since
+	for _, n := range failedNetworks {
+		if _, exists := networks[n.Name]; !exists {
+			saved = append(saved, n)
+		}
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(networksPath, data, 0600)
+}
+
+// setupNetwork creates the bridge of the network when it does not exist
+// and the iptables rules of its subnet
+func setupNetwork(n *network) error {
+	addr, err := networkdriver.GetIfaceAddr(n.Bridge)
+	if err != nil {
+		utils.Debugf("creating new bridge %s for the network %s", n.Bridge, n.Name)
+		if err := createBridge(n.Bridge, n.Subnet); err != nil {
+			return err
+		}
+		if addr, err = networkdriver.GetIfaceAddr(n.Bridge); err != nil {
+			return err
+		}
+	}
+	if enableIPTables {
+		if err := setupIPTables(n.Bridge, addr, icc); err != nil {
+			return err
+		}
+	}
+	n.ipNet = addr.(*net.IPNet)
+	n.Subnet = n.ipNet.String()
+	return nil
+}
+
+// setupIsolation drops the packets between the bridges of two networks in a
+// chain jumped to before the other rules of the FORWARD chain
+func setupIsolation() error {
+	if !enableIPTables {
+		return nil
+	}
+	if _, err := iptables.Raw("-n", "-L", isolationChain); err != nil {
+		if output, err := iptables.Raw("-N", isolationChain); err != nil {
+			return fmt.Errorf("Unable to create the %s chain: %s", isolationChain, err)
+		} else if len(output) != 0 {
+			return fmt.Errorf("Error creating the %s chain: %s", isolationChain, output)
+		}
+	}
+	if output, err := iptables.Raw("-F", isolationChain); err != nil {
+		return fmt.Errorf("Unable to flush the %s chain: %s", isolationChain, err)
+	} else if len(output) != 0 {
+		return fmt.Errorf("Error flushing the %s chain: %s", isolationChain, output)
+	}
+
+	// the rules of the networks are inserted at the top of FORWARD
+	// as well, the jump is moved above them
+	iptables.Raw("-D", "FORWARD", "-j", isolationChain)
+	if output, err := iptables.Raw("-I", "FORWARD", "-j", isolationChain); err != nil {
+		return fmt.Errorf("Unable to isolate the networks: %s", err)
+	} else if len(output) != 0 {
+		return fmt.Errorf("Error isolating the networks: %s", output)
+	}
+
+	for _, from := range networks {
+		for _, to := range networks {
+			if from == to {
+				continue
+			}
+			if output, err := iptables.Raw("-A", isolationChain, "-i", from.Bridge, "-o", to.Bridge, "-j", "DROP"); err != nil {
+				return fmt.Errorf("Unable to isolate the network %s: %s", from.Name, err)
+			} else if len(output) != 0 {
+				return fmt.Errorf("Error isolating the network %s: %s", from.Name, output)
+			}
+		}
+	}
+	return nil
+}
+
+// removeIPTables removes the rules added by setupIPTables for the bridge
+func removeIPTables(bridge string, addr net.Addr) {
+	for _, rule := range [][]string{
+		{"POSTROUTING", "-t", "nat", "-s", addr.String(), "!", "-d", addr.String(), "-j", "MASQUERADE"},
+		{"FORWARD", "-i", bridge, "-o", bridge, "-j", "ACCEPT"},
+		{"FORWARD", "-i", bridge, "-o", bridge, "-j", "DROP"},
+		{"FORWARD", "-i", bridge, "!", "-o", bridge, "-j", "ACCEPT"},
+		{"FORWARD", "-o", bridge, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"},
+	} {
+		iptables.Raw(append([]string{"-D"}, rule...)...)
+	}
+}
+
+// deleteBridgeIface deletes the bridge device, it must be down
+func deleteBridgeIface(name string) error {
+	s, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, syscall.IPPROTO_IP)
+	if err != nil {
+		return fmt.Errorf("Error creating bridge deletion socket: %s", err)
+	}
+	defer syscall.Close(s)
+
+	nameBytePtr, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return fmt.Errorf("Error converting bridge name %s to byte array: %s", name, err)
+	}
+	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, uintptr(s), siocBRDELBR, uintptr(unsafe.Pointer(nameBytePtr))); err != 0 {
+		return fmt.Errorf("Error deleting bridge: %s", err)
+	}
+	return nil
+}
+
+// CreateNetwork creates a network named after the first argument of the job,
+// on a new bridge with the subnet in the Subnet env or a free one.
+// The ID of the network is written to stdout.
+func CreateNetwork(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NAME", job.Name)
+	}
+	var (
+		name   = job.Args[0]
+		subnet = job.Getenv("Subnet")
+	)
+	if !validNetworkName.MatchString(name) {
+		return job.Errorf("Invalid network name (%s), only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", name)
+	}
+	for _, reserved := range reservedNetworkNames {
+		if name == reserved {
+			return job.Errorf("The network name %s is reserved", name)
+		}
+	}
+
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	if _, exists := networks[name]; exists {
+		return job.Errorf("A network named %s already exists", name)
+	}
+	if subnet != "" {
+		ip, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return job.Error(err)
+		}
+		if ip.To4() == nil {
+			return job.Errorf("The subnet %s is not an IPv4 subnet", subnet)
+		}
+		if ip.Equal(ipNet.IP) {
+			// the bridge gets the first address of the subnet
+			gateway := make(net.IP, net.IPv4len)
+			copy(gateway, ip.To4())
+			gateway[net.IPv4len-1]++
+			subnet = (&net.IPNet{IP: gateway, Mask: ipNet.Mask}).String()
+		}
+		for _, n := range networks {
+			if networkdriver.NetworkOverlaps(ipNet, n.ipNet) {
+				return job.Errorf("The subnet %s overlaps with the network %s", subnet, n.Name)
+			}
+		}
+	}
+
+	id := utils.GenerateRandomID()
+	n := &network{
+		ID:     id,
+		Name:   name,
+		Bridge: "br-" + utils.TruncateID(id),
+		Subnet: subnet,
+	}
+	if err := setupNetwork(n); err != nil {
+		return job.Error(err)
+	}
+	networks[name] = n
+	if err := setupIsolation(); err != nil {
+		return job.Error(err)
+	}
+	if err := saveNetworks(); err != nil {
+		return job.Error(err)
+	}
+	job.Printf("%s\n", id)
+	return engine.StatusOK
+}
+
+// ListNetworks writes the networks, sorted by name, as a table to stdout
+func ListNetworks(job *engine.Job) engine.Status {
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	names := []string{}
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	outs := engine.NewTable("", 0)
+	for _, name := range names {
+		n := networks[name]
+		out := &engine.Env{}
+		out.Set("Id", n.ID)
+		out.Set("Name", n.Name)
+		out.Set("Bridge", n.Bridge)
+		out.Set("Subnet", n.Subnet)
+		out.SetInt("Containers", len(containersOf(n)))
+		outs.Add(out)
+	}
+	if _, err := outs.WriteListTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
+// InspectNetwork writes the network named after the first
+// argument of the job as a JSON object to stdout
+func InspectNetwork(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NETWORK", job.Name)
+	}
+
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	n, err := getNetwork(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+	b, err := json.Marshal(&struct {
+		Id         string
+		Name       string
+		Bridge     string
+		Subnet     string
+		Gateway    string
+		Containers []string
+	}{n.ID, n.Name, n.Bridge, n.Subnet, n.ipNet.IP.String(), containersOf(n)})
+	if err != nil {
+		return job.Error(err)
+	}
+	job.Stdout.Write(b)
+	return engine.StatusOK
+}
+
+// RemoveNetwork removes the network named after the first argument of the
+// job and deletes its bridge, no running container may be on the network
+func RemoveNetwork(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s NETWORK", job.Name)
+	}
+
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	n, err := getNetwork(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+	if n.Name == DefaultNetworkName {
+		return job.Errorf("The default network %s can not be removed", n.Name)
+	}
+	if ids := containersOf(n); len(ids) > 0 {
+		return job.Errorf("The network %s has running containers: %s", n.Name, strings.Join(ids, ", "))
+	}
+
+	iface, err := net.InterfaceByName(n.Bridge)
+	if err != nil {
+		return job.Error(err)
+	}
+	if err := netlink.NetworkLinkDown(iface); err != nil {
+		return job.Errorf("Unable to stop network bridge: %s", err)
+	}
+	if err := deleteBridgeIface(n.Bridge); err != nil {
+		return job.Error(err)
+	}
+	removeIPTables(n.Bridge, n.ipNet)
+
+	delete(networks, n.Name)
+	if err := setupIsolation(); err != nil {
+		return job.Error(err)
+	}
+	if err := saveNetworks(); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
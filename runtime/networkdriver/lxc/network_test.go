@@ -0,0 +1,89 @@
+package lxc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetNetwork(t *testing.T) {
+	defer func() { networks = make(map[string]*network) }()
+
+	_, ipNet, _ := net.ParseCIDR("10.1.0.1/16")
+	networks = map[string]*network{
+		DefaultNetworkName: {ID: "a1b2c3", Name: DefaultNetworkName, Bridge: "docker0"},
+		"backend":          {ID: "a1f4e5", Name: "backend", Bridge: "br-a1f4e5", ipNet: ipNet},
+	}
+
+	for name, expected := range map[string]string{
+		"":                 DefaultNetworkName,
+		DefaultNetworkName: DefaultNetworkName,
+		"backend":          "backend",
+		"a1f":              "backend",
+	} {
+		n, err := getNetwork(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Name != expected {
+			t.Fatalf("Expected the network %s for %q, got %s", expected, name, n.Name)
+		}
+	}
+	for _, name := range []string{"a1", "frontend"} {
+		if _, err := getNetwork(name); err == nil {
+			t.Fatalf("Expected an error looking up the network %q", name)
+		}
+	}
+
+	if n := getNetworkByIP(net.ParseIP("10.1.2.3")); n == nil || n.Name != "backend" {
+		t.Fatalf("Expected 10.1.2.3 to be on the network backend, got %v", n)
+	}
+	if n := getNetworkByIP(net.ParseIP("10.2.0.1")); n != nil {
+		t.Fatalf("Expected 10.2.0.1 to be on no network, got %s", n.Name)
+	}
+}
+
+func TestSaveFailedNetworks(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-networks-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	defer func() {
+		networks = make(map[string]*network)
+		networksPath = ""
+		failedNetworks = nil
+	}()
+
+	networksPath = filepath.Join(root, "networks.json")
+	networks = map[string]*network{
+		DefaultNetworkName: {ID: "a1b2c3", Name: DefaultNetworkName, Bridge: "docker0"},
+		"frontend":         {ID: "d4e5f6", Name: "frontend", Bridge: "br-d4e5f6"},
+	}
+	failedNetworks = []*network{
+		{ID: "a1f4e5", Name: "backend", Bridge: "br-a1f4e5", Subnet: "10.1.0.1/16"},
+		{ID: "c7d8e9", Name: "frontend", Bridge: "br-c7d8e9", Subnet: "10.2.0.1/16"},
+	}
+	if err := saveNetworks(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(networksPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved []*network
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]string)
+	for _, n := range saved {
+		ids[n.Name] = n.ID
+	}
+	if len(saved) != 3 || ids["backend"] != "a1f4e5" || ids["frontend"] != "d4e5f6" {
+		t.Fatalf("Expected the failed network backend to be saved with the others, got %s", data)
+	}
+}
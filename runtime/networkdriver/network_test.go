@@ -188,3 +188,16 @@ func TestNetworkRange(t *testing.T) {
 		t.Error(size)
 	}
 }
+
+func TestNetworkRangeIPv6(t *testing.T) {
+	_, network, _ := net.ParseCIDR("2001:db8::1/64")
+	first, last := NetworkRange(network)
+	if !first.Equal(net.ParseIP("2001:db8::")) {
+		t.Error(first.String())
+	}
+	if !last.Equal(net.ParseIP("2001:db8::ffff:ffff:ffff:ffff")) {
+		t.Error(last.String())
+	}
+	AssertOverlap("2001:db8::1/64", "2001:db8::1:0:0:1/80", t)
+	AssertNoOverlap("2001:db8::1/64", "2001:db8:0:1::1/64", t)
+}
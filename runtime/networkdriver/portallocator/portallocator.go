@@ -2,6 +2,7 @@ package portallocator
 
 import (
 	"errors"
+	"fmt"
 	"github.com/dotcloud/docker/pkg/collections"
 	"net"
 	"sync"
@@ -60,6 +61,32 @@ func RequestPort(ip net.IP, proto string, port int) (int, error) {
 	return registerDynamicPort(ip, proto)
 }
 
+// RequestPortRange allocates all the ports from start to end, or none of
+// them when one of the ports is already allocated
+func RequestPortRange(ip net.IP, proto string, start, end int) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := validateProtocol(proto); err != nil {
+		return err
+	}
+	if start <= 0 || end < start || end > EndPortRange {
+		return fmt.Errorf("Invalid port range %d-%d", start, end)
+	}
+
+	for port := start; port <= end; port++ {
+		if isAllocated(ip, proto, port) {
+			return fmt.Errorf("Cannot allocate the ports %d-%d/%s on %s: the port %d has already been allocated", start, end, proto, ip, port)
+		}
+	}
+	for port := start; port <= end; port++ {
+		if err := registerSetPort(ip, proto, port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ReleasePort will return the provided port back into the
 // pool for reuse
 func ReleasePort(ip net.IP, proto string, port int) error {
@@ -138,8 +165,21 @@ func registerSetPort(ip net.IP, proto string, port int) error {
 	return nil
 }
 
+// isAllocated returns true when registerSetPort would fail to allocate the port
+func isAllocated(ip net.IP, proto string, port int) bool {
+	if defaultAllocatedPorts[proto].Exists(port) {
+		return true
+	}
+	if ipAllocated, exists := otherAllocatedPorts[ip.String()]; !equalsDefault(ip) && exists {
+		return ipAllocated[proto].Exists(port)
+	}
+	return false
+}
+
+// equalsDefault returns true for the addresses the ports are allocated on
+// by default, 0.0.0.0 and ::, which both bind the port on every interface
 func equalsDefault(ip net.IP) bool {
-	return ip == nil || ip.Equal(defaultIP)
+	return ip == nil || ip.IsUnspecified()
 }
 
 func nextPort(proto string) int {
@@ -182,3 +182,45 @@ func TestPortAllocation(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestRequestPortIPv6Unspecified(t *testing.T) {
+	defer reset()
+
+	if _, err := RequestPort(defaultIP, "tcp", 5000); err != nil {
+		t.Fatal(err)
+	}
+	// :: binds the port on every interface, as 0.0.0.0
+	if _, err := RequestPort(net.ParseIP("::"), "tcp", 5000); err != ErrPortAlreadyAllocated {
+		t.Fatalf("Expected error %s got %s", ErrPortAlreadyAllocated, err)
+	}
+	if _, err := RequestPort(net.ParseIP("2001:db8::1"), "tcp", 5001); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestPortRange(t *testing.T) {
+	defer reset()
+
+	if _, err := RequestPort(defaultIP, "udp", 10050); err != nil {
+		t.Fatal(err)
+	}
+	if err := RequestPortRange(defaultIP, "udp", 10000, 10100); err == nil {
+		t.Fatal("Expected an error allocating a range with an allocated port")
+	}
+	// none of the ports of the range were allocated
+	if _, err := RequestPort(defaultIP, "udp", 10000); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RequestPortRange(defaultIP, "udp", 10001, 10049); err != nil {
+		t.Fatal(err)
+	}
+	for _, port := range []int{10001, 10025, 10049} {
+		if _, err := RequestPort(defaultIP, "udp", port); err != ErrPortAlreadyAllocated {
+			t.Fatalf("Expected error %s allocating %d got %v", ErrPortAlreadyAllocated, port, err)
+		}
+	}
+	if err := RequestPortRange(defaultIP, "udp", 10049, 10040); err == nil {
+		t.Fatal("Expected an error allocating an invalid range")
+	}
+}
@@ -11,16 +11,19 @@ import (
 
 type mapping struct {
 	proto         string
-	userlandProxy proxy.Proxy
+	userlandProxy proxy.Proxy // nil for a range of ports
 	host          net.Addr
+	hostEnd       int // last port of a range of ports from the port of host
 	container     net.Addr
+	bridge        string // bridge of the container, the bridge of the chain when empty
 }
 
 var (
-	chain *iptables.Chain
-	lock  sync.Mutex
+	chain  *iptables.Chain
+	chain6 *iptables.Chain // chain of the ports mapped to the IPv6 addresses of the containers
+	lock   sync.Mutex
 
-	// udp:ip:port
+	// udp:ip:port, each port of a range has the mapping of the range
 	currentMappings = make(map[string]*mapping)
 	newProxy        = proxy.NewProxy
 )
@@ -35,53 +38,115 @@ func SetIptablesChain(c *iptables.Chain) {
 	chain = c
 }
 
+// SetIp6tablesChain sets the chain of the ports mapped to IPv6 containers
+func SetIp6tablesChain(c *iptables.Chain) {
+	chain6 = c
+}
+
 func Map(container net.Addr, hostIP net.IP, hostPort int) error {
+	return MapBridge(container, hostIP, hostPort, "")
+}
+
+// MapBridge maps the port like Map for a container on bridge,
+// which is not the bridge of the iptables chain
+func MapBridge(container net.Addr, hostIP net.IP, hostPort int, bridge string) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	m, err := newMapping(container, hostIP, hostPort, hostPort, bridge)
+	if err != nil {
+		return err
+	}
+	key := getKey(m.host)
+
+	containerIP, containerPort := getIPAndPort(m.container)
+	if err := forward(iptables.Add, m.proto, hostIP, hostPort, hostPort, containerIP.String(), containerPort, m.bridge); err != nil {
+		return err
+	}
+
+	p, err := newProxy(m.host, m.container)
+	if err != nil {
+		// need to undo the iptables rules before we reutrn
+		forward(iptables.Delete, m.proto, hostIP, hostPort, hostPort, containerIP.String(), containerPort, m.bridge)
+		return err
+	}
+
+	m.userlandProxy = p
+	currentMappings[key] = m
+
+	go p.Run()
+
+	return nil
+}
+
+// MapRange maps the host ports hostPort to hostPortEnd to the ports of
+// the container at the same offset from the port of container, with a
+// single iptables rule. No userland proxy is started for the range, its
+// ports are not reachable from the loopback of the host.
+func MapRange(container net.Addr, hostIP net.IP, hostPort, hostPortEnd int, bridge string) error {
 	lock.Lock()
 	defer lock.Unlock()
 
+	m, err := newMapping(container, hostIP, hostPort, hostPortEnd, bridge)
+	if err != nil {
+		return err
+	}
+
+	containerIP, containerPort := getIPAndPort(m.container)
+	if err := forward(iptables.Add, m.proto, hostIP, hostPort, hostPortEnd, containerIP.String(), containerPort, m.bridge); err != nil {
+		return err
+	}
+	for _, key := range m.keys() {
+		currentMappings[key] = m
+	}
+	return nil
+}
+
+// newMapping returns the mapping of the host ports hostPort to hostPortEnd,
+// which must not be mapped yet
+func newMapping(container net.Addr, hostIP net.IP, hostPort, hostPortEnd int, bridge string) (*mapping, error) {
 	var m *mapping
 	switch container.(type) {
 	case *net.TCPAddr:
 		m = &mapping{
 			proto:     "tcp",
 			host:      &net.TCPAddr{IP: hostIP, Port: hostPort},
+			hostEnd:   hostPortEnd,
 			container: container,
+			bridge:    bridge,
 		}
 	case *net.UDPAddr:
 		m = &mapping{
 			proto:     "udp",
 			host:      &net.UDPAddr{IP: hostIP, Port: hostPort},
+			hostEnd:   hostPortEnd,
 			container: container,
+			bridge:    bridge,
 		}
 	default:
-		return ErrUnknownBackendAddressType
+		return nil, ErrUnknownBackendAddressType
 	}
 
-	key := getKey(m.host)
-	if _, exists := currentMappings[key]; exists {
-		return ErrPortMappedForIP
+	for _, key := range m.keys() {
+		if _, exists := currentMappings[key]; exists {
+			return nil, ErrPortMappedForIP
+		}
 	}
+	return m, nil
+}
 
-	containerIP, containerPort := getIPAndPort(m.container)
-	if err := forward(iptables.Add, m.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
-		return err
+// keys returns the keys of the host ports of the mapping
+func (m *mapping) keys() []string {
+	hostIP, hostPort := getIPAndPort(m.host)
+	keys := make([]string, 0, m.hostEnd-hostPort+1)
+	for port := hostPort; port <= m.hostEnd; port++ {
+		keys = append(keys, fmt.Sprintf("%s:%d/%s", hostIP.String(), port, m.proto))
 	}
-
-	p, err := newProxy(m.host, m.container)
-	if err != nil {
-		// need to undo the iptables rules before we reutrn
-		forward(iptables.Delete, m.proto, hostIP, hostPort, containerIP.String(), containerPort)
-		return err
-	}
-
-	m.userlandProxy = p
-	currentMappings[key] = m
-
-	go p.Run()
-
-	return nil
+	return keys
 }
 
+// Unmap removes the mapping of the host port, or of the range of
+// ports starting at the host port
 func Unmap(host net.Addr) error {
 	lock.Lock()
 	defer lock.Unlock()
@@ -92,12 +157,16 @@ func Unmap(host net.Addr) error {
 		return ErrPortNotMapped
 	}
 
-	data.userlandProxy.Close()
-	delete(currentMappings, key)
+	if data.userlandProxy != nil {
+		data.userlandProxy.Close()
+	}
+	for _, key := range data.keys() {
+		delete(currentMappings, key)
+	}
 
 	containerIP, containerPort := getIPAndPort(data.container)
 	hostIP, hostPort := getIPAndPort(data.host)
-	if err := forward(iptables.Delete, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
+	if err := forward(iptables.Delete, data.proto, hostIP, hostPort, data.hostEnd, containerIP.String(), containerPort, data.bridge); err != nil {
 		return err
 	}
 	return nil
@@ -123,9 +192,16 @@ func getIPAndPort(a net.Addr) (net.IP, int) {
 	return nil, 0
 }
 
-func forward(action iptables.Action, proto string, sourceIP net.IP, sourcePort int, containerIP string, containerPort int) error {
-	if chain == nil {
+func forward(action iptables.Action, proto string, sourceIP net.IP, sourcePort, sourcePortEnd int, containerIP string, containerPort int, bridge string) error {
+	c := chain
+	if ip := net.ParseIP(containerIP); ip != nil && ip.To4() == nil {
+		c = chain6
+	}
+	if c == nil {
 		return nil
 	}
-	return chain.Forward(action, sourceIP, sourcePort, proto, containerIP, containerPort)
+	if bridge != "" {
+		c = &iptables.Chain{Name: c.Name, Bridge: bridge, IPv6: c.IPv6}
+	}
+	return c.ForwardRange(action, sourceIP, sourcePort, sourcePortEnd, proto, containerIP, containerPort)
 }
@@ -73,6 +73,36 @@ func TestMapPorts(t *testing.T) {
 	}
 }
 
+func TestMapRange(t *testing.T) {
+	defer reset()
+	hostIp := net.ParseIP("192.168.0.1")
+	containerAddr := &net.TCPAddr{IP: net.ParseIP("172.16.0.1"), Port: 8000}
+
+	if err := MapRange(containerAddr, hostIp, 9000, 9100, ""); err != nil {
+		t.Fatalf("Failed to map the range: %s", err)
+	}
+	if len(currentMappings) != 101 {
+		t.Fatalf("Expected each port of the range to be mapped, got %d mappings", len(currentMappings))
+	}
+	if m := currentMappings["192.168.0.1:9050/tcp"]; m == nil || m.userlandProxy != nil {
+		t.Fatalf("Expected the ports of the range to be mapped without a proxy")
+	}
+
+	if Map(containerAddr, hostIp, 9050) == nil {
+		t.Fatalf("Port is in the range - mapping should have failed")
+	}
+	if MapRange(containerAddr, hostIp, 8950, 9000, "") == nil {
+		t.Fatalf("Ports overlap the range - mapping should have failed")
+	}
+
+	if err := Unmap(&net.TCPAddr{IP: hostIp, Port: 9000}); err != nil {
+		t.Fatalf("Failed to release the range: %s", err)
+	}
+	if len(currentMappings) != 0 {
+		t.Fatalf("Expected the ports of the range to be released, got %d mappings", len(currentMappings))
+	}
+}
+
 func TestGetUDPKey(t *testing.T) {
 	addr := &net.UDPAddr{IP: net.ParseIP("192.168.1.5"), Port: 53}
 
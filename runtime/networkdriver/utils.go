@@ -54,16 +54,25 @@ func NetworkOverlaps(netX *net.IPNet, netY *net.IPNet) bool {
 	return false
 }
 
-// Calculates the first and last IP addresses in an IPNet
+// Calculates the first and last IP addresses in an IPNet, of 4 bytes
+// for an IPv4 network and of 16 bytes for an IPv6 network
 func NetworkRange(network *net.IPNet) (net.IP, net.IP) {
 	var (
-		netIP   = network.IP.To4()
-		firstIP = netIP.Mask(network.Mask)
-		lastIP  = net.IPv4(0, 0, 0, 0).To4()
+		netIP = network.IP.To4()
+		mask  = network.Mask
+	)
+	if netIP == nil {
+		netIP = network.IP.To16()
+	} else if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	var (
+		firstIP = netIP.Mask(mask)
+		lastIP  = make(net.IP, len(netIP))
 	)
 
 	for i := 0; i < len(lastIP); i++ {
-		lastIP[i] = netIP[i] | ^network.Mask[i]
+		lastIP[i] = netIP[i] | ^mask[i]
 	}
 	return firstIP, lastIP
 }
@@ -9,7 +9,10 @@ import (
 	"github.com/dotcloud/docker/engine"
 	"github.com/dotcloud/docker/graph"
 	"github.com/dotcloud/docker/image"
+	"github.com/dotcloud/docker/pkg/cgroups"
 	"github.com/dotcloud/docker/pkg/graphdb"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/pkg/libcontainer/selinux"
 	"github.com/dotcloud/docker/pkg/sysinfo"
 	"github.com/dotcloud/docker/runconfig"
 	"github.com/dotcloud/docker/runtime/execdriver"
@@ -20,6 +23,10 @@ import (
 	_ "github.com/dotcloud/docker/runtime/graphdriver/btrfs"
 	_ "github.com/dotcloud/docker/runtime/graphdriver/devmapper"
 	_ "github.com/dotcloud/docker/runtime/graphdriver/vfs"
+	"github.com/dotcloud/docker/runtime/logger"
+	_ "github.com/dotcloud/docker/runtime/logger/journald"
+	_ "github.com/dotcloud/docker/runtime/logger/jsonfilelog"
+	_ "github.com/dotcloud/docker/runtime/logger/syslog"
 	_ "github.com/dotcloud/docker/runtime/networkdriver/lxc"
 	"github.com/dotcloud/docker/runtime/networkdriver/portallocator"
 	"github.com/dotcloud/docker/utils"
@@ -60,6 +67,9 @@ type Runtime struct {
 	containerGraph *graphdb.Database
 	driver         graphdriver.Driver
 	execDriver     execdriver.Driver
+	execCommands   *execStore
+	uidMaps        []libcontainer.IdMap // uids of the host the containers are remapped to, nil without remapping
+	gidMaps        []libcontainer.IdMap
 }
 
 // List returns an array of all containers registered in the runtime.
@@ -140,6 +150,14 @@ func (runtime *Runtime) Register(container *Container) error {
 
 	container.runtime = runtime
 
+	// the static address of the container is kept for it from the start
+	// of the daemon, whether it runs or not
+	if !runtime.config.DisableNetwork {
+		if err := container.reserveIP(); err != nil {
+			utils.Errorf("Error reserving the address of %s: %s", container.ID, err)
+		}
+	}
+
 	// Attach to stdout and stderr
 	container.stderr = utils.NewWriteBroadcaster()
 	container.stdout = utils.NewWriteBroadcaster()
@@ -157,6 +175,20 @@ func (runtime *Runtime) Register(container *Container) error {
 	//        if so, then we need to restart monitor and init a new lock
 	// If the container is supposed to be running, make sure of it
 	if container.State.IsRunning() {
+		// the processes of the native driver outlive the daemon, the container
+		// is re-attached to its process when the driver still knows it
+		if container.State.IsGhost() && !strings.Contains(container.ExecDriver, "lxc") && runtime.execDriver.Info(container.ID).IsRunning() {
+			utils.Debugf("restoring ghost %s", container.ID)
+			err := container.restore()
+			if err == nil {
+				selinux.ReserveLabel(container.ProcessLabel)
+				return nil
+			}
+			utils.Errorf("Error restoring ghost %s: %s", container.ID, err)
+			if err := runtime.killGhost(container); err != nil {
+				utils.Errorf("Error killing ghost %s: %s", container.ID, err)
+			}
+		}
 		if container.State.IsGhost() {
 			utils.Debugf("killing ghost %s", container.ID)
 
@@ -176,7 +208,7 @@ func (runtime *Runtime) Register(container *Container) error {
 		info := runtime.execDriver.Info(container.ID)
 		if !info.IsRunning() {
 			utils.Debugf("Container %s was supposed to be running but is not.", container.ID)
-			if runtime.config.AutoRestart {
+			if runtime.config.AutoRestart || container.hostConfig.RestartPolicy.Name == "always" {
 				utils.Debugf("Restarting")
 				if err := container.Unmount(); err != nil {
 					utils.Debugf("restart unmount error %s", err)
@@ -194,6 +226,8 @@ func (runtime *Runtime) Register(container *Container) error {
 					return err
 				}
 			}
+		} else {
+			selinux.ReserveLabel(container.ProcessLabel)
 		}
 	} else {
 		// When the container is not running, we still initialize the waitLock
@@ -205,6 +239,19 @@ func (runtime *Runtime) Register(container *Container) error {
 	return nil
 }
 
+// killGhost kills the process of a ghost which could not be restored, it is
+// found by the exec driver with the state it kept, and waits for the driver
+// to clean up after it
+func (runtime *Runtime) killGhost(container *Container) error {
+	command := &execdriver.Command{ID: container.ID}
+	if err := runtime.execDriver.Kill(command, 9); err != nil {
+		return err
+	}
+	discard := utils.NopWriteCloser(ioutil.Discard)
+	_, err := runtime.execDriver.Restore(command, execdriver.NewPipes(nil, discard, discard, false))
+	return err
+}
+
 func (runtime *Runtime) ensureName(container *Container) error {
 	if container.Name == "" {
 		name, err := generateRandomName(runtime)
@@ -265,6 +312,9 @@ func (runtime *Runtime) Destroy(container *Container) error {
 	// Deregister the container before removing its directory, to avoid race conditions
 	runtime.idIndex.Delete(container.ID)
 	runtime.containers.Remove(element)
+	// the exit codes of its processes can't be inspected anymore
+	runtime.execCommands.DeleteContainer(container)
+	container.unreserveIP()
 	if err := os.RemoveAll(container.root); err != nil {
 		return fmt.Errorf("Unable to remove filesystem for %v: %v", container.ID, err)
 	}
@@ -486,6 +536,13 @@ func (runtime *Runtime) Create(config *runconfig.Config, name string) (*Containe
 	if err := runtime.driver.Create(container.ID, initID); err != nil {
 		return nil, nil, err
 	}
+	if runtime.uidMaps != nil {
+		for _, id := range []string{initID, container.ID} {
+			if err := graphdriver.ChownLayer(runtime.driver, id, runtime.uidMaps, runtime.gidMaps); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
 	resolvConf, err := utils.GetResolvConf()
 	if err != nil {
 		return nil, nil, err
@@ -625,6 +682,66 @@ func (runtime *Runtime) RegisterLink(parent, child *Container, alias string) err
 	return nil
 }
 
+// Rename changes the name of the container to newName. The links to the
+// container are kept: they are stored under the parents and refer to the
+// container by ID, only the links of the parents which were not registered
+// yet still refer to the container by name and are updated.
+func (runtime *Runtime) Rename(container *Container, newName string) error {
+	if !validContainerNamePattern.MatchString(strings.TrimPrefix(newName, "/")) {
+		return fmt.Errorf("Invalid container name (%s), only %s are allowed", newName, validContainerNameChars)
+	}
+	newName, err := GetFullContainerName(newName)
+	if err != nil {
+		return err
+	}
+
+	container.Lock()
+	defer container.Unlock()
+
+	oldName := container.Name
+	if oldName == newName {
+		return fmt.Errorf("Renaming a container with the same name as its current name")
+	}
+	if runtime.containerGraph.Exists(newName) {
+		nameAsKnownByUser := strings.TrimPrefix(newName, "/")
+		return fmt.Errorf("Conflict, The name %s is already assigned. You have to delete (or rename) that container to be able to assign %s to a container again.", nameAsKnownByUser, nameAsKnownByUser)
+	}
+	if err := runtime.containerGraph.Rename(oldName, newName); err != nil {
+		return err
+	}
+	container.Name = newName
+	if err := container.ToDisk(); err != nil {
+		container.Name = oldName
+		if err := runtime.containerGraph.Rename(newName, oldName); err != nil {
+			utils.Errorf("Error restoring the name %s of %s: %s", oldName, container.ID, err)
+		}
+		return err
+	}
+
+	for _, parent := range runtime.List() {
+		if parent == container || parent.hostConfig == nil {
+			continue
+		}
+		changed := false
+		for i, l := range parent.hostConfig.Links {
+			parts, err := utils.PartParser("name:alias", l)
+			if err != nil {
+				continue
+			}
+			if name, _ := GetFullContainerName(parts["name"]); name == oldName {
+				parent.hostConfig.Links[i] = newName + ":" + parts["alias"]
+				changed = true
+			}
+		}
+		if changed {
+			if err := parent.WriteHostConfig(); err != nil {
+				utils.Errorf("Error updating the links of %s: %s", parent.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
 // FIXME: harmonize with NewGraph()
 func NewRuntime(config *daemonconfig.Config, eng *engine.Engine) (*Runtime, error) {
 	runtime, err := NewRuntimeFromDirectory(config, eng)
@@ -635,6 +752,34 @@ func NewRuntime(config *daemonconfig.Config, eng *engine.Engine) (*Runtime, erro
 }
 
 func NewRuntimeFromDirectory(config *daemonconfig.Config, eng *engine.Engine) (*Runtime, error) {
+	if config.LogConfig.Type != "" {
+		if err := logger.ValidateDriver(config.LogConfig.Type); err != nil {
+			return nil, err
+		}
+		if err := logger.ValidateLogOpts(config.LogConfig.Type, config.LogConfig.Config); err != nil {
+			return nil, err
+		}
+	}
+
+	var uidMaps, gidMaps []libcontainer.IdMap
+	if config.UsernsRemap != "" {
+		var err error
+		if uidMaps, gidMaps, err = getRemappedIds(config.UsernsRemap); err != nil {
+			return nil, err
+		}
+		// the remapped layers and containers are kept apart from the other ones,
+		// the root of the containers goes through the roots of the daemon
+		remappedRoot := path.Join(config.Root, fmt.Sprintf("%d.%d", uidMaps[0].HostId, gidMaps[0].HostId))
+		if err := os.MkdirAll(remappedRoot, 0701); err != nil {
+			return nil, err
+		}
+		for _, dir := range []string{config.Root, remappedRoot} {
+			if err := os.Chmod(dir, 0701); err != nil {
+				return nil, err
+			}
+		}
+		config.Root = remappedRoot
+	}
 
 	// Set the default driver
 	graphdriver.DefaultDriver = config.GraphDriver
@@ -664,6 +809,7 @@ func NewRuntimeFromDirectory(config *daemonconfig.Config, eng *engine.Engine) (*
 	if err != nil {
 		return nil, err
 	}
+	g.SetIdMappings(uidMaps, gidMaps)
 
 	// We don't want to use a complex driver like aufs or devmapper
 	// for volumes, just a plain filesystem
@@ -676,6 +822,7 @@ func NewRuntimeFromDirectory(config *daemonconfig.Config, eng *engine.Engine) (*
 	if err != nil {
 		return nil, err
 	}
+	volumes.SetIdMappings(uidMaps, gidMaps)
 	utils.Debugf("Creating repository list")
 	repositories, err := graph.NewTagStore(path.Join(config.Root, "repositories-"+driver.String()), g)
 	if err != nil {
@@ -690,7 +837,9 @@ func NewRuntimeFromDirectory(config *daemonconfig.Config, eng *engine.Engine) (*
 		job.SetenvBool("EnableIpForward", config.EnableIpForward)
 		job.Setenv("BridgeIface", config.BridgeIface)
 		job.Setenv("BridgeIP", config.BridgeIP)
+		job.Setenv("BridgeIPv6", config.BridgeIPv6)
 		job.Setenv("DefaultBindingIP", config.DefaultIp.String())
+		job.Setenv("Root", config.Root)
 
 		if err := job.Run(); err != nil {
 			return nil, err
@@ -717,14 +866,25 @@ func NewRuntimeFromDirectory(config *daemonconfig.Config, eng *engine.Engine) (*
 		if _, err := utils.CopyFile(sysInitPath, localCopy); err != nil {
 			return nil, err
 		}
-		if err := os.Chmod(localCopy, 0700); err != nil {
+		mode := os.FileMode(0700)
+		if uidMaps != nil {
+			// the init of the containers is executed as their root
+			mode = 0755
+		}
+		if err := os.Chmod(localCopy, mode); err != nil {
 			return nil, err
 		}
 		sysInitPath = localCopy
 	}
 
+	if config.CgroupSlice != "" {
+		if _, err := cgroups.ExpandSlice(config.CgroupSlice); err != nil {
+			return nil, err
+		}
+	}
+
 	sysInfo := sysinfo.New(false)
-	ed, err := execdrivers.NewDriver(config.ExecDriver, config.Root, sysInitPath, sysInfo)
+	ed, err := execdrivers.NewDriver(config.ExecDriver, config.Root, sysInitPath, config.CgroupSlice, sysInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -742,7 +902,16 @@ func NewRuntimeFromDirectory(config *daemonconfig.Config, eng *engine.Engine) (*
 		driver:         driver,
 		sysInitPath:    sysInitPath,
 		execDriver:     ed,
+		execCommands:   newExecStore(),
 		eng:            eng,
+		uidMaps:        uidMaps,
+		gidMaps:        gidMaps,
+	}
+	if err := runtime.makeTraversable(path.Dir(sysInitPath)); err != nil {
+		return nil, err
+	}
+	if err := runtime.makeTraversable(path.Join(config.Root, "execdriver", config.ExecDriver)); err != nil {
+		return nil, err
 	}
 
 	if err := runtime.restore(); err != nil {
@@ -772,7 +941,7 @@ func (runtime *Runtime) Close() error {
 }
 
 func (runtime *Runtime) Mount(container *Container) error {
-	dir, err := runtime.driver.Get(container.ID)
+	dir, err := graphdriver.GetLabeled(runtime.driver, container.ID, container.MountLabel)
 	if err != nil {
 		return fmt.Errorf("Error getting container %s from driver %s: %s", container.ID, runtime.driver, err)
 	}
@@ -837,10 +1006,26 @@ func (runtime *Runtime) Run(c *Container, pipes *execdriver.Pipes, startCallback
 	return runtime.execDriver.Run(c.command, pipes, startCallback)
 }
 
+func (runtime *Runtime) Restore(c *Container, pipes *execdriver.Pipes) (int, error) {
+	return runtime.execDriver.Restore(c.command, pipes)
+}
+
 func (runtime *Runtime) Kill(c *Container, sig int) error {
 	return runtime.execDriver.Kill(c.command, sig)
 }
 
+func (runtime *Runtime) Pause(c *Container) error {
+	return runtime.execDriver.Pause(c.command)
+}
+
+func (runtime *Runtime) Unpause(c *Container) error {
+	return runtime.execDriver.Unpause(c.command)
+}
+
+func (runtime *Runtime) Update(c *Container, resources *execdriver.Resources) error {
+	return runtime.execDriver.Update(c.command, resources)
+}
+
 // Nuke kills all containers then removes all content
 // from the content root, including images, volumes and
 // container filesystems.
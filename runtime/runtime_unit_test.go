@@ -0,0 +1,138 @@
+package runtime
+
+import (
+	"container/list"
+	"fmt"
+	"github.com/dotcloud/docker/daemonconfig"
+	"github.com/dotcloud/docker/pkg/cgroups"
+	"github.com/dotcloud/docker/pkg/graphdb"
+	"github.com/dotcloud/docker/runconfig"
+	"github.com/dotcloud/docker/runtime/execdriver"
+	"github.com/dotcloud/docker/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ghostExecDriver knows a process left running by a previous daemon until
+// it is killed
+type ghostExecDriver struct {
+	running  bool
+	killed   int
+	restored int
+}
+
+func (d *ghostExecDriver) Run(c *execdriver.Command, pipes *execdriver.Pipes, startCallback execdriver.StartCallback) (int, error) {
+	return -1, fmt.Errorf("not implemented")
+}
+
+func (d *ghostExecDriver) Kill(c *execdriver.Command, sig int) error {
+	if sig == 9 {
+		d.running = false
+	}
+	d.killed++
+	return nil
+}
+
+func (d *ghostExecDriver) Name() string {
+	return "native-test"
+}
+
+func (d *ghostExecDriver) Info(id string) execdriver.Info {
+	return d
+}
+
+func (d *ghostExecDriver) IsRunning() bool {
+	return d.running
+}
+
+func (d *ghostExecDriver) GetPidsForContainer(id string) ([]int, error) {
+	return nil, nil
+}
+
+func (d *ghostExecDriver) Exec(c *execdriver.Command, processConfig *execdriver.ProcessConfig, pipes *execdriver.Pipes, startCallback execdriver.ExecStartCallback) (int, error) {
+	return -1, fmt.Errorf("not implemented")
+}
+
+func (d *ghostExecDriver) Pause(c *execdriver.Command) error {
+	return nil
+}
+
+func (d *ghostExecDriver) Unpause(c *execdriver.Command) error {
+	return nil
+}
+
+func (d *ghostExecDriver) Stats(id string) (*cgroups.Stats, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (d *ghostExecDriver) Update(c *execdriver.Command, resources *execdriver.Resources) error {
+	return nil
+}
+
+func (d *ghostExecDriver) Restore(c *execdriver.Command, pipes *execdriver.Pipes) (int, error) {
+	d.restored++
+	return -1, nil
+}
+
+// brokenGraphDriver can't mount the layers of the containers
+type brokenGraphDriver struct{}
+
+func (brokenGraphDriver) String() string                 { return "broken" }
+func (brokenGraphDriver) Create(id, parent string) error { return nil }
+func (brokenGraphDriver) Remove(id string) error         { return nil }
+func (brokenGraphDriver) Get(id string) (string, error)  { return "", fmt.Errorf("can't mount %s", id) }
+func (brokenGraphDriver) Put(id string)                  {}
+func (brokenGraphDriver) Exists(id string) bool          { return true }
+func (brokenGraphDriver) Status() [][2]string            { return nil }
+func (brokenGraphDriver) Cleanup() error                 { return nil }
+
+func TestRegisterGhostRestoreFailure(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-runtime-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	containerGraph, err := graphdb.NewSqliteConn(filepath.Join(root, "linkgraph.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer containerGraph.Close()
+
+	execDriver := &ghostExecDriver{running: true}
+	runtime := &Runtime{
+		containers:     list.New(),
+		idIndex:        utils.NewTruncIndex(),
+		containerGraph: containerGraph,
+		config:         &daemonconfig.Config{DisableNetwork: true},
+		driver:         brokenGraphDriver{},
+		execDriver:     execDriver,
+	}
+	container := &Container{
+		ID:         "4f3b2a1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a",
+		Name:       "/ghost",
+		root:       root,
+		Config:     &runconfig.Config{NetworkDisabled: true},
+		hostConfig: &runconfig.HostConfig{},
+		ExecDriver: "native-test",
+	}
+	container.State.SetRunning(42)
+	container.State.SetGhost(true)
+
+	if err := runtime.Register(container); err != nil {
+		t.Fatal(err)
+	}
+	// the process of the ghost is killed through the driver and the driver
+	// cleans up after it, rather than leaving it running unknown
+	if execDriver.killed != 1 || execDriver.restored != 1 {
+		t.Fatalf("Expected the ghost to be killed and waited for once, killed %d restored %d", execDriver.killed, execDriver.restored)
+	}
+	if execDriver.running {
+		t.Fatal("Expected the process of the ghost not to be running")
+	}
+	if container.State.IsRunning() || container.State.IsGhost() {
+		t.Fatal("Expected the container to be marked as stopped")
+	}
+}
@@ -10,11 +10,13 @@ import (
 type State struct {
 	sync.RWMutex
 	Running    bool
+	Paused     bool
 	Pid        int
 	ExitCode   int
 	StartedAt  time.Time
 	FinishedAt time.Time
 	Ghost      bool
+	Health     *Health
 }
 
 // String returns a human-readable description of the state
@@ -26,6 +28,12 @@ func (s *State) String() string {
 		if s.Ghost {
 			return fmt.Sprintf("Ghost")
 		}
+		if s.Paused {
+			return fmt.Sprintf("Up %s (Paused)", utils.HumanDuration(time.Now().UTC().Sub(s.StartedAt)))
+		}
+		if s.Health != nil {
+			return fmt.Sprintf("Up %s (%s)", utils.HumanDuration(time.Now().UTC().Sub(s.StartedAt)), s.Health.Status)
+		}
 		return fmt.Sprintf("Up %s", utils.HumanDuration(time.Now().UTC().Sub(s.StartedAt)))
 	}
 	return fmt.Sprintf("Exit %d", s.ExitCode)
@@ -38,6 +46,13 @@ func (s *State) IsRunning() bool {
 	return s.Running
 }
 
+func (s *State) IsPaused() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.Paused
+}
+
 func (s *State) IsGhost() bool {
 	s.RLock()
 	defer s.RUnlock()
@@ -64,6 +79,7 @@ func (s *State) SetRunning(pid int) {
 	defer s.Unlock()
 
 	s.Running = true
+	s.Paused = false
 	s.Ghost = false
 	s.ExitCode = 0
 	s.Pid = pid
@@ -75,7 +91,69 @@ func (s *State) SetStopped(exitCode int) {
 	defer s.Unlock()
 
 	s.Running = false
+	s.Paused = false
 	s.Pid = 0
 	s.FinishedAt = time.Now().UTC()
 	s.ExitCode = exitCode
 }
+
+func (s *State) SetPaused() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Paused = true
+}
+
+func (s *State) SetUnpaused() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Paused = false
+}
+
+func (s *State) SetHealth(health *Health) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.Health = health
+}
+
+// GetHealthStatus returns the health status of the container,
+// or an empty string if it has no health check
+func (s *State) GetHealthStatus() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.Health == nil {
+		return ""
+	}
+	return s.Health.Status
+}
+
+// addHealthResult records the result of a health check and updates the
+// health status, it returns the new status and whether it changed
+func (s *State) addHealthResult(result *HealthcheckResult, retries int) (string, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.Health == nil {
+		return "", false
+	}
+	h := s.Health
+	h.Log = append(h.Log, result)
+	if len(h.Log) > maxHealthLogEntries {
+		h.Log = h.Log[len(h.Log)-maxHealthLogEntries:]
+	}
+
+	previous := h.Status
+	if result.ExitCode == 0 {
+		h.FailingStreak = 0
+		h.Status = Healthy
+	} else {
+		h.FailingStreak++
+		if h.FailingStreak >= retries {
+			h.Status = Unhealthy
+		}
+	}
+	return h.Status, h.Status != previous
+}
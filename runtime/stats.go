@@ -0,0 +1,182 @@
+package runtime
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/dotcloud/docker/pkg/cgroups"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the number of USER_HZ ticks per second used by /proc/stat
+const clockTicks = 100
+
+type NetworkStats struct {
+	RxBytes   uint64 `json:"rx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	RxErrors  uint64 `json:"rx_errors"`
+	RxDropped uint64 `json:"rx_dropped"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	TxPackets uint64 `json:"tx_packets"`
+	TxErrors  uint64 `json:"tx_errors"`
+	TxDropped uint64 `json:"tx_dropped"`
+}
+
+// ContainerStats is a sample of the resource usage of a running container
+type ContainerStats struct {
+	Read        time.Time    `json:"read"`
+	SystemUsage uint64       `json:"system_cpu_usage"` // Total CPU time of the host (in nanoseconds)
+	Network     NetworkStats `json:"network"`
+	*cgroups.Stats
+}
+
+// Stats samples the cgroups usage of the container and the
+// counters of its network interface
+func (runtime *Runtime) Stats(container *Container) (*ContainerStats, error) {
+	if !container.State.IsRunning() {
+		return nil, fmt.Errorf("Container %s is not running", container.ID)
+	}
+	cgroupStats, err := runtime.execDriver.Stats(container.ID)
+	if err != nil {
+		return nil, err
+	}
+	stats := &ContainerStats{
+		Read:  time.Now().UTC(),
+		Stats: cgroupStats,
+	}
+	if stats.SystemUsage, err = getSystemCpuUsage(); err != nil {
+		return nil, err
+	}
+	if mode := container.hostConfig.NetworkMode; !container.Config.NetworkDisabled && (mode.IsBridge() || mode.IsContainer()) {
+		// the container joining the network of another one shares its veth
+		nc := container
+		if mode.IsContainer() {
+			if nc, err = container.getNetworkedContainer(); err != nil {
+				return nil, err
+			}
+		}
+		if nc.command != nil && nc.command.HostInterface != "" {
+			dir := filepath.Join("/sys/class/net", nc.command.HostInterface, "statistics")
+			if err := readVethStatistics(dir, &stats.Network); err != nil {
+				return nil, err
+			}
+			return stats, nil
+		}
+
+		// without the veth of the container, the counters are read from the
+		// network namespace of one of the container's processes
+		pids, err := runtime.execDriver.GetPidsForContainer(container.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(pids) > 0 {
+			f, err := os.Open(fmt.Sprintf("/proc/%d/net/dev", pids[0]))
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			if err := parseNetDev(f, "eth0", &stats.Network); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return stats, nil
+}
+
+// readVethStatistics reads the counters of the container from the statistics
+// directory of the host end of its veth, what the host end receives is what
+// the container transmits
+func readVethStatistics(dir string, stats *NetworkStats) error {
+	for _, counter := range []struct {
+		file  string
+		value *uint64
+	}{
+		{"tx_bytes", &stats.RxBytes},
+		{"tx_packets", &stats.RxPackets},
+		{"tx_errors", &stats.RxErrors},
+		{"tx_dropped", &stats.RxDropped},
+		{"rx_bytes", &stats.TxBytes},
+		{"rx_packets", &stats.TxPackets},
+		{"rx_errors", &stats.TxErrors},
+		{"rx_dropped", &stats.TxDropped},
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, counter.file))
+		if err != nil {
+			return err
+		}
+		value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return err
+		}
+		*counter.value = value
+	}
+	return nil
+}
+
+// parseNetDev reads the counters of iface from the content of /proc/net/dev
+func parseNetDev(r io.Reader, iface string, stats *NetworkStats) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		parts := strings.SplitN(s.Text(), ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) != iface {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 12 {
+			return fmt.Errorf("invalid counters for %s: %s", iface, parts[1])
+		}
+		values := make([]uint64, len(fields))
+		for i, field := range fields {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return err
+			}
+			values[i] = value
+		}
+		// receive: bytes packets errs drop fifo frame compressed multicast
+		// transmit: bytes packets errs drop fifo colls carrier compressed
+		stats.RxBytes, stats.RxPackets, stats.RxErrors, stats.RxDropped = values[0], values[1], values[2], values[3]
+		stats.TxBytes, stats.TxPackets, stats.TxErrors, stats.TxDropped = values[8], values[9], values[10], values[11]
+		return nil
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("interface %s not found", iface)
+}
+
+// getSystemCpuUsage returns the CPU time of the host in nanoseconds,
+// as the sum of the "cpu" line of /proc/stat
+func getSystemCpuUsage() (uint64, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || fields[0] != "cpu" {
+			continue
+		}
+		var total uint64
+		for _, field := range fields[1:] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			total += value
+		}
+		return total * uint64(time.Second) / clockTicks, nil
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("cpu usage not found in /proc/stat")
+}
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const netDevContents = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:       0       0    0    0    0     0          0         0        0       0    0    0    0     0       0          0
+  eth0:    1296      16    1    2    0     0          0         0      738       9    3    4    0     0       0          0`
+
+func TestParseNetDev(t *testing.T) {
+	stats := &NetworkStats{}
+	if err := parseNetDev(bytes.NewBufferString(netDevContents), "eth0", stats); err != nil {
+		t.Fatal(err)
+	}
+	expected := NetworkStats{
+		RxBytes: 1296, RxPackets: 16, RxErrors: 1, RxDropped: 2,
+		TxBytes: 738, TxPackets: 9, TxErrors: 3, TxDropped: 4,
+	}
+	if *stats != expected {
+		t.Fatalf("Expected %+v, received %+v", expected, *stats)
+	}
+}
+
+func TestReadVethStatistics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-veth-statistics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the counters of the host end of the veth
+	for file, value := range map[string]string{
+		"rx_bytes": "738\n", "rx_packets": "9\n", "rx_errors": "3\n", "rx_dropped": "4\n",
+		"tx_bytes": "1296\n", "tx_packets": "16\n", "tx_errors": "1\n", "tx_dropped": "2\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := &NetworkStats{}
+	if err := readVethStatistics(dir, stats); err != nil {
+		t.Fatal(err)
+	}
+	expected := NetworkStats{
+		RxBytes: 1296, RxPackets: 16, RxErrors: 1, RxDropped: 2,
+		TxBytes: 738, TxPackets: 9, TxErrors: 3, TxDropped: 4,
+	}
+	if *stats != expected {
+		t.Fatalf("Expected %+v, received %+v", expected, *stats)
+	}
+}
+
+func TestParseNetDevMissingInterface(t *testing.T) {
+	if err := parseNetDev(bytes.NewBufferString(netDevContents), "eth1", &NetworkStats{}); err == nil {
+		t.Fatal("Expected an error for a missing interface")
+	}
+}
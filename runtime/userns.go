@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"fmt"
+	"github.com/dotcloud/docker/pkg/libcontainer"
+	"github.com/dotcloud/docker/pkg/user"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// getRemappedIds maps the uids and gids of the containers to the subordinate
+// ids of a user (name or uid) in /etc/subuid and /etc/subgid, the root of the
+// containers is the first subordinate id of the user
+func getRemappedIds(spec string) ([]libcontainer.IdMap, []libcontainer.IdMap, error) {
+	name, uid := spec, spec
+	users, err := user.ParsePasswdFilter(func(u *user.User) bool {
+		return u.Name == spec || strconv.Itoa(u.Uid) == spec
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	if len(users) > 0 {
+		name, uid = users[0].Name, strconv.Itoa(users[0].Uid)
+	}
+	filter := func(s *user.SubId) bool {
+		return s.Name == name || s.Name == uid
+	}
+
+	subUids, err := user.ParseSubUidFilter(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	uidMaps, err := subIdMappings(subUids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Cannot remap the uids to %s: %s", spec, err)
+	}
+	subGids, err := user.ParseSubGidFilter(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	gidMaps, err := subIdMappings(subGids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Cannot remap the gids to %s: %s", spec, err)
+	}
+	return uidMaps, gidMaps, nil
+}
+
+// subIdMappings maps the ids of the containers, from 0, to the
+// ranges of subordinate ids one after the other
+func subIdMappings(subIds []*user.SubId) ([]libcontainer.IdMap, error) {
+	if len(subIds) == 0 {
+		return nil, fmt.Errorf("no subordinate ids")
+	}
+	var (
+		mappings []libcontainer.IdMap
+		size     int
+	)
+	for _, s := range subIds {
+		mappings = append(mappings, libcontainer.IdMap{ContainerId: size, HostId: s.SubId, Size: s.Count})
+		size += s.Count
+	}
+	// the ids of the layers already in the ranges of the host are not
+	// mapped again, these ranges cannot contain ids of the containers
+	for _, m := range mappings {
+		if m.HostId < size {
+			return nil, fmt.Errorf("the subordinate ids %d-%d overlap the ids of the containers 0-%d", m.HostId, m.HostId+m.Size-1, size-1)
+		}
+	}
+	return mappings, nil
+}
+
+// makeTraversable lets the remapped root of the containers go through dir
+// and its parents in the root of the daemon, which are only open to the
+// root of the host
+func (runtime *Runtime) makeTraversable(dir string) error {
+	if runtime.uidMaps == nil {
+		return nil
+	}
+	root := runtime.config.Root
+	for ; dir == root || strings.HasPrefix(dir, root+"/"); dir = path.Dir(dir) {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&0001 == 0 {
+			if err := os.Chmod(dir, fi.Mode()|0001); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rootIds returns the uid and gid of the host
+// the root of the containers is mapped to
+func (runtime *Runtime) rootIds() (int, int) {
+	if runtime.uidMaps == nil {
+		return 0, 0
+	}
+	return runtime.uidMaps[0].HostId, runtime.gidMaps[0].HostId
+}
@@ -0,0 +1,29 @@
+package runtime
+
+import (
+	"github.com/dotcloud/docker/pkg/user"
+	"testing"
+)
+
+func TestSubIdMappings(t *testing.T) {
+	mappings, err := subIdMappings([]*user.SubId{
+		{Name: "dockremap", SubId: 100000, Count: 65536},
+		{Name: "dockremap", SubId: 300000, Count: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mappings) != 2 {
+		t.Fatalf("Expected 2 mappings, received %d", len(mappings))
+	}
+	if m := mappings[1]; m.ContainerId != 65536 || m.HostId != 300000 || m.Size != 1000 {
+		t.Fatalf("Expected 65536 300000 1000, received %d %d %d", m.ContainerId, m.HostId, m.Size)
+	}
+
+	if _, err := subIdMappings(nil); err == nil {
+		t.Fatal("Expected an error without subordinate ids")
+	}
+	if _, err := subIdMappings([]*user.SubId{{Name: "dockremap", SubId: 1000, Count: 65536}}); err == nil {
+		t.Fatal("Expected an error for subordinate ids overlapping the ids of the containers")
+	}
+}
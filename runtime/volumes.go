@@ -3,6 +3,7 @@ package runtime
 import (
 	"fmt"
 	"github.com/dotcloud/docker/archive"
+	"github.com/dotcloud/docker/pkg/libcontainer/selinux"
 	"github.com/dotcloud/docker/runtime/execdriver"
 	"github.com/dotcloud/docker/utils"
 	"io/ioutil"
@@ -263,6 +264,14 @@ func createVolumes(container *Container) error {
 				}
 			}
 		}
+
+		// The volumes created by docker can be shared with other containers,
+		// their files get the label of the container without its level
+		if !isBindMount {
+			if err := selinux.Relabel(srcPath, selinux.SharedLabel(container.MountLabel)); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
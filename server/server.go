@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/dotcloud/docker/archive"
@@ -10,10 +11,14 @@ import (
 	"github.com/dotcloud/docker/graph"
 	"github.com/dotcloud/docker/image"
 	"github.com/dotcloud/docker/pkg/graphdb"
+	"github.com/dotcloud/docker/pkg/libcontainer/seccomp"
 	"github.com/dotcloud/docker/pkg/signal"
+	"github.com/dotcloud/docker/pkg/tailfile"
 	"github.com/dotcloud/docker/registry"
 	"github.com/dotcloud/docker/runconfig"
 	"github.com/dotcloud/docker/runtime"
+	"github.com/dotcloud/docker/runtime/execdriver"
+	"github.com/dotcloud/docker/runtime/logger"
 	"github.com/dotcloud/docker/utils"
 	"io"
 	"io/ioutil"
@@ -66,6 +71,10 @@ func InitServer(job *engine.Job) engine.Status {
 		"export":           srv.ContainerExport,
 		"create":           srv.ContainerCreate,
 		"stop":             srv.ContainerStop,
+		"pause":            srv.ContainerPause,
+		"unpause":          srv.ContainerUnpause,
+		"rename":           srv.ContainerRename,
+		"update":           srv.ContainerUpdate,
 		"restart":          srv.ContainerRestart,
 		"start":            srv.ContainerStart,
 		"kill":             srv.ContainerKill,
@@ -82,9 +91,15 @@ func InitServer(job *engine.Job) engine.Status {
 		"container_copy":   srv.ContainerCopy,
 		"insert":           srv.ImageInsert,
 		"attach":           srv.ContainerAttach,
+		"logs":             srv.ContainerLogs,
+		"exec":             srv.ContainerExecCreate,
+		"exec_start":       srv.ContainerExecStart,
+		"exec_inspect":     srv.ContainerExecInspect,
+		"exec_resize":      srv.ContainerExecResize,
 		"search":           srv.ImagesSearch,
 		"changes":          srv.ContainerChanges,
 		"top":              srv.ContainerTop,
+		"stats":            srv.ContainerStats,
 		"version":          srv.DockerVersion,
 		"load":             srv.ImageLoad,
 		"build":            srv.Build,
@@ -936,6 +951,43 @@ func (srv *Server) ContainerTop(job *engine.Job) engine.Status {
 	return job.Errorf("No such container: %s", name)
 }
 
+func (srv *Server) ContainerStats(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	var (
+		name   = job.Args[0]
+		stream = job.GetenvBool("stream")
+	)
+	container := srv.runtime.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Container %s is not running", name)
+	}
+
+	enc := json.NewEncoder(job.Stdout)
+	for {
+		stats, err := srv.runtime.Stats(container)
+		if err != nil {
+			// the container stopped while streaming
+			if !container.State.IsRunning() {
+				return engine.StatusOK
+			}
+			return job.Error(err)
+		}
+		if err := enc.Encode(stats); err != nil {
+			// the client went away
+			return engine.StatusOK
+		}
+		if !stream {
+			return engine.StatusOK
+		}
+		time.Sleep(1 * time.Second)
+	}
+}
+
 func (srv *Server) ContainerChanges(job *engine.Job) engine.Status {
 	if n := len(job.Args); n != 1 {
 		return job.Errorf("Usage: %s CONTAINER", job.Name)
@@ -1656,6 +1708,9 @@ func (srv *Server) ContainerCreate(job *engine.Job) engine.Status {
 	if config.Memory != 0 && config.Memory < 524288 {
 		return job.Errorf("Minimum memory limit allowed is 512k")
 	}
+	if err := runconfig.ValidateResources(config); err != nil {
+		return job.Error(err)
+	}
 	if config.Memory > 0 && !srv.runtime.SystemConfig().MemoryLimit {
 		job.Errorf("Your kernel does not support memory limit capabilities. Limitation discarded.\n")
 		config.Memory = 0
@@ -2069,6 +2124,30 @@ func (srv *Server) ContainerStart(job *engine.Job) engine.Status {
 				}
 			}
 		}
+		if logConfig := hostConfig.LogConfig; logConfig.Type != "" {
+			if err := logger.ValidateDriver(logConfig.Type); err != nil {
+				return job.Errorf("Cannot start container %s: %s", name, err)
+			}
+			if err := logger.ValidateLogOpts(logConfig.Type, logConfig.Config); err != nil {
+				return job.Errorf("Cannot start container %s: invalid options for the %s log driver: %s", name, logConfig.Type, err)
+			}
+		}
+		if _, err := execdriver.TweakCapabilities(execdriver.DefaultCapabilityMask, hostConfig.CapAdd, hostConfig.CapDrop); err != nil {
+			return job.Errorf("Cannot start container %s: %s", name, err)
+		}
+		if profile := hostConfig.SeccompProfile; profile != "" && profile != "unconfined" {
+			if _, err := seccomp.LoadProfile(profile); err != nil {
+				return job.Errorf("Cannot start container %s: %s", name, err)
+			}
+		}
+		if _, _, err := runconfig.ParseSecurityOpt(hostConfig.SecurityOpt); err != nil {
+			return job.Errorf("Cannot start container %s: %s", name, err)
+		}
+		for _, u := range hostConfig.Ulimits {
+			if err := u.Validate(); err != nil {
+				return job.Errorf("Cannot start container %s: %s", name, err)
+			}
+		}
 		// Register any links from the host config before starting the container
 		if err := srv.RegisterLinks(container, hostConfig); err != nil {
 			return job.Error(err)
@@ -2106,6 +2185,87 @@ func (srv *Server) ContainerStop(job *engine.Job) engine.Status {
 	return engine.StatusOK
 }
 
+func (srv *Server) ContainerPause(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	name := job.Args[0]
+	container := srv.runtime.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if err := container.Pause(); err != nil {
+		return job.Errorf("Cannot pause container %s: %s", name, err)
+	}
+	srv.LogEvent("pause", container.ID, srv.runtime.Repositories().ImageName(container.Image))
+	return engine.StatusOK
+}
+
+func (srv *Server) ContainerUnpause(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	name := job.Args[0]
+	container := srv.runtime.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if err := container.Unpause(); err != nil {
+		return job.Errorf("Cannot unpause container %s: %s", name, err)
+	}
+	srv.LogEvent("unpause", container.ID, srv.runtime.Repositories().ImageName(container.Image))
+	return engine.StatusOK
+}
+
+func (srv *Server) ContainerRename(job *engine.Job) engine.Status {
+	if len(job.Args) != 2 {
+		return job.Errorf("Usage: %s OLD_NAME NEW_NAME", job.Name)
+	}
+	oldName, newName := job.Args[0], job.Args[1]
+	container := srv.runtime.Get(oldName)
+	if container == nil {
+		return job.Errorf("No such container: %s", oldName)
+	}
+	if err := srv.runtime.Rename(container, newName); err != nil {
+		return job.Errorf("Cannot rename container %s: %s", oldName, err)
+	}
+	srv.LogEvent("rename", container.ID, srv.runtime.Repositories().ImageName(container.Image))
+	return engine.StatusOK
+}
+
+func (srv *Server) ContainerUpdate(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	name := job.Args[0]
+	container := srv.runtime.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	resources := &execdriver.Resources{
+		Memory:     job.GetenvInt64("Memory"),
+		MemorySwap: job.GetenvInt64("MemorySwap"),
+		CpuShares:  job.GetenvInt64("CpuShares"),
+	}
+	if resources.Memory < 0 || resources.MemorySwap < -1 || resources.CpuShares < 0 {
+		return job.Errorf("Bad parameter: the limits cannot be negative")
+	}
+	if resources.Memory != 0 && resources.Memory < 524288 {
+		return job.Errorf("Minimum memory limit allowed is 512k")
+	}
+	if resources.Memory > 0 && !srv.runtime.SystemConfig().MemoryLimit {
+		return job.Errorf("Your kernel does not support memory limit capabilities")
+	}
+	if resources.MemorySwap > 0 && !srv.runtime.SystemConfig().SwapLimit {
+		return job.Errorf("Your kernel does not support swap limit capabilities")
+	}
+	if err := container.Update(resources); err != nil {
+		return job.Errorf("Cannot update container %s: %s", name, err)
+	}
+	srv.LogEvent("update", container.ID, srv.runtime.Repositories().ImageName(container.Image))
+	return engine.StatusOK
+}
+
 func (srv *Server) ContainerWait(job *engine.Job) engine.Status {
 	if len(job.Args) != 1 {
 		return job.Errorf("Usage: %s", job.Name)
@@ -2141,6 +2301,157 @@ func (srv *Server) ContainerResize(job *engine.Job) engine.Status {
 	return job.Errorf("No such container: %s", name)
 }
 
+func (srv *Server) ContainerLogs(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER\n", job.Name)
+	}
+
+	var (
+		name   = job.Args[0]
+		stdout = job.GetenvBool("stdout")
+		stderr = job.GetenvBool("stderr")
+		follow = job.GetenvBool("follow")
+		tail   = job.Getenv("tail")
+		lines  = -1
+		w      = &logsWriter{timestamps: job.GetenvBool("timestamps")}
+	)
+	if !(stdout || stderr) {
+		return job.Errorf("Bad parameter: you must choose at least one stream")
+	}
+	if stdout {
+		w.stdout = job.Stdout
+	}
+	if stderr {
+		w.stderr = job.Stderr
+	}
+	if tail != "" && tail != "all" {
+		n, err := strconv.Atoi(tail)
+		if err != nil || n < 0 {
+			return job.Errorf("Bad parameter: tail must be a positive number or all, not %s", tail)
+		}
+		lines = n
+	}
+	if since := job.GetenvInt64("since"); since > 0 {
+		w.since = time.Unix(since, 0)
+	}
+
+	container := srv.runtime.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+
+	files, err := container.ReadLogs()
+	if err != nil {
+		return job.Errorf("Error reading logs: %s", err)
+	}
+	for _, f := range files {
+		defer f.Close()
+	}
+	var src io.Reader
+	if lines == -1 {
+		readers := make([]io.Reader, len(files))
+		for i, f := range files {
+			readers[i] = f
+		}
+		src = io.MultiReader(readers...)
+	} else {
+		// only the end of the files is read to find the last lines
+		var tailed [][]byte
+		for i := len(files) - 1; i >= 0 && len(tailed) < lines; i-- {
+			fileLines, err := tailfile.TailFile(files[i], lines-len(tailed))
+			if err != nil {
+				return job.Errorf("Error reading logs: %s", err)
+			}
+			tailed = append(fileLines, tailed...)
+		}
+		src = bytes.NewReader(bytes.Join(tailed, []byte("\n")))
+	}
+	if err := w.copyFrom(src); err != nil {
+		utils.Errorf("Error streaming logs: %s", err)
+		return engine.StatusOK
+	}
+
+	if follow && container.State.IsRunning() {
+		var (
+			errs    = make(chan error, 2)
+			streams int
+		)
+		if stdout {
+			stdoutPipe := container.StdoutLogPipe()
+			defer stdoutPipe.Close()
+			go func() {
+				errs <- w.copyFrom(stdoutPipe)
+			}()
+			streams++
+		}
+		if stderr {
+			stderrPipe := container.StderrLogPipe()
+			defer stderrPipe.Close()
+			go func() {
+				errs <- w.copyFrom(stderrPipe)
+			}()
+			streams++
+		}
+		// the pipes are closed when the container stops, or on the first
+		// error, when the client went away
+		for i := 0; i < streams; i++ {
+			if err := <-errs; err != nil {
+				utils.Debugf("Error streaming logs: %s", err)
+				break
+			}
+		}
+	}
+	return engine.StatusOK
+}
+
+// logsWriter writes the json log lines selected by the logs job,
+// stdout and stderr are nil when the stream is not selected
+type logsWriter struct {
+	stdout     io.Writer
+	stderr     io.Writer
+	timestamps bool
+	since      time.Time
+}
+
+// copyFrom decodes the json log lines of src until its end
+func (w *logsWriter) copyFrom(src io.Reader) error {
+	dec := json.NewDecoder(src)
+	for {
+		l := &utils.JSONLog{}
+		if err := dec.Decode(l); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := w.write(l); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *logsWriter) write(l *utils.JSONLog) error {
+	if !w.since.IsZero() && l.Created.Before(w.since) {
+		return nil
+	}
+	var out io.Writer
+	switch l.Stream {
+	case "stdout":
+		out = w.stdout
+	case "stderr":
+		out = w.stderr
+	}
+	if out == nil {
+		return nil
+	}
+	var err error
+	if w.timestamps {
+		_, err = fmt.Fprintf(out, "%s %s", l.Created.Format(time.RFC3339Nano), l.Log)
+	} else {
+		_, err = fmt.Fprintf(out, "%s", l.Log)
+	}
+	return err
+}
+
 func (srv *Server) ContainerAttach(job *engine.Job) engine.Status {
 	if len(job.Args) != 1 {
 		return job.Errorf("Usage: %s CONTAINER\n", job.Name)
@@ -2162,45 +2473,53 @@ func (srv *Server) ContainerAttach(job *engine.Job) engine.Status {
 
 	//logs
 	if logs {
-		cLog, err := container.ReadLog("json")
-		if err != nil && os.IsNotExist(err) {
+		// the logs are read like the logs job reads them, from the files
+		// of the log driver of the container
+		files, err := container.ReadLogs()
+		if err != nil {
+			utils.Errorf("Error reading logs: %s", err)
+		} else if len(files) == 0 {
 			// Legacy logs
 			utils.Debugf("Old logs format")
 			if stdout {
 				cLog, err := container.ReadLog("stdout")
 				if err != nil {
 					utils.Errorf("Error reading logs (stdout): %s", err)
-				} else if _, err := io.Copy(job.Stdout, cLog); err != nil {
-					utils.Errorf("Error streaming logs (stdout): %s", err)
+				} else {
+					if _, err := io.Copy(job.Stdout, cLog); err != nil {
+						utils.Errorf("Error streaming logs (stdout): %s", err)
+					}
+					cLog.Close()
 				}
 			}
 			if stderr {
 				cLog, err := container.ReadLog("stderr")
 				if err != nil {
 					utils.Errorf("Error reading logs (stderr): %s", err)
-				} else if _, err := io.Copy(job.Stderr, cLog); err != nil {
-					utils.Errorf("Error streaming logs (stderr): %s", err)
+				} else {
+					if _, err := io.Copy(job.Stderr, cLog); err != nil {
+						utils.Errorf("Error streaming logs (stderr): %s", err)
+					}
+					cLog.Close()
 				}
 			}
-		} else if err != nil {
-			utils.Errorf("Error reading logs (json): %s", err)
 		} else {
-			dec := json.NewDecoder(cLog)
-			for {
-				l := &utils.JSONLog{}
-
-				if err := dec.Decode(l); err == io.EOF {
-					break
-				} else if err != nil {
-					utils.Errorf("Error streaming logs: %s", err)
-					break
-				}
-				if l.Stream == "stdout" && stdout {
-					fmt.Fprintf(job.Stdout, "%s", l.Log)
-				}
-				if l.Stream == "stderr" && stderr {
-					fmt.Fprintf(job.Stderr, "%s", l.Log)
-				}
+			w := &logsWriter{}
+			if stdout {
+				w.stdout = job.Stdout
+			}
+			if stderr {
+				w.stderr = job.Stderr
+			}
+			readers := make([]io.Reader, len(files))
+			for i, f := range files {
+				readers[i] = f
+			}
+			if err := w.copyFrom(io.MultiReader(readers...)); err != nil {
+				utils.Errorf("Error streaming logs: %s", err)
+			}
+			for _, f := range files {
+				f.Close()
 			}
 		}
 	}
@@ -2245,6 +2564,115 @@ func (srv *Server) ContainerAttach(job *engine.Job) engine.Status {
 	return engine.StatusOK
 }
 
+func (srv *Server) ContainerExecCreate(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s CONTAINER", job.Name)
+	}
+	name := job.Args[0]
+	container := srv.runtime.Get(name)
+	if container == nil {
+		return job.Errorf("No such container: %s", name)
+	}
+	if !container.State.IsRunning() {
+		return job.Errorf("Impossible to exec in a stopped container: %s", name)
+	}
+	execConfig, err := srv.runtime.ExecCreate(container, runconfig.ExecConfigFromJob(job))
+	if err != nil {
+		return job.Error(err)
+	}
+	job.Printf("%s\n", execConfig.ID)
+	return engine.StatusOK
+}
+
+func (srv *Server) ContainerExecStart(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s EXEC_ID", job.Name)
+	}
+	execConfig, err := srv.runtime.GetExecConfig(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+
+	var (
+		cStdin           io.ReadCloser
+		cStdout, cStderr io.Writer
+	)
+	if execConfig.OpenStdin {
+		r, w := io.Pipe()
+		go func() {
+			defer w.Close()
+			defer utils.Debugf("Closing buffered stdin pipe")
+			io.Copy(w, job.Stdin)
+		}()
+		cStdin = r
+	}
+	if execConfig.OpenStdout {
+		cStdout = job.Stdout
+	} else {
+		cStdout = ioutil.Discard
+	}
+	if execConfig.OpenStderr {
+		cStderr = job.Stderr
+	} else {
+		cStderr = ioutil.Discard
+	}
+
+	container := execConfig.Container
+	srv.LogEvent("exec_start", container.ID, srv.runtime.Repositories().ImageName(container.Image))
+
+	exitCode, err := srv.runtime.Exec(execConfig, execdriver.NewPipes(cStdin, cStdout, cStderr, execConfig.OpenStdin))
+	if err != nil {
+		return job.Errorf("Cannot run exec command %s in container %s: %s", execConfig.ID, container.ID, err)
+	}
+	utils.Debugf("Exec %s in container %s exited with %d", execConfig.ID, container.ID, exitCode)
+	return engine.StatusOK
+}
+
+func (srv *Server) ContainerExecInspect(job *engine.Job) engine.Status {
+	if len(job.Args) != 1 {
+		return job.Errorf("Usage: %s EXEC_ID", job.Name)
+	}
+	execConfig, err := srv.runtime.GetExecConfig(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+	execConfig.Lock()
+	v := &engine.Env{}
+	v.Set("ID", execConfig.ID)
+	v.Set("Container", execConfig.Container.ID)
+	v.SetBool("Running", execConfig.Running)
+	v.SetInt("ExitCode", execConfig.ExitCode)
+	v.SetBool("Tty", execConfig.ProcessConfig.Tty)
+	execConfig.Unlock()
+	if _, err := v.WriteTo(job.Stdout); err != nil {
+		return job.Error(err)
+	}
+	srv.runtime.ExecExitCodeRead(execConfig)
+	return engine.StatusOK
+}
+
+func (srv *Server) ContainerExecResize(job *engine.Job) engine.Status {
+	if len(job.Args) != 3 {
+		return job.Errorf("Not enough arguments. Usage: %s EXEC_ID HEIGHT WIDTH\n", job.Name)
+	}
+	execConfig, err := srv.runtime.GetExecConfig(job.Args[0])
+	if err != nil {
+		return job.Error(err)
+	}
+	height, err := strconv.Atoi(job.Args[1])
+	if err != nil {
+		return job.Error(err)
+	}
+	width, err := strconv.Atoi(job.Args[2])
+	if err != nil {
+		return job.Error(err)
+	}
+	if err := execConfig.Resize(height, width); err != nil {
+		return job.Error(err)
+	}
+	return engine.StatusOK
+}
+
 func (srv *Server) ContainerInspect(name string) (*runtime.Container, error) {
 	if container := srv.runtime.Get(name); container != nil {
 		return container, nil
@@ -8,6 +8,7 @@ import (
 	_ "github.com/dotcloud/docker/runtime/execdriver/native"
 	"log"
 	"os"
+	"strings"
 )
 
 func executeProgram(args *execdriver.InitArgs) error {
@@ -34,11 +35,17 @@ func SysInit() {
 		ip         = flag.String("i", "", "ip address")
 		workDir    = flag.String("w", "", "workdir")
 		privileged = flag.Bool("privileged", false, "privileged mode")
+		capAdd     = flag.String("cap-add", "", "capabilities to keep, comma separated")
+		capDrop    = flag.String("cap-drop", "", "capabilities to drop, comma separated")
+		readonly   = flag.Bool("readonly", false, "mount the rootfs readonly")
+		ulimits    = flag.String("ulimit", "", "ulimits to set, comma separated")
 		mtu        = flag.Int("mtu", 1500, "interface mtu")
 		driver     = flag.String("driver", "", "exec driver")
 		pipe       = flag.Int("pipe", 0, "sync pipe fd")
 		console    = flag.String("console", "", "console (pty slave) path")
 		root       = flag.String("root", ".", "root path for configuration files")
+		ipv6       = flag.String("ipv6", "", "ipv6 address")
+		gatewayV6  = flag.String("gateway6", "", "ipv6 gateway address")
 	)
 	flag.Parse()
 
@@ -48,15 +55,29 @@ func SysInit() {
 		Ip:         *ip,
 		WorkDir:    *workDir,
 		Privileged: *privileged,
+		CapAdd:     splitList(*capAdd),
+		CapDrop:    splitList(*capDrop),
+		Readonly:   *readonly,
+		Ulimits:    splitList(*ulimits),
 		Args:       flag.Args(),
 		Mtu:        *mtu,
 		Driver:     *driver,
 		Console:    *console,
 		Pipe:       *pipe,
 		Root:       *root,
+
+		IPv6:        *ipv6,
+		IPv6Gateway: *gatewayV6,
 	}
 
 	if err := executeProgram(args); err != nil {
 		log.Fatal(err)
 	}
 }
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}